@@ -3,10 +3,13 @@ package api
 import (
 	"database/sql"
 	"errors"
+	"fmt"
 	"net/http"
 
 	db "db.sqlc.dev/app/db/sqlc"
+	"db.sqlc.dev/app/money"
 	"db.sqlc.dev/app/token"
+	"db.sqlc.dev/app/util"
 	"github.com/gin-gonic/gin"
 	"github.com/lib/pq"
 )
@@ -22,6 +25,38 @@ type createAccountRequest struct {
 	// use the oneof condition to declare bank only supports 2 types of currency for now: USD and EUR
 	// substitue oneof condition by custom currency validator
 	Currency string `json:"currency" binding:"required,currency"`
+	// Type: checking by default, a savings account earns the interest of its currency
+	Type string `json:"type" binding:"omitempty,oneof=checking savings"`
+	// Nickname tells apart the accounts of the user in the same currency, e.g. "Travel fund" and "Bills"
+	Nickname string `json:"nickname" binding:"max=64"`
+}
+
+// accountResponse renders the balance and the overdraft limit of an account both as money.Amount
+// and as decimal strings of the major unit, e.g. "balance": {"minor": 1234, "currency": "USD"} and "balance_decimal": "12.34"
+type accountResponse struct {
+	db.Account
+	// Balance, OverdraftLimit and HeldBalance replace the minor units of db.Account
+	Balance               money.Amount `json:"balance"`
+	OverdraftLimit        money.Amount `json:"overdraft_limit"`
+	HeldBalance           money.Amount `json:"held_balance"`
+	BalanceDecimal        string       `json:"balance_decimal,omitempty"`
+	OverdraftLimitDecimal string       `json:"overdraft_limit_decimal,omitempty"`
+	HeldBalanceDecimal    string       `json:"held_balance_decimal,omitempty"`
+}
+
+func newAccountResponse(currencies *util.CurrencyRegistry, account db.Account) accountResponse {
+	balance := account.BalanceAmount()
+	overdraftLimit := money.New(account.OverdraftLimit, account.Currency)
+	heldBalance := money.New(account.HeldBalance, account.Currency)
+	return accountResponse{
+		Account:               account,
+		Balance:               balance,
+		OverdraftLimit:        overdraftLimit,
+		HeldBalance:           heldBalance,
+		BalanceDecimal:        currencies.FormatAmount(balance),
+		OverdraftLimitDecimal: currencies.FormatAmount(overdraftLimit),
+		HeldBalanceDecimal:    currencies.FormatAmount(heldBalance),
+	}
 }
 
 // declare a function createAccount with a server pointer receiver
@@ -41,21 +76,44 @@ func (server *Server) createAccount(ctx *gin.Context) {
 	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
 
 	// STEP 2.: insert the new account into the database;
-	arg := db.CreateAccountParams{
-		// API RULE: A logged-in user can only create an accounr for him/herself
-		Owner:    authPayload.Username,
-		Currency: req.Currency,
-		Balance:  0,
+	arg := db.CreateAccountTxParams{
+		CreateAccountParams: db.CreateAccountParams{
+			// API RULE: A logged-in user can only create an accounr for him/herself
+			Owner:    authPayload.Username,
+			Currency: req.Currency,
+			Balance:  0,
+			Type:     req.Type,
+			Nickname: req.Nickname,
+		},
+		// API RULE: a user can't own more than MaxAccountsPerUser open accounts, joint holdings don't count
+		MaxAccounts: server.config.MaxAccountsPerUser,
+	}
+	if arg.Type == "" {
+		arg.Type = util.CheckingAccount
 	}
 
-	account, err := server.store.CreateAccount(ctx, arg)
+	account, err := server.store.CreateAccountTx(ctx, arg)
 	// send JSON response with 500 Internal Server Error status code to client if err is not nil
 	if err != nil {
+		// the user of the token doesn't exist
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusForbidden, errorResponse(err))
+			return
+		}
+		if errors.Is(err, db.ErrAccountLimitReached) {
+			ctx.JSON(http.StatusUnprocessableEntity, errorCodeResponse(errCodeAccountLimitReached, err))
+			return
+		}
 		if pqErr, ok := err.(*pq.Error); ok {
 			switch pqErr.Code.Name() {
-			case "foreign_key_violation", "unique_violation":
+			case "foreign_key_violation":
 				ctx.JSON(http.StatusForbidden, errorResponse(err))
 				return
+			// API RULE: the accounts of a user in a currency must have different nicknames
+			case "unique_violation":
+				err := fmt.Errorf("account %q already exists in %s", req.Nickname, req.Currency)
+				ctx.JSON(http.StatusConflict, errorCodeResponse(errCodeAccountNicknameTaken, err))
+				return
 			}
 		}
 		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
@@ -63,7 +121,7 @@ func (server *Server) createAccount(ctx *gin.Context) {
 	}
 
 	// send a 200 OK status code to client if no error;
-	ctx.JSON(http.StatusOK, account)
+	ctx.JSON(http.StatusOK, newAccountResponse(server.currencies, account))
 
 }
 
@@ -92,20 +150,19 @@ func (server *Server) getAccount(ctx *gin.Context) {
 
 	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
 
-	// API RULE: A logged-in user can only get accounts that he/she owns
-	if account.Owner != authPayload.Username {
-		err := errors.New("account does not belong to the authenticated user")
-		ctx.JSON(http.StatusUnauthorized, errorResponse(err))
+	// API RULE: A logged-in depositor can only get accounts that he/she holds, a banker can get any account
+	if !server.authorizeAccount(ctx, authPayload, account, db.PermissionView) {
 		return
 	}
 
-	ctx.JSON(http.StatusOK, account)
+	ctx.JSON(http.StatusOK, newAccountResponse(server.currencies, account))
 }
 
 type listAccountRequest struct {
 	// to get parameters from query string, use form tag
-	PageID   int32 `form:"page_id" binding:"required,min=1"`
-	PageSize int32 `form:"page_size" binding:"required,min=5,max=10"`
+	// Owner is optional, only bankers can list the accounts held by other users
+	Owner string `form:"owner" binding:"omitempty,alphanum"`
+	pageQuery
 }
 
 func (server *Server) listAccount(ctx *gin.Context) {
@@ -114,21 +171,250 @@ func (server *Server) listAccount(ctx *gin.Context) {
 		ctx.JSON(http.StatusBadRequest, errorResponse(err))
 		return
 	}
+	if err := req.validatePage(); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+	afterID, err := req.afterID()
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
 
 	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
 
-	arg := db.ListAccountsParams{
-		// API RULE: A logged-in user can only list accounts that he/she owns
-		Owner:  authPayload.Username,
-		Limit:  req.PageSize,
-		Offset: (req.PageID - 1) * req.PageSize,
+	owner := authPayload.Username
+	if len(req.Owner) > 0 {
+		owner = req.Owner
+	}
+
+	// API RULE: A logged-in depositor can only list accounts that he/she holds, a banker can list the accounts of any user
+	if owner != authPayload.Username && authPayload.Role != util.BankerRole {
+		err := errors.New("accounts do not belong to the authenticated user")
+		ctx.JSON(http.StatusUnauthorized, errorResponse(err))
+		return
+	}
+
+	var accounts []db.Account
+	if req.cursorMode() {
+		// read one more account to know if there is a next page
+		accounts, err = server.store.ListAccountsAfter(ctx, db.ListAccountsAfterParams{
+			Username: owner,
+			AfterID:  afterID,
+			Limit:    req.PageSize + 1,
+		})
+	} else {
+		accounts, err = server.store.ListAccounts(ctx, db.ListAccountsParams{
+			Username: owner,
+			Limit:    req.PageSize,
+			Offset:   req.offset(),
+		})
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	var nextCursor string
+	if len(accounts) > int(req.PageSize) {
+		accounts = accounts[:req.PageSize]
+		nextCursor = encodeCursor(accounts[len(accounts)-1].ID)
+	}
+
+	rsp := make([]accountResponse, len(accounts))
+	for i, account := range accounts {
+		rsp[i] = newAccountResponse(server.currencies, account)
+	}
+	req.sendPage(ctx, rsp, nextCursor)
+}
+
+type updateOverdraftLimitRequest struct {
+	// use a pointer to accept 0, which removes the overdraft of the account.
+	// The limit is in the currency of the account
+	OverdraftLimit *money.Amount `json:"overdraft_limit" binding:"required,min=0"`
+}
+
+// updateOverdraftLimit sets how far the balance of an account can go negative, only bankers can call it
+func (server *Server) updateOverdraftLimit(ctx *gin.Context) {
+	var uri getAccountRequest
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	var req updateOverdraftLimitRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	if _, valid := server.validAccount(ctx, uri.ID, req.OverdraftLimit.Currency); !valid {
+		return
+	}
+
+	arg := db.UpdateAccountOverdraftLimitParams{
+		ID:             uri.ID,
+		OverdraftLimit: req.OverdraftLimit.Minor,
+	}
+
+	account, err := server.store.UpdateAccountOverdraftLimit(ctx, arg)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return
+		}
+		// API RULE: the limit can't be lower than the current overdraft of the account
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code.Name() == "check_violation" {
+			err := errors.New("overdraft limit is lower than the current overdraft of the account")
+			ctx.JSON(http.StatusUnprocessableEntity, errorCodeResponse(errCodeOverdraftLimitTooLow, err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, newAccountResponse(server.currencies, account))
+}
+
+type updateAccountStatusRequest struct {
+	// Status: an account is closed with closeAccount
+	Status string `json:"status" binding:"required,oneof=active debit_frozen frozen"`
+	Reason string `json:"reason" binding:"required"`
+}
+
+// updateAccountStatus freezes or unfreezes an account, only bankers can change the status of an account
+func (server *Server) updateAccountStatus(ctx *gin.Context) {
+	var uri getAccountRequest
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	var req updateAccountStatusRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	arg := db.UpdateAccountStatusTxParams{
+		AccountID: uri.ID,
+		Status:    req.Status,
+		Reason:    req.Reason,
+		Actor:     authPayload.Username,
 	}
 
-	accounts, err := server.store.ListAccounts(ctx, arg)
+	account, err := server.store.UpdateAccountStatusTx(ctx, arg)
 	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return
+		}
+		// API RULE: a closed account can't be opened again
+		if errors.Is(err, db.ErrAccountClosed) {
+			ctx.JSON(http.StatusUnprocessableEntity, errorCodeResponse(errCodeAccountClosed, err))
+			return
+		}
 		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
 		return
 	}
 
-	ctx.JSON(http.StatusOK, accounts)
+	ctx.JSON(http.StatusOK, newAccountResponse(server.currencies, account))
+}
+
+type closeAccountRequest struct {
+	// SweepToAccountID: optional account of the same owner receiving the balance of the closed account
+	SweepToAccountID int64  `json:"sweep_to_account_id" binding:"omitempty,min=1"`
+	Reason           string `json:"reason"`
+}
+
+type closeAccountResponse struct {
+	Account accountResponse `json:"account"`
+	// Sweep: transfer of the balance to the sweep account, omitted if the balance was already zero
+	Sweep *transferTxResponse `json:"sweep,omitempty"`
+}
+
+// closeAccount closes an account that has no money left, or after sweeping its balance to another account of its owner.
+// A closed account keeps its entries and transfers but rejects any new transfer
+func (server *Server) closeAccount(ctx *gin.Context) {
+	var uri getAccountRequest
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	// the body is optional, an account without money is closed without sweep
+	var req closeAccountRequest
+	if ctx.Request.ContentLength != 0 {
+		if err := ctx.ShouldBindJSON(&req); err != nil {
+			ctx.JSON(http.StatusBadRequest, errorResponse(err))
+			return
+		}
+	}
+
+	account, ok := server.findAccount(ctx, uri.ID)
+	if !ok {
+		return
+	}
+
+	// API RULE: an account is closed by a holder with the manage permission
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	if !server.authorizeAccount(ctx, authPayload, account, db.PermissionManage) {
+		return
+	}
+
+	arg := db.CloseAccountTxParams{
+		AccountID: account.ID,
+		Reason:    req.Reason,
+		Actor:     authPayload.Username,
+	}
+	if account.Balance > 0 && req.SweepToAccountID != 0 {
+		sweepAccount, ok := server.findAccount(ctx, req.SweepToAccountID)
+		if !ok {
+			return
+		}
+		// API RULE: the balance can only be swept to another account the owner can move money from
+		held, err := server.holdsAccount(ctx, account.Owner, sweepAccount, db.PermissionTransfer)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+			return
+		}
+		if sweepAccount.ID == account.ID || !held {
+			err := errors.New("sweep account must be another account of the owner")
+			ctx.JSON(http.StatusBadRequest, errorResponse(err))
+			return
+		}
+
+		// the sweep is free of charge, but it is converted into the currency of the sweep account
+		sweep := db.TransferTxParams{
+			FromAccountID: account.ID,
+			ToAccountID:   sweepAccount.ID,
+			Amount:        account.BalanceAmount(),
+		}
+		if sweepAccount.Currency != account.Currency {
+			if err := server.convertTransfer(ctx, &sweep, account.Currency, sweepAccount.Currency); err != nil {
+				writePricingError(ctx, err)
+				return
+			}
+		}
+		arg.Sweep = &sweep
+	}
+
+	result, err := server.store.CloseAccountTx(ctx, arg)
+	if err != nil {
+		// API RULE: an account is closed without money, debt or pending transfer
+		if errors.Is(err, db.ErrAccountNotSettled) {
+			ctx.JSON(http.StatusUnprocessableEntity, errorCodeResponse(errCodeAccountNotSettled, err))
+			return
+		}
+		ctx.JSON(transferErrorResponse(err))
+		return
+	}
+
+	rsp := closeAccountResponse{Account: newAccountResponse(server.currencies, result.Account)}
+	if arg.Sweep != nil {
+		sweep := newTransferTxResponse(server.currencies, result.Sweep)
+		rsp.Sweep = &sweep
+	}
+	ctx.JSON(http.StatusOK, rsp)
 }
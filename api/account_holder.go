@@ -0,0 +1,204 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+
+	db "db.sqlc.dev/app/db/sqlc"
+	"db.sqlc.dev/app/token"
+	"db.sqlc.dev/app/util"
+	"github.com/gin-gonic/gin"
+	"github.com/lib/pq"
+)
+
+// holdsAccount reports whether the user is a holder of the account with the permission.
+// The owner of an account is always a holder with the manage permission
+func (server *Server) holdsAccount(ctx context.Context, username string, account db.Account, permission string) (bool, error) {
+	if account.Owner == username {
+		return true, nil
+	}
+
+	holder, err := server.store.GetAccountHolder(ctx, db.GetAccountHolderParams{
+		AccountID: account.ID,
+		Username:  username,
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return false, nil
+		}
+		return false, err
+	}
+	return holder.Allows(permission), nil
+}
+
+// canAccessAccount reports whether the logged-in user holds the account with the permission,
+// a banker can view any account
+func (server *Server) canAccessAccount(ctx context.Context, authPayload *token.Payload, account db.Account, permission string) (bool, error) {
+	if authPayload.Role == util.BankerRole && permission == db.PermissionView {
+		return true, nil
+	}
+	return server.holdsAccount(ctx, authPayload.Username, account, permission)
+}
+
+// canAccessAnyAccount reports whether the logged-in user can access one of the accounts with the permission
+func (server *Server) canAccessAnyAccount(ctx context.Context, authPayload *token.Payload, permission string, accounts ...db.Account) (bool, error) {
+	for _, account := range accounts {
+		allowed, err := server.canAccessAccount(ctx, authPayload, account, permission)
+		if err != nil || allowed {
+			return allowed, err
+		}
+	}
+	return false, nil
+}
+
+// authorizeAccount checks that the logged-in user holds the account with the permission,
+// and sends the error response if not
+func (server *Server) authorizeAccount(ctx *gin.Context, authPayload *token.Payload, account db.Account, permission string) bool {
+	allowed, err := server.canAccessAccount(ctx, authPayload, account, permission)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return false
+	}
+	if !allowed {
+		err := fmt.Errorf("account [%d] does not belong to the authenticated user", account.ID)
+		ctx.JSON(http.StatusUnauthorized, errorResponse(err))
+		return false
+	}
+	return true
+}
+
+// listAccountHolders lists the users sharing an account and their permissions
+func (server *Server) listAccountHolders(ctx *gin.Context) {
+	var uri getAccountRequest
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	account, ok := server.findAccount(ctx, uri.ID)
+	if !ok {
+		return
+	}
+
+	// API RULE: the holders of an account and the bankers can see who holds it
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	if !server.authorizeAccount(ctx, authPayload, account, db.PermissionView) {
+		return
+	}
+
+	holders, err := server.store.ListAccountHolders(ctx, account.ID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, holders)
+}
+
+type accountHolderRequest struct {
+	ID       int64  `uri:"id" binding:"required,min=1"`
+	Username string `uri:"username" binding:"required,alphanum"`
+}
+
+type updateAccountHolderRequest struct {
+	Permission string `json:"permission" binding:"required,oneof=view transfer manage"`
+}
+
+// findHolderAccount gets the account of the URI of a holder, and checks that the logged-in user can manage its holders.
+// It sends the error response if not
+func (server *Server) findHolderAccount(ctx *gin.Context, uri accountHolderRequest) (db.Account, bool) {
+	account, ok := server.findAccount(ctx, uri.ID)
+	if !ok {
+		return account, false
+	}
+
+	// API RULE: the permission of the owner can't change, the owner always manages the account
+	if uri.Username == account.Owner {
+		err := errors.New("the owner of the account can't be changed")
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return account, false
+	}
+	return account, true
+}
+
+// updateAccountHolder shares an account with a user, or changes the permission of a holder.
+// Only the holders with the manage permission can change the holders of an account
+func (server *Server) updateAccountHolder(ctx *gin.Context) {
+	var uri accountHolderRequest
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	var req updateAccountHolderRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	account, ok := server.findHolderAccount(ctx, uri)
+	if !ok {
+		return
+	}
+
+	// API RULE: only the holders with the manage permission share an account, bankers can't grant access to it
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	if !server.authorizeAccount(ctx, authPayload, account, db.PermissionManage) {
+		return
+	}
+
+	holder, err := server.store.UpsertAccountHolder(ctx, db.UpsertAccountHolderParams{
+		AccountID:  account.ID,
+		Username:   uri.Username,
+		Permission: req.Permission,
+	})
+	if err != nil {
+		// the user doesn't exist
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code.Name() == "foreign_key_violation" {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, holder)
+}
+
+// deleteAccountHolder stops sharing an account with a holder.
+// The holders with the manage permission can remove a holder, and a holder can leave the account
+func (server *Server) deleteAccountHolder(ctx *gin.Context) {
+	var uri accountHolderRequest
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	account, ok := server.findHolderAccount(ctx, uri)
+	if !ok {
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	if authPayload.Username != uri.Username && !server.authorizeAccount(ctx, authPayload, account, db.PermissionManage) {
+		return
+	}
+
+	holder, err := server.store.DeleteAccountHolder(ctx, db.DeleteAccountHolderParams{
+		AccountID: account.ID,
+		Username:  uri.Username,
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, holder)
+}
@@ -0,0 +1,163 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	db "db.sqlc.dev/app/db/sqlc"
+	"db.sqlc.dev/app/money"
+	"db.sqlc.dev/app/token"
+	"github.com/gin-gonic/gin"
+)
+
+// errAccountNotOwned: the from account of a transfer of a batch doesn't belong to the authenticated user
+var errAccountNotOwned = errors.New("from account does not belong to the authenticated user")
+
+type batchTransferRequest struct {
+	// dive: validate each transfer of the batch like the request of a single transfer
+	Transfers []transferRequest `json:"transfers" binding:"required,min=1,max=500,dive"`
+	// Atomic: all the transfers are made or none of them, otherwise each transfer is made independently
+	Atomic bool `json:"atomic"`
+}
+
+// batchTransferItemResponse is the outcome of a transfer of a batch,
+// Status and Error are the status code and the error of the response of the same single transfer
+type batchTransferItemResponse struct {
+	Index  int                 `json:"index"`
+	Status int                 `json:"status"`
+	Result *transferTxResponse `json:"result,omitempty"`
+	Error  string              `json:"error,omitempty"`
+	Code   string              `json:"code,omitempty"`
+}
+
+type batchTransferResponse struct {
+	Results   []batchTransferItemResponse `json:"results"`
+	Succeeded int                         `json:"succeeded"`
+	Failed    int                         `json:"failed"`
+}
+
+// addResult adds the successful transfer of the index to the response
+func (rsp *batchTransferResponse) addResult(index int, result transferTxResponse) {
+	rsp.Results = append(rsp.Results, batchTransferItemResponse{
+		Index:  index,
+		Status: http.StatusOK,
+		Result: &result,
+	})
+	rsp.Succeeded++
+}
+
+// addError adds the failed transfer of the index to the response
+func (rsp *batchTransferResponse) addError(index int, err error) {
+	status, body := transferErrorResponse(err)
+	code, _ := body["code"].(string)
+	rsp.Results = append(rsp.Results, batchTransferItemResponse{
+		Index:  index,
+		Status: status,
+		Error:  err.Error(),
+		Code:   code,
+	})
+	rsp.Failed++
+}
+
+// createBatchTransfer makes a batch of transfers of the authenticated user, e.g. the payments of a payroll run.
+// An atomic batch fails with the error of its first failed transfer and moves no money,
+// otherwise the response has the outcome of each transfer
+func (server *Server) createBatchTransfer(ctx *gin.Context) {
+	var req batchTransferRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	// the transfers of a batch often share their from account, each account is read once
+	accounts := make(map[int64]db.Account)
+	args := make([]db.TransferTxParams, len(req.Transfers))
+	errs := make([]error, len(req.Transfers))
+	for i, item := range req.Transfers {
+		args[i], errs[i] = server.batchTransferTxParams(ctx, item, authPayload.Username, accounts)
+		if errs[i] != nil && req.Atomic {
+			writeBatchTransferError(ctx, i, errs[i])
+			return
+		}
+	}
+
+	var rsp batchTransferResponse
+	if req.Atomic {
+		results, err := server.store.BatchTransferTx(ctx, args)
+		if err != nil {
+			var batchErr *db.BatchTransferError
+			if errors.As(err, &batchErr) {
+				writeBatchTransferError(ctx, batchErr.Index, batchErr.Err)
+				return
+			}
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+			return
+		}
+
+		for i, result := range results {
+			rsp.addResult(i, newTransferTxResponse(server.currencies, result))
+		}
+		ctx.JSON(http.StatusOK, rsp)
+		return
+	}
+
+	for i, arg := range args {
+		if errs[i] != nil {
+			rsp.addError(i, errs[i])
+			continue
+		}
+
+		result, err := server.store.TransferTx(ctx, arg)
+		if err != nil {
+			rsp.addError(i, err)
+			continue
+		}
+		rsp.addResult(i, newTransferTxResponse(server.currencies, result))
+	}
+	ctx.JSON(http.StatusOK, rsp)
+}
+
+// writeBatchTransferError sends the response of the failed transfer of the index of an atomic batch
+func writeBatchTransferError(ctx *gin.Context, index int, err error) {
+	status, body := transferErrorResponse(fmt.Errorf("transfer %d of the batch: %w", index, err))
+	body["index"] = index
+	ctx.JSON(status, body)
+}
+
+// batchTransferTxParams checks the accounts of a transfer of a batch and prices it like newTransferTxParams,
+// accounts holds the accounts already read for the batch
+func (server *Server) batchTransferTxParams(ctx context.Context, req transferRequest, username string, accounts map[int64]db.Account) (db.TransferTxParams, error) {
+	var arg db.TransferTxParams
+
+	fromAccount, err := server.cachedAccount(ctx, req.FromAccountID, accounts)
+	if err != nil {
+		return arg, err
+	}
+	if fromAccount.Currency != req.Amount.Currency {
+		return arg, fmt.Errorf("%w: account [%d] currency %s vs %s", money.ErrCurrencyMismatch, fromAccount.ID, fromAccount.Currency, req.Amount.Currency)
+	}
+	held, err := server.holdsAccount(ctx, username, fromAccount, db.PermissionTransfer)
+	if err != nil {
+		return arg, err
+	}
+	if !held {
+		return arg, fmt.Errorf("%w: account [%d]", errAccountNotOwned, fromAccount.ID)
+	}
+
+	toAccount, err := server.cachedAccount(ctx, req.ToAccountID, accounts)
+	if err != nil {
+		return arg, err
+	}
+
+	arg = db.TransferTxParams{
+		FromAccountID: req.FromAccountID,
+		ToAccountID:   req.ToAccountID,
+		Amount:        req.Amount,
+	}
+	err = server.priceTransfer(ctx, &arg, fromAccount, toAccount)
+	return arg, err
+}
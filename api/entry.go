@@ -0,0 +1,108 @@
+package api
+
+import (
+	"database/sql"
+	"net/http"
+
+	db "db.sqlc.dev/app/db/sqlc"
+	"db.sqlc.dev/app/token"
+	"github.com/gin-gonic/gin"
+)
+
+type listEntriesRequest struct {
+	AccountID int64 `uri:"id" binding:"required,min=1"`
+}
+
+type listEntriesQuery struct {
+	pageQuery
+	historyFilter
+}
+
+// listEntries lists the entries of an account: the credits are incoming, the debits are outgoing,
+// and the amount filters apply to the absolute amount of the entries
+func (server *Server) listEntries(ctx *gin.Context) {
+	var req listEntriesRequest
+	if err := ctx.ShouldBindUri(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	var query listEntriesQuery
+	if err := ctx.ShouldBindQuery(&query); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+	if err := query.validate(); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+	if err := query.validatePage(); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+	afterID, err := query.afterID()
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	account, err := server.store.GetAccount(ctx, req.AccountID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	// API RULE: A logged-in depositor can only list entries of accounts that he/she holds,
+	// a banker can list the entries of any account
+	if !server.authorizeAccount(ctx, authPayload, account, db.PermissionView) {
+		return
+	}
+
+	var entries []db.Entry
+	if query.cursorMode() {
+		// read one more entry to know if there is a next page
+		entries, err = server.store.ListAccountEntriesAfter(ctx, db.ListAccountEntriesAfterParams{
+			AccountID: account.ID,
+			Direction: query.Direction,
+			StartTime: nullTime(query.StartTime),
+			EndTime:   nullTime(query.EndTime),
+			MinAmount: nullInt64(query.MinAmount),
+			MaxAmount: nullInt64(query.MaxAmount),
+			AfterID:   afterID,
+			Limit:     query.PageSize + 1,
+		})
+	} else {
+		entries, err = server.store.ListAccountEntries(ctx, db.ListAccountEntriesParams{
+			AccountID: account.ID,
+			Direction: query.Direction,
+			StartTime: nullTime(query.StartTime),
+			EndTime:   nullTime(query.EndTime),
+			MinAmount: nullInt64(query.MinAmount),
+			MaxAmount: nullInt64(query.MaxAmount),
+			Limit:     query.PageSize,
+			Offset:    query.offset(),
+		})
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	var nextCursor string
+	if len(entries) > int(query.PageSize) {
+		entries = entries[:query.PageSize]
+		nextCursor = encodeCursor(entries[len(entries)-1].ID)
+	}
+
+	rsp := make([]entryResponse, len(entries))
+	for i, entry := range entries {
+		rsp[i] = newEntryResponse(server.currencies, entry, account.Currency)
+	}
+	query.sendPage(ctx, rsp, nextCursor)
+}
@@ -0,0 +1,87 @@
+package api
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	db "db.sqlc.dev/app/db/sqlc"
+	"github.com/gin-gonic/gin"
+)
+
+// idempotencyKeyHeader: header of the key chosen by the client to identify a request and its retries
+const idempotencyKeyHeader = "Idempotency-Key"
+
+// maxIdempotencyKeyLength limits the size of the keys stored in the database
+const maxIdempotencyKeyLength = 255
+
+// idempotencyKey reads the optional idempotency key of the request, ok is false if the key is invalid
+// and a response has been sent
+func idempotencyKey(ctx *gin.Context) (key string, ok bool) {
+	key = ctx.GetHeader(idempotencyKeyHeader)
+	if len(key) > maxIdempotencyKeyLength {
+		err := fmt.Errorf("%s header must be at most %d characters", idempotencyKeyHeader, maxIdempotencyKeyLength)
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return "", false
+	}
+	return key, true
+}
+
+// hashRequest returns the hash of the bound request, to detect a key reused with a different request
+func hashRequest(req interface{}) (string, error) {
+	data, err := json.Marshal(req)
+	if err != nil {
+		return "", err
+	}
+
+	hash := sha256.Sum256(data)
+	return hex.EncodeToString(hash[:]), nil
+}
+
+// replayIdempotentRequest sends the stored response of a request already processed with the same idempotency key,
+// rendered by the render function like the response of the first request.
+// It returns false if the key hasn't been used yet, so that the request must be processed
+func (server *Server) replayIdempotentRequest(
+	ctx *gin.Context,
+	username string,
+	key string,
+	requestHash string,
+	render func(stored json.RawMessage) (interface{}, error),
+) bool {
+	stored, err := server.store.GetIdempotencyKey(ctx, db.GetIdempotencyKeyParams{
+		Username:       username,
+		IdempotencyKey: key,
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return false
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return true
+	}
+
+	// an expired key can be used again
+	if !time.Now().Before(stored.ExpiresAt) {
+		return false
+	}
+
+	// API RULE: an idempotency key can't be reused with a different request
+	if stored.RequestHash != requestHash {
+		err := errors.New("idempotency key has already been used with a different request")
+		ctx.JSON(http.StatusConflict, errorCodeResponse(errCodeIdempotencyKeyReused, err))
+		return true
+	}
+
+	rsp, err := render(stored.ResponseBody)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return true
+	}
+	ctx.JSON(http.StatusOK, rsp)
+	return true
+}
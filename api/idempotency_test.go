@@ -0,0 +1,305 @@
+package api
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	mockdb "db.sqlc.dev/app/db/mock"
+	db "db.sqlc.dev/app/db/sqlc"
+	"db.sqlc.dev/app/money"
+	"db.sqlc.dev/app/util"
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+// eqTransferTxIdempotencyMatcher matches the TransferTx params with an idempotency key,
+// whose expiration time depends on the time of the request
+type eqTransferTxIdempotencyMatcher struct {
+	arg db.TransferTxParams
+}
+
+func (e eqTransferTxIdempotencyMatcher) Matches(x interface{}) bool {
+	arg, ok := x.(db.TransferTxParams)
+	if !ok || arg.IdempotencyKey == nil || e.arg.IdempotencyKey == nil {
+		return false
+	}
+
+	key := *arg.IdempotencyKey
+	expected := *e.arg.IdempotencyKey
+	if key.ExpiresAt.Sub(expected.ExpiresAt).Abs() > time.Second {
+		return false
+	}
+	key.ExpiresAt = expected.ExpiresAt
+
+	return arg.FromAccountID == e.arg.FromAccountID &&
+		arg.ToAccountID == e.arg.ToAccountID &&
+		arg.Amount == e.arg.Amount &&
+		key == expected
+}
+
+func (e eqTransferTxIdempotencyMatcher) String() string {
+	return fmt.Sprintf("matches arg %v and idempotency key %v", e.arg, e.arg.IdempotencyKey)
+}
+
+func EqTransferTxIdempotency(arg db.TransferTxParams) gomock.Matcher {
+	return eqTransferTxIdempotencyMatcher{arg}
+}
+
+func TestTransferIdempotencyKeyAPI(t *testing.T) {
+	amount := int64(10)
+	key := util.RandomString(16)
+
+	user1, _ := randomUser(t)
+	user2, _ := randomUser(t)
+
+	account1 := randomAccount(user1.Username)
+	account2 := randomAccount(user2.Username)
+	account1.Currency = util.USD
+	account2.Currency = util.USD
+
+	body := gin.H{
+		"from_account_id": account1.ID,
+		"to_account_id":   account2.ID,
+		"amount":          gin.H{"minor": amount, "currency": util.USD},
+	}
+	requestHash, err := hashRequest(transferRequest{
+		FromAccountID: account1.ID,
+		ToAccountID:   account2.ID,
+		Amount:        money.New(amount, util.USD),
+	})
+	require.NoError(t, err)
+
+	result := db.TransferTxResult{
+		Transfer:    randomTransfer(account1.ID, account2.ID),
+		FromAccount: account1,
+		ToAccount:   account2,
+	}
+	storedBody, err := json.Marshal(result)
+	require.NoError(t, err)
+	// the stored result is rendered like the response of the first request
+	responseBody, err := json.Marshal(newTransferTxResponse(newTestCurrencyRegistry(t), result))
+	require.NoError(t, err)
+
+	storedKey := db.IdempotencyKey{
+		Username:       user1.Username,
+		IdempotencyKey: key,
+		RequestHash:    requestHash,
+		ResponseBody:   storedBody,
+		ExpiresAt:      time.Now().Add(time.Hour),
+	}
+	getKeyArg := db.GetIdempotencyKeyParams{
+		Username:       user1.Username,
+		IdempotencyKey: key,
+	}
+	transferArg := db.TransferTxParams{
+		FromAccountID: account1.ID,
+		ToAccountID:   account2.ID,
+		Amount:        money.New(amount, util.USD),
+		IdempotencyKey: &db.TransferIdempotencyKey{
+			Username:    user1.Username,
+			Key:         key,
+			RequestHash: requestHash,
+			ExpiresAt:   time.Now().Add(time.Hour),
+		},
+	}
+
+	testCases := []struct {
+		name          string
+		key           string
+		body          gin.H
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(recoder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "FirstRequest",
+			key:  key,
+			body: body,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetIdempotencyKey(gomock.Any(), gomock.Eq(getKeyArg)).Times(1).Return(db.IdempotencyKey{}, sql.ErrNoRows)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).Times(1).Return(account1, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account2.ID)).Times(1).Return(account2, nil)
+				store.EXPECT().TransferTx(gomock.Any(), EqTransferTxIdempotency(transferArg)).Times(1).Return(result, nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				require.JSONEq(t, string(responseBody), recorder.Body.String())
+			},
+		},
+		{
+			name: "Retry",
+			key:  key,
+			body: body,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetIdempotencyKey(gomock.Any(), gomock.Eq(getKeyArg)).Times(1).Return(storedKey, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				require.JSONEq(t, string(responseBody), recorder.Body.String())
+			},
+		},
+		{
+			name: "DifferentRequest",
+			key:  key,
+			body: gin.H{
+				"from_account_id": account1.ID,
+				"to_account_id":   account2.ID,
+				"amount":          gin.H{"minor": amount + 1, "currency": util.USD},
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetIdempotencyKey(gomock.Any(), gomock.Eq(getKeyArg)).Times(1).Return(storedKey, nil)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusConflict, recorder.Code)
+
+				var rsp gin.H
+				err := json.Unmarshal(recorder.Body.Bytes(), &rsp)
+				require.NoError(t, err)
+				require.Equal(t, errCodeIdempotencyKeyReused, rsp["code"])
+			},
+		},
+		{
+			name: "ExpiredKey",
+			key:  key,
+			body: body,
+			buildStubs: func(store *mockdb.MockStore) {
+				expiredKey := storedKey
+				expiredKey.ExpiresAt = time.Now().Add(-time.Minute)
+
+				store.EXPECT().GetIdempotencyKey(gomock.Any(), gomock.Eq(getKeyArg)).Times(1).Return(expiredKey, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).Times(1).Return(account1, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account2.ID)).Times(1).Return(account2, nil)
+				store.EXPECT().TransferTx(gomock.Any(), EqTransferTxIdempotency(transferArg)).Times(1).Return(result, nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name: "ConcurrentRetry",
+			key:  key,
+			body: body,
+			buildStubs: func(store *mockdb.MockStore) {
+				// the concurrent retry stores its result while this request is processed
+				gomock.InOrder(
+					store.EXPECT().GetIdempotencyKey(gomock.Any(), gomock.Eq(getKeyArg)).Times(1).Return(db.IdempotencyKey{}, sql.ErrNoRows),
+					store.EXPECT().TransferTx(gomock.Any(), EqTransferTxIdempotency(transferArg)).Times(1).Return(db.TransferTxResult{}, db.ErrIdempotencyKeyInUse),
+					store.EXPECT().GetIdempotencyKey(gomock.Any(), gomock.Eq(getKeyArg)).Times(1).Return(storedKey, nil),
+				)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).Times(1).Return(account1, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account2.ID)).Times(1).Return(account2, nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				require.JSONEq(t, string(responseBody), recorder.Body.String())
+			},
+		},
+		{
+			name: "ConcurrentRetryExpiredKey",
+			key:  key,
+			body: body,
+			buildStubs: func(store *mockdb.MockStore) {
+				expiredKey := storedKey
+				expiredKey.ExpiresAt = time.Now().Add(-time.Minute)
+
+				// the key stored by the concurrent request has expired, so its response isn't replayed
+				gomock.InOrder(
+					store.EXPECT().GetIdempotencyKey(gomock.Any(), gomock.Eq(getKeyArg)).Times(1).Return(db.IdempotencyKey{}, sql.ErrNoRows),
+					store.EXPECT().TransferTx(gomock.Any(), EqTransferTxIdempotency(transferArg)).Times(1).Return(db.TransferTxResult{}, db.ErrIdempotencyKeyInUse),
+					store.EXPECT().GetIdempotencyKey(gomock.Any(), gomock.Eq(getKeyArg)).Times(1).Return(expiredKey, nil),
+				)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).Times(1).Return(account1, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account2.ID)).Times(1).Return(account2, nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusConflict, recorder.Code)
+
+				var rsp gin.H
+				err := json.Unmarshal(recorder.Body.Bytes(), &rsp)
+				require.NoError(t, err)
+				require.Equal(t, errCodeIdempotencyKeyReused, rsp["code"])
+			},
+		},
+		{
+			name: "KeyTooLong",
+			key:  strings.Repeat("k", maxIdempotencyKeyLength+1),
+			body: body,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetIdempotencyKey(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "GetIdempotencyKeyError",
+			key:  key,
+			body: body,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetIdempotencyKey(gomock.Any(), gomock.Any()).Times(1).Return(db.IdempotencyKey{}, sql.ErrConnDone)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+		{
+			name: "NoKey",
+			body: body,
+			buildStubs: func(store *mockdb.MockStore) {
+				arg := db.TransferTxParams{
+					FromAccountID: account1.ID,
+					ToAccountID:   account2.ID,
+					Amount:        money.New(amount, util.USD),
+				}
+
+				store.EXPECT().GetIdempotencyKey(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).Times(1).Return(account1, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account2.ID)).Times(1).Return(account2, nil)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Eq(arg)).Times(1).Return(result, nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			data, err := json.Marshal(tc.body)
+			require.NoError(t, err)
+
+			request, err := http.NewRequest(http.MethodPost, "/transfers", bytes.NewReader(data))
+			require.NoError(t, err)
+			if tc.key != "" {
+				request.Header.Set(idempotencyKeyHeader, tc.key)
+			}
+
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, user1.Username, util.DepositorRole, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(recorder)
+		})
+	}
+}
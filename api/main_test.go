@@ -6,23 +6,62 @@ import (
 	"time"
 
 	db "db.sqlc.dev/app/db/sqlc"
+	"db.sqlc.dev/app/fx"
+	"db.sqlc.dev/app/token"
 	"db.sqlc.dev/app/util"
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/require"
 )
 
+// testMaxAccountsPerUser is the account limit of the users of the test server
+const testMaxAccountsPerUser = 10
+
 func newTestServer(t *testing.T, store db.Store) *Server {
 	config := util.Config{
-		TokenSymmetricKey:   util.RandomString(32),
-		AccessTokenDuration: time.Minute,
+		TokenSymmetricKey:       util.RandomString(32),
+		AccessTokenDuration:     time.Minute,
+		RefreshTokenDuration:    time.Hour,
+		IdempotencyKeyDuration:  time.Hour,
+		TransferHoldDuration:    time.Hour,
+		MaxTransferHoldDuration: 24 * time.Hour,
+		MaxAccountsPerUser:      testMaxAccountsPerUser,
 	}
 
 	server, err := NewServer(config, store)
 	require.NoError(t, err)
 
+	// keep revoked tokens in memory, so that the mock store only receives the calls expected by each test
+	server.revocationStore = token.NewCachedRevocationStore(nil, 0)
+	// fixed exchange rates of cross-currency transfers
+	server.rateProvider = fx.NewStaticRateProvider(
+		newTestRate(t, util.USD, util.EUR, "0.9"),
+		newTestRate(t, util.USD, util.CAD, "0.4"),
+		newTestRate(t, util.USD, "JPY", "150"),
+	)
+	server.currencies = newTestCurrencyRegistry(t)
+	server.setupRouter()
+
 	return server
 }
 
+func newTestRate(t *testing.T, from string, to string, value string) fx.Rate {
+	rate, err := fx.ParseRate(from, to, value)
+	require.NoError(t, err)
+	return rate
+}
+
+// newTestCurrencyRegistry returns the default currencies with currencies of other minor units
+func newTestCurrencyRegistry(t *testing.T) *util.CurrencyRegistry {
+	currencies := append([]util.Currency{
+		{Code: "JPY", NumericCode: "392", Exponent: 0},
+		{Code: "BHD", NumericCode: "048", Exponent: 3},
+	}, util.DefaultCurrencies...)
+
+	registry, err := util.NewCurrencyRegistry(currencies...)
+	require.NoError(t, err)
+	return registry
+}
+
 func TestMain(m *testing.M) {
 	gin.SetMode(gin.TestMode)
 	os.Exit(m.Run())
@@ -17,7 +17,7 @@ const (
 )
 
 // authMiddleware: high-order authentication function, returns the authentication middleware function(gin.HandlerFunc)
-func authMiddleware(tokenMaker token.Maker) gin.HandlerFunc {
+func authMiddleware(tokenMaker token.Maker, revocationStore token.RevocationStore) gin.HandlerFunc {
 	// define the authentication middleware function
 	return func(ctx *gin.Context) {
 		// extract the authorization header from the request
@@ -53,9 +53,45 @@ func authMiddleware(tokenMaker token.Maker) gin.HandlerFunc {
 			ctx.AbortWithStatusJSON(http.StatusUnauthorized, errorResponse(err))
 			return
 		}
+
+		// a refresh token lives much longer than an access token, it can only renew access tokens
+		if err := payload.CheckType(token.TokenTypeAccess); err != nil {
+			ctx.AbortWithStatusJSON(http.StatusUnauthorized, errorResponse(err))
+			return
+		}
+
+		// reject the token if it has been revoked before its expiration time, e.g. after logout
+		revoked, err := revocationStore.IsRevoked(ctx, payload)
+		if err != nil {
+			ctx.AbortWithStatusJSON(http.StatusInternalServerError, errorResponse(err))
+			return
+		}
+		if revoked {
+			ctx.AbortWithStatusJSON(http.StatusUnauthorized, errorResponse(token.ErrRevokedToken))
+			return
+		}
 		// store the payload in the context
 		ctx.Set(authorizationPayloadKey, payload)
 		// forward the request to the next handler
 		ctx.Next()
 	}
 }
+
+// authorizationMiddleware: high-order authorization function, returns a middleware function(gin.HandlerFunc)
+// that only forwards the request if the role of the logged-in user is one of the accessible roles.
+// It must be added after authMiddleware, which stores the payload of the access token in the context
+func authorizationMiddleware(accessibleRoles ...string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+		for _, role := range accessibleRoles {
+			if authPayload.Role == role {
+				ctx.Next()
+				return
+			}
+		}
+
+		err := fmt.Errorf("role %s is not allowed to access this API", authPayload.Role)
+		ctx.AbortWithStatusJSON(http.StatusForbidden, errorResponse(err))
+	}
+}
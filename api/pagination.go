@@ -0,0 +1,98 @@
+package api
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxOffsetPageSize limits the pages of the page_id mode, whose offset scans get slower with each page
+const maxOffsetPageSize = 10
+
+// pageQuery selects a page of a list, in one of two modes:
+//   - page_id mode: page_id is the number of the page, read with an offset scan
+//   - cursor mode: without page_id, the page starts after the cursor returned with the previous page,
+//     the first page has no cursor
+//
+// Both modes answer with a pageResponse. Legacy asks the page_id mode for the bare array of the items,
+// the shape of the lists before the cursor mode, kept for the clients that were not updated.
+type pageQuery struct {
+	PageID   int32  `form:"page_id" binding:"omitempty,min=1"`
+	PageSize int32  `form:"page_size" binding:"required,min=5,max=100"`
+	After    string `form:"after"`
+	Legacy   bool   `form:"legacy"`
+}
+
+// cursorMode returns true if the page is read after a cursor rather than by page_id
+func (query pageQuery) cursorMode() bool {
+	return query.PageID == 0
+}
+
+// validatePage checks the parameters of the mode of the page
+func (query pageQuery) validatePage() error {
+	if query.cursorMode() {
+		if query.Legacy {
+			return errors.New("legacy can only be used with page_id")
+		}
+		return nil
+	}
+	if query.After != "" {
+		return errors.New("page_id and after can't be used together")
+	}
+	if query.PageSize > maxOffsetPageSize {
+		return fmt.Errorf("page_size must be at most %d with page_id", maxOffsetPageSize)
+	}
+	return nil
+}
+
+// offset returns the number of items before the page of the page_id mode
+func (query pageQuery) offset() int32 {
+	return (query.PageID - 1) * query.PageSize
+}
+
+// pageCursor is the position of the last item of a page, sent to the clients as an opaque string
+type pageCursor struct {
+	ID int64 `json:"id"`
+}
+
+// encodeCursor returns the cursor of the page after the item of the input ID
+func encodeCursor(id int64) string {
+	data, _ := json.Marshal(pageCursor{ID: id})
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// afterID returns the ID of the last item before the page of the cursor mode, 0 for the first page
+func (query pageQuery) afterID() (int64, error) {
+	if query.After == "" {
+		return 0, nil
+	}
+
+	data, err := base64.RawURLEncoding.DecodeString(query.After)
+	if err != nil {
+		return 0, errors.New("invalid cursor")
+	}
+	var cursor pageCursor
+	if err := json.Unmarshal(data, &cursor); err != nil || cursor.ID <= 0 {
+		return 0, errors.New("invalid cursor")
+	}
+	return cursor.ID, nil
+}
+
+// pageResponse is a page of a list, NextCursor is empty on the last page and in page_id mode
+type pageResponse struct {
+	Items      interface{} `json:"items"`
+	NextCursor string      `json:"next_cursor,omitempty"`
+}
+
+// sendPage answers with the items of the page in a pageResponse, or as a bare array in legacy mode
+func (query pageQuery) sendPage(ctx *gin.Context, items interface{}, nextCursor string) {
+	if query.Legacy {
+		ctx.JSON(http.StatusOK, items)
+		return
+	}
+	ctx.JSON(http.StatusOK, pageResponse{Items: items, NextCursor: nextCursor})
+}
@@ -0,0 +1,62 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	db "db.sqlc.dev/app/db/sqlc"
+	"db.sqlc.dev/app/token"
+	"github.com/google/uuid"
+)
+
+// dbRevocationBackend stores token revocations in Postgres, which implements the token.RevocationBackend interface
+type dbRevocationBackend struct {
+	store db.Store
+}
+
+func newDBRevocationBackend(store db.Store) token.RevocationBackend {
+	return &dbRevocationBackend{store: store}
+}
+
+func (backend *dbRevocationBackend) RevokeToken(ctx context.Context, payload *token.Payload) error {
+	_, err := backend.store.CreateRevokedToken(ctx, db.CreateRevokedTokenParams{
+		ID:        payload.ID,
+		Username:  payload.Username,
+		ExpiresAt: payload.ExpiredAt,
+	})
+	return err
+}
+
+func (backend *dbRevocationBackend) IsTokenRevoked(ctx context.Context, tokenID uuid.UUID) (bool, error) {
+	_, err := backend.store.GetRevokedToken(ctx, tokenID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+func (backend *dbRevocationBackend) RevokeAllTokens(ctx context.Context, username string, revokedAt time.Time) error {
+	_, err := backend.store.UpdateUserTokensRevokedAt(ctx, db.UpdateUserTokensRevokedAtParams{
+		Username:        username,
+		TokensRevokedAt: revokedAt,
+	})
+	return err
+}
+
+// TokensRevokedAt returns the later of the last logout from all sessions and the last password change of the user,
+// since tokens issued before either of them must not be accepted anymore
+func (backend *dbRevocationBackend) TokensRevokedAt(ctx context.Context, username string) (time.Time, error) {
+	user, err := backend.store.GetUser(ctx, username)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	if user.PasswordChangedAt.After(user.TokensRevokedAt) {
+		return user.PasswordChangedAt, nil
+	}
+	return user.TokensRevokedAt, nil
+}
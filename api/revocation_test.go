@@ -0,0 +1,55 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	mockdb "db.sqlc.dev/app/db/mock"
+	db "db.sqlc.dev/app/db/sqlc"
+	"db.sqlc.dev/app/token"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRevocationAfterPasswordChange(t *testing.T) {
+	user, _ := randomUser(t)
+	user.TokensRevokedAt = time.Now().Add(-time.Hour)
+
+	oldPayload, err := token.NewPayload(user.Username, user.Role, token.TokenTypeAccess, time.Minute)
+	require.NoError(t, err)
+
+	user.PasswordChangedAt = time.Now()
+
+	newPayload, err := token.NewPayload(user.Username, user.Role, token.TokenTypeAccess, time.Minute)
+	require.NoError(t, err)
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	// each lookup hits the database only once, the following checks are served from the cache
+	store.EXPECT().
+		GetUser(gomock.Any(), gomock.Eq(user.Username)).
+		Times(1).
+		Return(user, nil)
+	store.EXPECT().
+		GetRevokedToken(gomock.Any(), gomock.Eq(newPayload.ID)).
+		Times(1).
+		Return(db.RevokedToken{}, sql.ErrNoRows)
+
+	revocationStore := token.NewCachedRevocationStore(newDBRevocationBackend(store), time.Minute)
+
+	for i := 0; i < 2; i++ {
+		// token issued before the password change
+		revoked, err := revocationStore.IsRevoked(context.Background(), oldPayload)
+		require.NoError(t, err)
+		require.True(t, revoked)
+
+		// token issued after the password change
+		revoked, err = revocationStore.IsRevoked(context.Background(), newPayload)
+		require.NoError(t, err)
+		require.False(t, revoked)
+	}
+}
@@ -0,0 +1,446 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	db "db.sqlc.dev/app/db/sqlc"
+	"db.sqlc.dev/app/money"
+	"db.sqlc.dev/app/scheduler"
+	"db.sqlc.dev/app/token"
+	"db.sqlc.dev/app/util"
+	"github.com/gin-gonic/gin"
+	"github.com/lib/pq"
+)
+
+type createScheduledTransferRequest struct {
+	FromAccountID int64 `json:"from_account_id" binding:"required,min=1"`
+	ToAccountID   int64 `json:"to_account_id" binding:"required,min=1"`
+	// Amount: amount of each run in the currency of the from account
+	Amount money.Amount `json:"amount" binding:"required,gt=0"`
+	// Recurrence: cron expression or "@every <duration>" (see scheduler.ParseRecurrence), empty for a one-off transfer
+	Recurrence string `json:"recurrence"`
+	// StartAt: time of the one-off transfer, or start of the recurrence
+	StartAt time.Time `json:"start_at" binding:"required"`
+	// EndAt: optional end of the recurrence, no occurrence runs after it
+	EndAt *time.Time `json:"end_at"`
+}
+
+// scheduledTransferResponse renders the amount of a scheduled transfer both as money.Amount and as a decimal string
+type scheduledTransferResponse struct {
+	ID            int64        `json:"id"`
+	Owner         string       `json:"owner"`
+	FromAccountID int64        `json:"from_account_id"`
+	ToAccountID   int64        `json:"to_account_id"`
+	Amount        money.Amount `json:"amount"`
+	AmountDecimal string       `json:"amount_decimal,omitempty"`
+	Recurrence    string       `json:"recurrence"`
+	Status        string       `json:"status"`
+	NextRunAt     time.Time    `json:"next_run_at"`
+	NextAttemptAt time.Time    `json:"next_attempt_at"`
+	Attempts      int32        `json:"attempts"`
+	EndAt         *time.Time   `json:"end_at"`
+	CreatedAt     time.Time    `json:"created_at"`
+}
+
+func newScheduledTransferResponse(currencies *util.CurrencyRegistry, scheduled db.ScheduledTransfer) scheduledTransferResponse {
+	amount := money.New(scheduled.Amount, scheduled.Currency)
+	rsp := scheduledTransferResponse{
+		ID:            scheduled.ID,
+		Owner:         scheduled.Owner,
+		FromAccountID: scheduled.FromAccountID,
+		ToAccountID:   scheduled.ToAccountID,
+		Amount:        amount,
+		AmountDecimal: currencies.FormatAmount(amount),
+		Recurrence:    scheduled.Recurrence,
+		Status:        scheduled.Status,
+		NextRunAt:     scheduled.NextRunAt,
+		NextAttemptAt: scheduled.NextAttemptAt,
+		Attempts:      scheduled.Attempts,
+		CreatedAt:     scheduled.CreatedAt,
+	}
+	if scheduled.EndAt.Valid {
+		rsp.EndAt = &scheduled.EndAt.Time
+	}
+	return rsp
+}
+
+type scheduledTransferRunResponse struct {
+	ID         int64     `json:"id"`
+	RunAt      time.Time `json:"run_at"`
+	Attempt    int32     `json:"attempt"`
+	Status     string    `json:"status"`
+	TransferID *int64    `json:"transfer_id"`
+	Error      string    `json:"error,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+func newScheduledTransferRunResponse(run db.ScheduledTransferRun) scheduledTransferRunResponse {
+	rsp := scheduledTransferRunResponse{
+		ID:        run.ID,
+		RunAt:     run.RunAt,
+		Attempt:   run.Attempt,
+		Status:    run.Status,
+		Error:     run.Error,
+		CreatedAt: run.CreatedAt,
+	}
+	if run.TransferID.Valid {
+		rsp.TransferID = &run.TransferID.Int64
+	}
+	return rsp
+}
+
+// createScheduledTransfer schedules a one-off or recurring transfer from an account of the logged-in user
+func (server *Server) createScheduledTransfer(ctx *gin.Context) {
+	var req createScheduledTransferRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	// API RULE: a transfer can't be scheduled in the past, and its recurrence must be valid
+	if req.StartAt.Before(time.Now()) {
+		err := errors.New("start_at must not be in the past")
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+	nextRunAt, err := scheduler.FirstRun(req.Recurrence, req.StartAt)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+	endAt, ok := scheduledTransferEnd(ctx, req.Recurrence, nextRunAt, req.EndAt)
+	if !ok {
+		return
+	}
+
+	fromAccount, valid := server.validAccount(ctx, req.FromAccountID, req.Amount.Currency)
+	if !valid {
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	if !server.authorizeAccount(ctx, authPayload, fromAccount, db.PermissionTransfer) {
+		return
+	}
+
+	toAccount, valid := server.findAccount(ctx, req.ToAccountID)
+	if !valid {
+		return
+	}
+
+	// fail now rather than at each run if the transfer can't be priced, e.g. without exchange rate
+	arg := db.TransferTxParams{
+		FromAccountID: req.FromAccountID,
+		ToAccountID:   req.ToAccountID,
+		Amount:        req.Amount,
+	}
+	if err := server.priceTransfer(ctx, &arg, fromAccount, toAccount); err != nil {
+		writePricingError(ctx, err)
+		return
+	}
+
+	scheduled, err := server.store.CreateScheduledTransfer(ctx, db.CreateScheduledTransferParams{
+		Owner:         authPayload.Username,
+		FromAccountID: req.FromAccountID,
+		ToAccountID:   req.ToAccountID,
+		Amount:        req.Amount.Minor,
+		Currency:      req.Amount.Currency,
+		Recurrence:    req.Recurrence,
+		NextRunAt:     nextRunAt,
+		EndAt:         endAt,
+	})
+	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code.Name() == "foreign_key_violation" {
+			ctx.JSON(http.StatusForbidden, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, newScheduledTransferResponse(server.currencies, scheduled))
+}
+
+// scheduledTransferEnd checks the end of a recurrence, it returns false if it is invalid and a response has been sent
+func scheduledTransferEnd(ctx *gin.Context, recurrence string, nextRunAt time.Time, endAt *time.Time) (sql.NullTime, bool) {
+	if endAt == nil {
+		return sql.NullTime{}, true
+	}
+	if recurrence == "" {
+		err := errors.New("end_at is only allowed with a recurrence")
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return sql.NullTime{}, false
+	}
+	if endAt.Before(nextRunAt) {
+		err := fmt.Errorf("end_at must not be before the next run at %s", nextRunAt.Format(time.RFC3339))
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return sql.NullTime{}, false
+	}
+	return sql.NullTime{Time: *endAt, Valid: true}, true
+}
+
+type scheduledTransferRequest struct {
+	ID int64 `uri:"id" binding:"required,min=1"`
+}
+
+// findScheduledTransfer gets the scheduled transfer of the URI, and checks that the logged-in user can access it:
+// only its owner can change it, bankers can also read it.
+// It returns false if the transfer can't be accessed and a response has been sent
+func (server *Server) findScheduledTransfer(ctx *gin.Context, write bool) (db.ScheduledTransfer, bool) {
+	var req scheduledTransferRequest
+	if err := ctx.ShouldBindUri(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return db.ScheduledTransfer{}, false
+	}
+
+	scheduled, err := server.store.GetScheduledTransfer(ctx, req.ID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return scheduled, false
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return scheduled, false
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	if scheduled.Owner != authPayload.Username && (write || authPayload.Role != util.BankerRole) {
+		err := errors.New("scheduled transfer does not belong to the authenticated user")
+		ctx.JSON(http.StatusUnauthorized, errorResponse(err))
+		return scheduled, false
+	}
+	return scheduled, true
+}
+
+func (server *Server) getScheduledTransfer(ctx *gin.Context) {
+	scheduled, ok := server.findScheduledTransfer(ctx, false)
+	if !ok {
+		return
+	}
+
+	ctx.JSON(http.StatusOK, newScheduledTransferResponse(server.currencies, scheduled))
+}
+
+type listScheduledTransfersRequest struct {
+	PageID   int32 `form:"page_id" binding:"required,min=1"`
+	PageSize int32 `form:"page_size" binding:"required,min=5,max=10"`
+}
+
+// listScheduledTransfers lists the scheduled transfers of the logged-in user
+func (server *Server) listScheduledTransfers(ctx *gin.Context) {
+	var req listScheduledTransfersRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	scheduledTransfers, err := server.store.ListScheduledTransfers(ctx, db.ListScheduledTransfersParams{
+		Owner:  authPayload.Username,
+		Limit:  req.PageSize,
+		Offset: (req.PageID - 1) * req.PageSize,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	rsp := make([]scheduledTransferResponse, len(scheduledTransfers))
+	for i, scheduled := range scheduledTransfers {
+		rsp[i] = newScheduledTransferResponse(server.currencies, scheduled)
+	}
+	ctx.JSON(http.StatusOK, rsp)
+}
+
+type updateScheduledTransferRequest struct {
+	// the omitted fields keep their value
+	// Amount: in the currency of the transfer, which can't be changed
+	Amount     *money.Amount `json:"amount" binding:"omitempty,gt=0"`
+	Recurrence *string       `json:"recurrence"`
+	EndAt      *time.Time    `json:"end_at"`
+	// Status: pause or resume the transfer
+	Status *string `json:"status" binding:"omitempty,oneof=active paused"`
+}
+
+// updateScheduledTransfer changes the amount, the recurrence or the end of a scheduled transfer, or pauses and resumes it
+func (server *Server) updateScheduledTransfer(ctx *gin.Context) {
+	scheduled, ok := server.findScheduledTransfer(ctx, true)
+	if !ok {
+		return
+	}
+
+	var req updateScheduledTransferRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	// API RULE: a completed or failed transfer can't be changed, a new transfer must be scheduled
+	if scheduled.Status != scheduler.StatusActive && scheduled.Status != scheduler.StatusPaused {
+		err := fmt.Errorf("scheduled transfer is %s", scheduled.Status)
+		ctx.JSON(http.StatusUnprocessableEntity, errorResponse(err))
+		return
+	}
+
+	arg := db.UpdateScheduledTransferParams{
+		ID:         scheduled.ID,
+		Amount:     scheduled.Amount,
+		Recurrence: scheduled.Recurrence,
+		Status:     scheduled.Status,
+		NextRunAt:  scheduled.NextRunAt,
+		EndAt:      scheduled.EndAt,
+	}
+	if req.Amount != nil {
+		if req.Amount.Currency != scheduled.Currency {
+			err := fmt.Errorf("%w: scheduled transfer currency %s vs %s", money.ErrCurrencyMismatch, scheduled.Currency, req.Amount.Currency)
+			ctx.JSON(http.StatusBadRequest, errorResponse(err))
+			return
+		}
+		arg.Amount = req.Amount.Minor
+	}
+	if req.Status != nil {
+		arg.Status = *req.Status
+	}
+
+	now := time.Now()
+	if req.Recurrence != nil && *req.Recurrence != scheduled.Recurrence {
+		// the new recurrence starts at the planned next run, or now if it is overdue
+		start := scheduled.NextRunAt
+		if start.Before(now) {
+			start = now
+		}
+		nextRunAt, err := scheduler.FirstRun(*req.Recurrence, start)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, errorResponse(err))
+			return
+		}
+		arg.Recurrence = *req.Recurrence
+		arg.NextRunAt = nextRunAt
+	} else if scheduled.Status == scheduler.StatusPaused && arg.Status == scheduler.StatusActive && arg.Recurrence != "" &&
+		arg.NextRunAt.Before(now) {
+		// a resumed recurring transfer skips the occurrences missed while it was paused
+		recurrence, err := scheduler.ParseRecurrence(arg.Recurrence)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+			return
+		}
+		arg.NextRunAt = recurrence.Next(now)
+	}
+
+	if req.EndAt != nil {
+		arg.EndAt.Time = *req.EndAt
+		arg.EndAt.Valid = true
+	} else if arg.Recurrence == "" {
+		// a transfer changed into a one-off transfer has no end
+		arg.EndAt = sql.NullTime{}
+	}
+	if arg.EndAt.Valid {
+		endAt := arg.EndAt.Time
+		var ok bool
+		if arg.EndAt, ok = scheduledTransferEnd(ctx, arg.Recurrence, arg.NextRunAt, &endAt); !ok {
+			return
+		}
+	}
+
+	scheduled, err := server.store.UpdateScheduledTransfer(ctx, arg)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, newScheduledTransferResponse(server.currencies, scheduled))
+}
+
+// deleteScheduledTransfer cancels a scheduled transfer and deletes its runs, the executed transfers are kept
+func (server *Server) deleteScheduledTransfer(ctx *gin.Context) {
+	scheduled, ok := server.findScheduledTransfer(ctx, true)
+	if !ok {
+		return
+	}
+
+	if err := server.store.DeleteScheduledTransfer(ctx, scheduled.ID); err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{})
+}
+
+type listScheduledTransferRunsRequest struct {
+	PageID   int32 `form:"page_id" binding:"required,min=1"`
+	PageSize int32 `form:"page_size" binding:"required,min=5,max=10"`
+}
+
+// listScheduledTransferRuns lists the runs of a scheduled transfer, the latest first
+func (server *Server) listScheduledTransferRuns(ctx *gin.Context) {
+	scheduled, ok := server.findScheduledTransfer(ctx, false)
+	if !ok {
+		return
+	}
+
+	var req listScheduledTransferRunsRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	runs, err := server.store.ListScheduledTransferRuns(ctx, db.ListScheduledTransferRunsParams{
+		ScheduledTransferID: scheduled.ID,
+		Limit:               req.PageSize,
+		Offset:              (req.PageID - 1) * req.PageSize,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	rsp := make([]scheduledTransferRunResponse, len(runs))
+	for i, run := range runs {
+		rsp[i] = newScheduledTransferRunResponse(run)
+	}
+	ctx.JSON(http.StatusOK, rsp)
+}
+
+// ExecuteScheduledTransfer executes the occurrence next_run_at of a scheduled transfer with Store.TransferTx,
+// priced like a transfer requested by its owner. It implements scheduler.Executor
+func (server *Server) ExecuteScheduledTransfer(ctx context.Context, scheduled db.ScheduledTransfer) (db.TransferTxResult, error) {
+	fromAccount, err := server.store.GetAccount(ctx, scheduled.FromAccountID)
+	if err != nil {
+		return db.TransferTxResult{}, fmt.Errorf("cannot get from account [%d]: %w", scheduled.FromAccountID, err)
+	}
+	held, err := server.holdsAccount(ctx, scheduled.Owner, fromAccount, db.PermissionTransfer)
+	if err != nil {
+		return db.TransferTxResult{}, fmt.Errorf("cannot check holder of from account [%d]: %w", fromAccount.ID, err)
+	}
+	if !held {
+		return db.TransferTxResult{}, fmt.Errorf("from account [%d] no longer belongs to %s", fromAccount.ID, scheduled.Owner)
+	}
+
+	toAccount, err := server.store.GetAccount(ctx, scheduled.ToAccountID)
+	if err != nil {
+		return db.TransferTxResult{}, fmt.Errorf("cannot get to account [%d]: %w", scheduled.ToAccountID, err)
+	}
+
+	arg := db.TransferTxParams{
+		FromAccountID: scheduled.FromAccountID,
+		ToAccountID:   scheduled.ToAccountID,
+		Amount:        money.New(scheduled.Amount, scheduled.Currency),
+	}
+	if err := server.priceTransfer(ctx, &arg, fromAccount, toAccount); err != nil {
+		return db.TransferTxResult{}, err
+	}
+
+	// the succeeded run is recorded with the transfer, a repeated run of the occurrence returns db.ErrOccurrenceExecuted
+	arg.ScheduledRun = &db.ScheduledTransferOccurrence{
+		ScheduledTransferID: scheduled.ID,
+		RunAt:               scheduled.NextRunAt,
+		Attempt:             scheduled.Attempts + 1,
+	}
+	return server.store.TransferTx(ctx, arg)
+}
@@ -1,9 +1,13 @@
 package api
 
 import (
+	"context"
 	"fmt"
 
 	db "db.sqlc.dev/app/db/sqlc"
+	"db.sqlc.dev/app/fee"
+	"db.sqlc.dev/app/fx"
+	"db.sqlc.dev/app/money"
 	"db.sqlc.dev/app/token"
 	"db.sqlc.dev/app/util"
 	"github.com/gin-gonic/gin"
@@ -18,53 +22,194 @@ type Server struct {
 	// see store.go for Store struct
 	router     *gin.Engine // send each API request to the correct handler for processing
 	tokenMaker token.Maker
+	// revocationStore keeps track of tokens that are revoked before they expire
+	revocationStore token.RevocationStore
+	// rateProvider gives the exchange rates of transfers between accounts of different currencies
+	rateProvider fx.RateProvider
+	// currencies: supported currencies and their minor units
+	currencies *util.CurrencyRegistry
+	// feeSchedule gives the fees charged on transfers
+	feeSchedule *fee.Schedule
 }
 
 // NewServer creates a new Server instance, and setup all HTTP API routes for our service on that server.
 func NewServer(config util.Config, store db.Store) (*Server, error) {
-	// initialize a tokenMaker with symmetric key defined in config
-	// Can CHANGE NewPasetoMaker to NewJWTMaker
-	tokenMaker, err := token.NewPasetoMaker(config.TokenSymmetricKey)
+	// initialize a tokenMaker of the type defined in config
+	tokenMaker, err := newTokenMaker(config)
 	if err != nil {
 		return nil, fmt.Errorf("cannot create token maker: %w", err)
 	}
 
+	rateProvider, err := newRateProvider(config)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create exchange rate provider: %w", err)
+	}
+
+	currencies, err := newCurrencyRegistry(config)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create currency registry: %w", err)
+	}
+
+	feeSchedule, err := newFeeSchedule(config)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create fee schedule: %w", err)
+	}
+	if err := checkFeeAccounts(context.Background(), store, currencies, feeSchedule); err != nil {
+		return nil, fmt.Errorf("invalid fee schedule: %w", err)
+	}
+
 	server := &Server{
-		config:     config,
-		store:      store,
-		tokenMaker: tokenMaker,
+		config:          config,
+		store:           store,
+		tokenMaker:      tokenMaker,
+		revocationStore: token.NewCachedRevocationStore(newDBRevocationBackend(store), config.RevocationCacheDuration),
+		rateProvider:    rateProvider,
+		currencies:      currencies,
+		feeSchedule:     feeSchedule,
 	}
 
 	// register custom validator(validCurrency) with Gin
 	if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
-		v.RegisterValidation("currency", validCurrency)
+		v.RegisterValidation("currency", server.validCurrency)
+		v.RegisterCustomTypeFunc(amountValue, money.Amount{})
 	}
 
 	server.setupRouter()
 	return server, nil
 }
 
+// newTokenMaker creates the token maker selected by config.TokenType
+func newTokenMaker(config util.Config) (token.Maker, error) {
+	// a keyring signs the tokens with its active key and verifies the tokens of the older keys,
+	// so that the keys can be rotated without invalidating the issued tokens
+	if config.TokenKeyringPath != "" {
+		return token.LoadKeyring(config.TokenType, config.TokenKeyringPath)
+	}
+
+	switch config.TokenType {
+	case token.TypePaseto, "":
+		return token.NewPasetoMaker(config.TokenSymmetricKey)
+	case token.TypeJWT:
+		return token.NewJWTMaker(config.TokenSymmetricKey)
+	case token.TypePasetoPublic, token.TypeJWTEdDSA:
+		privateKey, err := token.LoadEd25519PrivateKey(config.TokenPrivateKeyPath)
+		if err != nil {
+			return nil, err
+		}
+		publicKey, err := token.LoadEd25519PublicKey(config.TokenPublicKeyPath)
+		if err != nil {
+			return nil, err
+		}
+
+		if config.TokenType == token.TypePasetoPublic {
+			return token.NewPasetoPublicMaker(privateKey, publicKey)
+		}
+		return token.NewJWTEdDSAMaker(privateKey, publicKey)
+	}
+	return nil, fmt.Errorf("unsupported token type %s", config.TokenType)
+}
+
+// newRateProvider creates the exchange rate provider of the rate file defined in config,
+// without rate file only transfers between accounts of the same currency are possible
+func newRateProvider(config util.Config) (fx.RateProvider, error) {
+	if config.FXRatesPath == "" {
+		return fx.NewStaticRateProvider(), nil
+	}
+	return fx.LoadRateFile(config.FXRatesPath)
+}
+
+// newCurrencyRegistry loads the currencies of the currency file defined in config,
+// without currency file the default currencies are supported
+func newCurrencyRegistry(config util.Config) (*util.CurrencyRegistry, error) {
+	if config.CurrenciesPath == "" {
+		return util.DefaultCurrencyRegistry(), nil
+	}
+	return util.LoadCurrencyRegistry(config.CurrenciesPath)
+}
+
+// newFeeSchedule loads the fee rules of the fee file defined in config,
+// without fee file the transfers are free
+func newFeeSchedule(config util.Config) (*fee.Schedule, error) {
+	if config.FeesPath == "" {
+		return fee.NewSchedule(nil, nil)
+	}
+	return fee.LoadSchedule(config.FeesPath)
+}
+
+// checkFeeAccounts checks that the house fee account of each currency of the schedule exists in the currency,
+// so that a wrong fee file stops the server instead of failing the transfers charged with a fee
+func checkFeeAccounts(ctx context.Context, store db.Store, currencies *util.CurrencyRegistry, schedule *fee.Schedule) error {
+	for currency, id := range schedule.FeeAccounts() {
+		if !currencies.IsSupported(currency) {
+			return fmt.Errorf("%w: fee account currency %s", errUnsupportedCurrency, currency)
+		}
+
+		account, err := store.GetAccount(ctx, id)
+		if err != nil {
+			return fmt.Errorf("cannot get fee account [%d] of %s: %w", id, currency, err)
+		}
+		if account.Currency != currency {
+			return fmt.Errorf("fee account [%d] of %s has currency %s", id, currency, account.Currency)
+		}
+	}
+	return nil
+}
+
 func (server *Server) setupRouter() {
 	router := gin.Default()
 
 	// Server API for user:
 	router.POST("/users", server.createUser)
 	router.POST("/users/login", server.loginUser)
+	// renew the access token with the refresh token returned by login API
+	router.POST("/tokens/renew_access", server.renewAccessToken)
+	// public keys of the tokens, for the services verifying them
+	router.GET("/.well-known/jwks.json", server.getJWKS)
 
 	// PROTECT all other APIs by the authorization middleware
 	// create a group of routes using router.Group with path prefix "/" and add the authMiddleware using .Use()
-	authRoutes := router.Group("/").Use(authMiddleware(server.tokenMaker))
+	authRoutes := router.Group("/").Use(authMiddleware(server.tokenMaker, server.revocationStore))
+
+	// Server API for user logout: revoke the tokens of the logged in user
+	authRoutes.POST("/users/logout", server.logoutUser)
+	authRoutes.POST("/users/logout_all", server.logoutAllUser)
+	authRoutes.POST("/users/change_password", server.changeUserPassword)
 
 	// Server API for Account:
 	// add routes to router
+	// any user can create accounts and move money from the accounts he/she holds: the banker role gives no right
+	// on the money of the depositors, only the reading of their data and the changes of their overdraft limits and statuses
 	authRoutes.POST("/accounts", server.createAccount)
 	// add a : before id to tell Gin that id is a URI parameter
 	authRoutes.GET("/accounts/:id", server.getAccount)
 	// to get list of accounts, obtain page_id & page_size from query
 	authRoutes.GET("/accounts", server.listAccount)
+	authRoutes.GET("/accounts/:id/transfers", server.listTransfers)
+	authRoutes.GET("/accounts/:id/entries", server.listEntries)
+	authRoutes.PUT("/accounts/:id/overdraft_limit", authorizationMiddleware(util.BankerRole), server.updateOverdraftLimit)
+	authRoutes.PUT("/accounts/:id/status", authorizationMiddleware(util.BankerRole), server.updateAccountStatus)
+	authRoutes.POST("/accounts/:id/close", server.closeAccount)
+	authRoutes.GET("/accounts/:id/holders", server.listAccountHolders)
+	authRoutes.PUT("/accounts/:id/holders/:username", server.updateAccountHolder)
+	authRoutes.DELETE("/accounts/:id/holders/:username", server.deleteAccountHolder)
 
 	// Server API for transfer:
 	authRoutes.POST("/transfers", server.createTransfer)
+	authRoutes.GET("/transfers/:id", server.getTransfer)
+	authRoutes.POST("/transfers/:id/reverse", server.reverseTransfer)
+	authRoutes.POST("/transfers/batch", server.createBatchTransfer)
+	// two-phase transfers: the money is held on the from account until the transfer is captured or voided
+	authRoutes.POST("/transfers/authorize", server.authorizeTransfer)
+	authRoutes.POST("/transfers/:id/capture", server.captureTransfer)
+	authRoutes.POST("/transfers/:id/void", server.voidTransfer)
+
+	// Server API for scheduled transfer, executed by the scheduler when they are due:
+	authRoutes.POST("/scheduled-transfers", server.createScheduledTransfer)
+	authRoutes.GET("/scheduled-transfers", server.listScheduledTransfers)
+	authRoutes.GET("/scheduled-transfers/:id", server.getScheduledTransfer)
+	authRoutes.PATCH("/scheduled-transfers/:id", server.updateScheduledTransfer)
+	authRoutes.DELETE("/scheduled-transfers/:id", server.deleteScheduledTransfer)
+	authRoutes.GET("/scheduled-transfers/:id/runs", server.listScheduledTransferRuns)
 
 	server.router = router
 }
@@ -83,3 +228,35 @@ func (server *Server) Start(address string) error {
 func errorResponse(err error) gin.H {
 	return gin.H{"error": err.Error()}
 }
+
+// Stable error codes returned with the error message, for the clients that need to handle specific errors
+const (
+	errCodeInsufficientFunds    = "insufficient_funds"
+	errCodeOverdraftLimitTooLow = "overdraft_limit_too_low"
+	errCodeIdempotencyKeyReused = "idempotency_key_reused"
+	// errCodeExchangeRateUnavailable: no exchange rate is known between the currencies of a transfer
+	errCodeExchangeRateUnavailable = "exchange_rate_unavailable"
+	// errCodeReversalExceedsTransfer: the reversals of a transfer can't give back more than its amount
+	errCodeReversalExceedsTransfer = "reversal_exceeds_transfer"
+	// errCodeTransferNotReversible: a reversal can't be reversed
+	errCodeTransferNotReversible = "transfer_not_reversible"
+	// errCodeTransferNotPending: only a pending transfer can be captured or voided
+	errCodeTransferNotPending = "transfer_not_pending"
+	// errCodeHoldExpired: the hold of a pending transfer has expired before its capture
+	errCodeHoldExpired = "hold_expired"
+	// errCodeAccountLimitReached: a user can't own more than MaxAccountsPerUser open accounts
+	errCodeAccountLimitReached = "account_limit_reached"
+	// errCodeAccountNicknameTaken: the accounts of a user in a currency must have different nicknames
+	errCodeAccountNicknameTaken = "account_nickname_taken"
+	// errCodeAccountClosed: a closed account can't send nor receive money
+	errCodeAccountClosed = "account_closed"
+	// errCodeAccountFrozen: a frozen account can't send money, nor receive it if it is fully frozen
+	errCodeAccountFrozen = "account_frozen"
+	// errCodeAccountNotSettled: an account can only be closed without money, debt or pending transfer
+	errCodeAccountNotSettled = "account_not_settled"
+)
+
+// errorCodeResponse converts error msg and its stable error code into a key-value object
+func errorCodeResponse(code string, err error) gin.H {
+	return gin.H{"error": err.Error(), "code": code}
+}
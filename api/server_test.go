@@ -0,0 +1,85 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	mockdb "db.sqlc.dev/app/db/mock"
+	db "db.sqlc.dev/app/db/sqlc"
+	"db.sqlc.dev/app/fee"
+	"db.sqlc.dev/app/util"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckFeeAccounts(t *testing.T) {
+	feeAccount := randomAccount(util.RandomOwner())
+	feeAccount.Currency = util.USD
+
+	testCases := []struct {
+		name        string
+		feeAccounts map[string]int64
+		buildStubs  func(store *mockdb.MockStore)
+		checkResult func(err error)
+	}{
+		{
+			name:        "OK",
+			feeAccounts: map[string]int64{util.USD: feeAccount.ID},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(feeAccount.ID)).Times(1).Return(feeAccount, nil)
+			},
+			checkResult: func(err error) {
+				require.NoError(t, err)
+			},
+		},
+		{
+			name:        "AccountNotFound",
+			feeAccounts: map[string]int64{util.USD: feeAccount.ID},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(feeAccount.ID)).Times(1).Return(db.Account{}, sql.ErrNoRows)
+			},
+			checkResult: func(err error) {
+				require.ErrorIs(t, err, sql.ErrNoRows)
+			},
+		},
+		{
+			name:        "CurrencyMismatch",
+			feeAccounts: map[string]int64{util.EUR: feeAccount.ID},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(feeAccount.ID)).Times(1).Return(feeAccount, nil)
+			},
+			checkResult: func(err error) {
+				require.Error(t, err)
+			},
+		},
+		{
+			name:        "UnsupportedCurrency",
+			feeAccounts: map[string]int64{"XXX": feeAccount.ID},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResult: func(err error) {
+				require.ErrorIs(t, err, errUnsupportedCurrency)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			schedule, err := fee.NewSchedule(nil, tc.feeAccounts)
+			require.NoError(t, err)
+
+			err = checkFeeAccounts(context.Background(), store, newTestCurrencyRegistry(t), schedule)
+			tc.checkResult(err)
+		})
+	}
+}
@@ -1,13 +1,19 @@
 package api
 
 import (
+	"context"
 	"database/sql"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"net/http"
+	"time"
 
 	db "db.sqlc.dev/app/db/sqlc"
+	"db.sqlc.dev/app/fx"
+	"db.sqlc.dev/app/money"
 	"db.sqlc.dev/app/token"
+	"db.sqlc.dev/app/util"
 	"github.com/gin-gonic/gin"
 )
 
@@ -15,13 +21,108 @@ import (
 type transferRequest struct {
 	FromAccountID int64 `json:"from_account_id" binding:"required,min=1"`
 	ToAccountID   int64 `json:"to_account_id" binding:"required,min=1"`
-	// gt=0: require Amount to be greater than 0
-	Amount   int64  `json:"amount" binding:"required,gt=0"`
-	Currency string `json:"currency" binding:"required,currency"`
+	// Amount: the currency of the amount must be the currency of the from account.
+	// The to account can have another currency, the amount is converted at the current exchange rate.
+	// gt=0: require the minor units of Amount to be greater than 0
+	Amount money.Amount `json:"amount" binding:"required,gt=0"`
 }
 
-// validAccount checks if an account with a specific ID really exists, and its currency matches the input currency
-func (server *Server) validAccount(ctx *gin.Context, accountID int64, currency string) (db.Account, bool) {
+// transferResponse renders the amounts of a transfer both as money.Amount and as decimal strings,
+// amount is in the currency of the from account and to_amount in the currency of the to account
+type transferResponse struct {
+	db.Transfer
+	// ReversalOf: ID of the transfer reversed by this transfer, replaces the nullable field of db.Transfer
+	ReversalOf *int64 `json:"reversal_of,omitempty"`
+	// Amount, ToAmount and Fee replace the minor units of db.Transfer
+	Amount          money.Amount `json:"amount"`
+	ToAmount        money.Amount `json:"to_amount"`
+	Fee             money.Amount `json:"fee"`
+	AmountDecimal   string       `json:"amount_decimal,omitempty"`
+	ToAmountDecimal string       `json:"to_amount_decimal,omitempty"`
+	FeeDecimal      string       `json:"fee_decimal,omitempty"`
+}
+
+// newTransferResponse renders a transfer from an account of currency from to an account of currency to
+func newTransferResponse(currencies *util.CurrencyRegistry, transfer db.Transfer, from string, to string) transferResponse {
+	amount := money.New(transfer.Amount, from)
+	toAmount := money.New(transfer.ToAmount, to)
+	fee := money.New(transfer.Fee, from)
+	rsp := transferResponse{
+		Transfer:        transfer,
+		Amount:          amount,
+		ToAmount:        toAmount,
+		Fee:             fee,
+		AmountDecimal:   currencies.FormatAmount(amount),
+		ToAmountDecimal: currencies.FormatAmount(toAmount),
+		FeeDecimal:      currencies.FormatAmount(fee),
+	}
+	if transfer.ReversalOf.Valid {
+		rsp.ReversalOf = &transfer.ReversalOf.Int64
+	}
+	return rsp
+}
+
+// entryResponse renders the amount of an entry in the currency of its account
+type entryResponse struct {
+	db.Entry
+	// Amount replaces the minor units of db.Entry
+	Amount        money.Amount `json:"amount"`
+	AmountDecimal string       `json:"amount_decimal,omitempty"`
+}
+
+func newEntryResponse(currencies *util.CurrencyRegistry, entry db.Entry, currency string) entryResponse {
+	amount := money.New(entry.Amount, currency)
+	return entryResponse{
+		Entry:         entry,
+		Amount:        amount,
+		AmountDecimal: currencies.FormatAmount(amount),
+	}
+}
+
+type transferTxResponse struct {
+	Transfer                transferResponse `json:"transfer"`
+	FromAccount             accountResponse  `json:"from_account"`
+	ToAccount               accountResponse  `json:"to_account"`
+	FromEntry               entryResponse    `json:"from_entry"`
+	ToEntry                 entryResponse    `json:"to_entry"`
+	FeeEntry                *entryResponse   `json:"fee_entry,omitempty"`
+	AvailableBalance        money.Amount     `json:"available_balance"`
+	AvailableBalanceDecimal string           `json:"available_balance_decimal,omitempty"`
+}
+
+func newTransferTxResponse(currencies *util.CurrencyRegistry, result db.TransferTxResult) transferTxResponse {
+	from := result.FromAccount.Currency
+	to := result.ToAccount.Currency
+
+	availableBalance := money.New(result.AvailableBalance, from)
+	rsp := transferTxResponse{
+		Transfer:                newTransferResponse(currencies, result.Transfer, from, to),
+		FromAccount:             newAccountResponse(currencies, result.FromAccount),
+		ToAccount:               newAccountResponse(currencies, result.ToAccount),
+		FromEntry:               newEntryResponse(currencies, result.FromEntry, from),
+		ToEntry:                 newEntryResponse(currencies, result.ToEntry, to),
+		AvailableBalance:        availableBalance,
+		AvailableBalanceDecimal: currencies.FormatAmount(availableBalance),
+	}
+	// a transfer without fee has no fee entry
+	if result.FeeEntry.ID != 0 {
+		feeEntry := newEntryResponse(currencies, result.FeeEntry, from)
+		rsp.FeeEntry = &feeEntry
+	}
+	return rsp
+}
+
+// renderTransferTxResult renders the stored result of a transfer, to replay the response of an idempotent request
+func (server *Server) renderTransferTxResult(stored json.RawMessage) (interface{}, error) {
+	var result db.TransferTxResult
+	if err := json.Unmarshal(stored, &result); err != nil {
+		return nil, err
+	}
+	return newTransferTxResponse(server.currencies, result), nil
+}
+
+// findAccount gets the account with a specific ID, and sends the error response if it doesn't exist
+func (server *Server) findAccount(ctx *gin.Context, accountID int64) (db.Account, bool) {
 	account, err := server.store.GetAccount(ctx, accountID)
 	if err != nil {
 		if err == sql.ErrNoRows {
@@ -33,6 +134,16 @@ func (server *Server) validAccount(ctx *gin.Context, accountID int64, currency s
 		return account, false
 	}
 
+	return account, true
+}
+
+// validAccount checks if an account with a specific ID really exists, and its currency matches the input currency
+func (server *Server) validAccount(ctx *gin.Context, accountID int64, currency string) (db.Account, bool) {
+	account, ok := server.findAccount(ctx, accountID)
+	if !ok {
+		return account, false
+	}
+
 	if account.Currency != currency {
 		err := fmt.Errorf("account [%d] currency mismatch: %s vs %s", account.ID, account.Currency, currency)
 		ctx.JSON(http.StatusBadRequest, errorResponse(err))
@@ -54,43 +165,486 @@ func (server *Server) createTransfer(ctx *gin.Context) {
 		return
 	}
 
-	fromAccount, valid := server.validAccount(ctx, req.FromAccountID, req.Currency)
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
 
-	// STEP 2.: check if accounts exist and match the currency,
-	// also check if account owner stored in payload is the owner of fromAccount
-	if !valid {
+	// a retry of a request with an Idempotency-Key header gets the response of the first request,
+	// without moving the money again
+	key, ok := idempotencyKey(ctx)
+	if !ok {
 		return
 	}
+	var requestHash string
+	if key != "" {
+		var err error
+		requestHash, err = hashRequest(req)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+			return
+		}
+		if server.replayIdempotentRequest(ctx, authPayload.Username, key, requestHash, server.renderTransferTxResult) {
+			return
+		}
+	}
 
-	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
-	if fromAccount.Owner != authPayload.Username {
-		err := errors.New("From account does not belong to the authenticated user")
-		ctx.JSON(http.StatusUnauthorized, errorResponse(err)) // call ctx.JSON() function to send a JSON response
+	arg, ok := server.newTransferTxParams(ctx, req, authPayload)
+	if !ok {
 		return
 	}
+	if key != "" {
+		arg.IdempotencyKey = &db.TransferIdempotencyKey{
+			Username:    authPayload.Username,
+			Key:         key,
+			RequestHash: requestHash,
+			ExpiresAt:   time.Now().Add(server.config.IdempotencyKeyDuration),
+		}
+	}
 
-	_, valid = server.validAccount(ctx, req.ToAccountID, req.Currency)
-	if !valid {
+	// Function TransferTx defined in ./db/store.go
+	result, err := server.store.TransferTx(ctx, arg)
+	// send JSON response with 500 Internal Server Error status code to client if err is not nil
+	if err != nil {
+		// a concurrent retry of the request has stored its result first, the transfer has been rolled back
+		if errors.Is(err, db.ErrIdempotencyKeyInUse) && server.replayIdempotentRequest(ctx, authPayload.Username, key, requestHash, server.renderTransferTxResult) {
+			return
+		}
+		// API RULE: the from account must have enough money for the transfer, and both accounts must be open
+		ctx.JSON(transferErrorResponse(err))
 		return
 	}
 
-	// STEP 3.: insert the new transfer into the database;
+	// send a 200 OK status code to client if no error;
+	ctx.JSON(http.StatusOK, newTransferTxResponse(server.currencies, result))
+
+}
+
+// newTransferTxParams checks the accounts of a transfer request and prices the transfer,
+// it sends the error response if the transfer can't be made by the authenticated user
+func (server *Server) newTransferTxParams(ctx *gin.Context, req transferRequest, authPayload *token.Payload) (db.TransferTxParams, bool) {
+	var arg db.TransferTxParams
+
+	// check if accounts exist and match the currency,
+	// also check if the user stored in payload holds fromAccount with the transfer permission
+	fromAccount, valid := server.validAccount(ctx, req.FromAccountID, req.Amount.Currency)
+	if !valid {
+		return arg, false
+	}
+
+	if !server.authorizeAccount(ctx, authPayload, fromAccount, db.PermissionTransfer) {
+		return arg, false
+	}
+
+	toAccount, valid := server.findAccount(ctx, req.ToAccountID)
+	if !valid {
+		return arg, false
+	}
+
 	// TransferTxParams struct defined in ./db/store.go
-	arg := db.TransferTxParams{
+	arg = db.TransferTxParams{
 		FromAccountID: req.FromAccountID,
 		ToAccountID:   req.ToAccountID,
 		Amount:        req.Amount,
 	}
+	// a transfer between currencies credits the to account with the converted amount
+	if err := server.priceTransfer(ctx, &arg, fromAccount, toAccount); err != nil {
+		writePricingError(ctx, err)
+		return arg, false
+	}
+	return arg, true
+}
 
-	// Function TransferTx defined in ./db/store.go
-	result, err := server.store.TransferTx(ctx, arg)
-	// send JSON response with 500 Internal Server Error status code to client if err is not nil
+// Errors of priceTransfer, besides fx.ErrRateNotFound
+var (
+	errUnsupportedCurrency = errors.New("currency is not supported")
+	errInvalidAmount       = errors.New("invalid amount")
+	// errFeeAccountMissing: the fee schedule has no fee account for the currency of a fee, answered with 500
+	errFeeAccountMissing = errors.New("no fee account for the currency")
+)
+
+// priceTransfer sets the amount credited to the to account in its currency with the applied exchange rate,
+// and the fee of the transfer with the house account receiving it
+func (server *Server) priceTransfer(ctx context.Context, arg *db.TransferTxParams, fromAccount db.Account, toAccount db.Account) error {
+	if toAccount.Currency != fromAccount.Currency {
+		if err := server.convertTransfer(ctx, arg, fromAccount.Currency, toAccount.Currency); err != nil {
+			return err
+		}
+	}
+	return server.chargeFee(arg, fromAccount)
+}
+
+// writePricingError sends the response of an error of priceTransfer
+func writePricingError(ctx *gin.Context, err error) {
+	ctx.JSON(transferErrorResponse(err))
+}
+
+// transferErrorResponse returns the status and the body of the response of an error of a transfer:
+// an error of the checks of its accounts, of priceTransfer, of TransferTx or of ReverseTransferTx
+func transferErrorResponse(err error) (int, gin.H) {
+	switch {
+	case errors.Is(err, db.ErrReversalExceedsTransfer):
+		return http.StatusUnprocessableEntity, errorCodeResponse(errCodeReversalExceedsTransfer, err)
+	case errors.Is(err, db.ErrTransferNotReversible):
+		return http.StatusUnprocessableEntity, errorCodeResponse(errCodeTransferNotReversible, err)
+	case errors.Is(err, db.ErrReversalTooSmall):
+		return http.StatusBadRequest, errorResponse(err)
+	case errors.Is(err, fx.ErrRateNotFound):
+		return http.StatusUnprocessableEntity, errorCodeResponse(errCodeExchangeRateUnavailable, err)
+	case errors.Is(err, errUnsupportedCurrency):
+		return http.StatusUnprocessableEntity, errorResponse(err)
+	case errors.Is(err, errInvalidAmount), errors.Is(err, money.ErrCurrencyMismatch):
+		return http.StatusBadRequest, errorResponse(err)
+	case errors.Is(err, db.ErrInsufficientFunds):
+		return http.StatusUnprocessableEntity, errorCodeResponse(errCodeInsufficientFunds, err)
+	case errors.Is(err, db.ErrIdempotencyKeyInUse):
+		// the key is used by a request whose response can't be replayed, the client must retry with a new key
+		return http.StatusConflict, errorCodeResponse(errCodeIdempotencyKeyReused, err)
+	case errors.Is(err, db.ErrAccountClosed):
+		return http.StatusUnprocessableEntity, errorCodeResponse(errCodeAccountClosed, err)
+	case errors.Is(err, db.ErrAccountFrozen):
+		return http.StatusUnprocessableEntity, errorCodeResponse(errCodeAccountFrozen, err)
+	case errors.Is(err, errAccountNotOwned):
+		return http.StatusUnauthorized, errorResponse(err)
+	case errors.Is(err, sql.ErrNoRows):
+		return http.StatusNotFound, errorResponse(err)
+	default:
+		return http.StatusInternalServerError, errorResponse(err)
+	}
+}
+
+// convertTransfer sets the amount credited to the to account in its currency and the applied exchange rate
+func (server *Server) convertTransfer(ctx context.Context, arg *db.TransferTxParams, from string, to string) error {
+	rate, err := server.rateProvider.Rate(ctx, from, to)
+	if err != nil {
+		return err
+	}
+
+	// the rate applies to the major units, e.g. 1 USD = 150 JPY converts 100 USD cents into 150 yen
+	fromCurrency, ok := server.currencies.Lookup(from)
+	if !ok {
+		return fmt.Errorf("%w: %s", errUnsupportedCurrency, from)
+	}
+	toCurrency, ok := server.currencies.Lookup(to)
+	if !ok {
+		return fmt.Errorf("%w: %s", errUnsupportedCurrency, to)
+	}
+
+	toAmount, err := rate.ConvertAmount(arg.Amount, fromCurrency.Exponent, toCurrency.Exponent)
+	if err != nil {
+		return fmt.Errorf("%w: %v", errInvalidAmount, err)
+	}
+	// API RULE: the to account must receive at least 1 minor unit of its currency
+	if !toAmount.IsPositive() {
+		return fmt.Errorf("%w: %s is too small to be converted into %s", errInvalidAmount, arg.Amount, to)
+	}
+
+	arg.ToAmount = toAmount
+	arg.ExchangeRate = rate.String()
+	return nil
+}
+
+// chargeFee sets the fee of the transfer from an account of its type and the house account receiving it
+func (server *Server) chargeFee(arg *db.TransferTxParams, fromAccount db.Account) error {
+	fee, err := server.feeSchedule.Fee(arg.Amount, fromAccount.Type)
+	if err != nil {
+		return fmt.Errorf("%w: %v", errInvalidAmount, err)
+	}
+	if fee.IsZero() {
+		return nil
+	}
+
+	// the schedule has a fee account for the currency of each rule, a fee without fee account can't be credited
+	feeAccountID, ok := server.feeSchedule.FeeAccountID(fee.Currency)
+	if !ok {
+		return fmt.Errorf("%w: %s", errFeeAccountMissing, fee.Currency)
+	}
+	arg.Fee = fee
+	arg.FeeAccountID = feeAccountID
+	return nil
+}
+
+type listTransfersRequest struct {
+	AccountID int64 `uri:"id" binding:"required,min=1"`
+}
+
+type listTransfersQuery struct {
+	pageQuery
+	historyFilter
+}
+
+// historyFilter filters the transfers or the entries of an account, all the filters are optional
+type historyFilter struct {
+	// StartTime and EndTime: RFC 3339 times of the period, StartTime is included and EndTime excluded
+	StartTime *time.Time `form:"start_time"`
+	EndTime   *time.Time `form:"end_time"`
+	// MinAmount and MaxAmount: bounds of the amount in minor units of the currency of the account
+	MinAmount *int64 `form:"min_amount" binding:"omitempty,min=0"`
+	MaxAmount *int64 `form:"max_amount" binding:"omitempty,min=0"`
+	// Direction: incoming for the money received by the account, outgoing for the money taken from it
+	Direction string `form:"direction" binding:"omitempty,oneof=incoming outgoing"`
+}
+
+// validate checks that the bounds of the filter are in order
+func (filter historyFilter) validate() error {
+	if filter.StartTime != nil && filter.EndTime != nil && !filter.EndTime.After(*filter.StartTime) {
+		return errors.New("end_time must be after start_time")
+	}
+	if filter.MinAmount != nil && filter.MaxAmount != nil && *filter.MaxAmount < *filter.MinAmount {
+		return errors.New("max_amount must not be lower than min_amount")
+	}
+	return nil
+}
+
+// nullTime returns the nullable column value of an optional time
+func nullTime(t *time.Time) sql.NullTime {
+	if t == nil {
+		return sql.NullTime{}
+	}
+	return sql.NullTime{Time: *t, Valid: true}
+}
+
+// nullInt64 returns the nullable column value of an optional integer
+func nullInt64(n *int64) sql.NullInt64 {
+	if n == nil {
+		return sql.NullInt64{}
+	}
+	return sql.NullInt64{Int64: *n, Valid: true}
+}
+
+// listTransfers lists the incoming and outgoing transfers of an account,
+// the amount filters apply to the amount taken from or given to the account in its currency
+func (server *Server) listTransfers(ctx *gin.Context) {
+	var req listTransfersRequest
+	if err := ctx.ShouldBindUri(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	var query listTransfersQuery
+	if err := ctx.ShouldBindQuery(&query); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+	if err := query.validate(); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+	if err := query.validatePage(); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+	afterID, err := query.afterID()
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	account, err := server.store.GetAccount(ctx, req.AccountID)
 	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return
+		}
 		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
 		return
 	}
 
-	// send a 200 OK status code to client if no error;
-	ctx.JSON(http.StatusOK, result)
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	// API RULE: A logged-in depositor can only list transfers of accounts that he/she holds,
+	// a banker can list the transfers of any account
+	if !server.authorizeAccount(ctx, authPayload, account, db.PermissionView) {
+		return
+	}
+
+	var transfers []db.Transfer
+	if query.cursorMode() {
+		// read one more transfer to know if there is a next page
+		transfers, err = server.store.ListAccountTransfersAfter(ctx, db.ListAccountTransfersAfterParams{
+			AccountID: account.ID,
+			Direction: query.Direction,
+			StartTime: nullTime(query.StartTime),
+			EndTime:   nullTime(query.EndTime),
+			MinAmount: nullInt64(query.MinAmount),
+			MaxAmount: nullInt64(query.MaxAmount),
+			AfterID:   afterID,
+			Limit:     query.PageSize + 1,
+		})
+	} else {
+		transfers, err = server.store.ListAccountTransfers(ctx, db.ListAccountTransfersParams{
+			AccountID: account.ID,
+			Direction: query.Direction,
+			StartTime: nullTime(query.StartTime),
+			EndTime:   nullTime(query.EndTime),
+			MinAmount: nullInt64(query.MinAmount),
+			MaxAmount: nullInt64(query.MaxAmount),
+			Limit:     query.PageSize,
+			Offset:    query.offset(),
+		})
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	var nextCursor string
+	if len(transfers) > int(query.PageSize) {
+		transfers = transfers[:query.PageSize]
+		nextCursor = encodeCursor(transfers[len(transfers)-1].ID)
+	}
+
+	// the amounts of the other account are in its currency, each other account is read once per page
+	accounts := map[int64]db.Account{account.ID: account}
+	rsp := make([]transferResponse, len(transfers))
+	for i, transfer := range transfers {
+		fromAccount, err := server.cachedAccount(ctx, transfer.FromAccountID, accounts)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+			return
+		}
+		toAccount, err := server.cachedAccount(ctx, transfer.ToAccountID, accounts)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+			return
+		}
+		rsp[i] = newTransferResponse(server.currencies, transfer, fromAccount.Currency, toAccount.Currency)
+	}
+	query.sendPage(ctx, rsp, nextCursor)
+}
+
+// cachedAccount gets an account from the accounts already read or from the store
+func (server *Server) cachedAccount(ctx context.Context, accountID int64, accounts map[int64]db.Account) (db.Account, error) {
+	if account, ok := accounts[accountID]; ok {
+		return account, nil
+	}
+
+	account, err := server.store.GetAccount(ctx, accountID)
+	if err != nil {
+		return account, fmt.Errorf("cannot get account [%d]: %w", accountID, err)
+	}
+	accounts[accountID] = account
+	return account, nil
+}
+
+type getTransferRequest struct {
+	ID int64 `uri:"id" binding:"required,min=1"`
+}
+
+// getTransfer gets a transfer of an account of the authenticated user
+func (server *Server) getTransfer(ctx *gin.Context) {
+	var req getTransferRequest
+	if err := ctx.ShouldBindUri(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	transfer, err := server.store.GetTransfer(ctx, req.ID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	fromAccount, ok := server.findAccount(ctx, transfer.FromAccountID)
+	if !ok {
+		return
+	}
+	toAccount, ok := server.findAccount(ctx, transfer.ToAccountID)
+	if !ok {
+		return
+	}
+
+	// API RULE: A logged-in depositor can only get the transfers from or to the accounts that he/she holds,
+	// a banker can get any transfer
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	allowed, err := server.canAccessAnyAccount(ctx, authPayload, db.PermissionView, fromAccount, toAccount)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	if !allowed {
+		err := errors.New("transfer does not belong to the authenticated user")
+		ctx.JSON(http.StatusUnauthorized, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, newTransferResponse(server.currencies, transfer, fromAccount.Currency, toAccount.Currency))
+}
+
+type reverseTransferRequest struct {
+	ID int64 `uri:"id" binding:"required,min=1"`
+}
+
+type reverseTransferBody struct {
+	// Amount: optional amount given back to the from account in its currency, the whole transfer is reversed without it
+	Amount *money.Amount `json:"amount" binding:"omitempty,gt=0"`
+}
+
+type reverseTransferResponse struct {
+	transferTxResponse
+	OriginalTransfer transferResponse `json:"original_transfer"`
+}
+
+// reverseTransfer gives back all or part of a transfer to its from account, e.g. to refund a payment.
+// The owner of the to account and the bankers can reverse a transfer, the fee of the transfer is not refunded
+func (server *Server) reverseTransfer(ctx *gin.Context) {
+	var req reverseTransferRequest
+	if err := ctx.ShouldBindUri(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	// the body is optional
+	var body reverseTransferBody
+	if ctx.Request.ContentLength != 0 {
+		if err := ctx.ShouldBindJSON(&body); err != nil {
+			ctx.JSON(http.StatusBadRequest, errorResponse(err))
+			return
+		}
+	}
+
+	transfer, err := server.store.GetTransfer(ctx, req.ID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	toAccount, ok := server.findAccount(ctx, transfer.ToAccountID)
+	if !ok {
+		return
+	}
+
+	// API RULE: the money is given back by a holder of the to account with the transfer permission, or by a banker
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	if authPayload.Role != util.BankerRole && !server.authorizeAccount(ctx, authPayload, toAccount, db.PermissionTransfer) {
+		return
+	}
+
+	arg := db.ReverseTransferTxParams{
+		TransferID: transfer.ID,
+	}
+	if body.Amount != nil {
+		if _, valid := server.validAccount(ctx, transfer.FromAccountID, body.Amount.Currency); !valid {
+			return
+		}
+		arg.Amount = body.Amount.Minor
+	}
+
+	result, err := server.store.ReverseTransferTx(ctx, arg)
+	if err != nil {
+		ctx.JSON(transferErrorResponse(err))
+		return
+	}
 
+	// the original transfer goes in the opposite direction of the reversal
+	original := newTransferResponse(server.currencies, result.OriginalTransfer, result.ToAccount.Currency, result.FromAccount.Currency)
+	ctx.JSON(http.StatusOK, reverseTransferResponse{
+		transferTxResponse: newTransferTxResponse(server.currencies, result.TransferTxResult),
+		OriginalTransfer:   original,
+	})
 }
@@ -0,0 +1,200 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	db "db.sqlc.dev/app/db/sqlc"
+	"db.sqlc.dev/app/money"
+	"db.sqlc.dev/app/token"
+	"db.sqlc.dev/app/util"
+	"github.com/gin-gonic/gin"
+)
+
+type authorizeTransferRequest struct {
+	transferRequest
+	// ExpiresAt: optional expiry time of the hold, the hold lasts TransferHoldDuration without it
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+type holdResponse struct {
+	Amount        money.Amount `json:"amount"`
+	AmountDecimal string       `json:"amount_decimal,omitempty"`
+	ExpiresAt     time.Time    `json:"expires_at"`
+}
+
+type transferHoldResponse struct {
+	Transfer                transferResponse `json:"transfer"`
+	FromAccount             accountResponse  `json:"from_account"`
+	Hold                    holdResponse     `json:"hold"`
+	AvailableBalance        money.Amount     `json:"available_balance"`
+	AvailableBalanceDecimal string           `json:"available_balance_decimal,omitempty"`
+}
+
+// newTransferHoldResponse renders the result of a hold placed or released on an account,
+// to is the currency of the to account of the transfer
+func newTransferHoldResponse(currencies *util.CurrencyRegistry, result db.TransferHoldTxResult, to string) transferHoldResponse {
+	from := result.FromAccount.Currency
+	holdAmount := money.New(result.Hold.Amount, from)
+	availableBalance := money.New(result.AvailableBalance, from)
+
+	return transferHoldResponse{
+		Transfer:    newTransferResponse(currencies, result.Transfer, from, to),
+		FromAccount: newAccountResponse(currencies, result.FromAccount),
+		Hold: holdResponse{
+			Amount:        holdAmount,
+			AmountDecimal: currencies.FormatAmount(holdAmount),
+			ExpiresAt:     result.Hold.ExpiresAt,
+		},
+		AvailableBalance:        availableBalance,
+		AvailableBalanceDecimal: currencies.FormatAmount(availableBalance),
+	}
+}
+
+// authorizeTransfer creates a pending transfer: its amount and fee are held on the from account,
+// and the money is moved when the transfer is captured
+func (server *Server) authorizeTransfer(ctx *gin.Context) {
+	var req authorizeTransferRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	// API RULE: a hold must expire in the future, and can't last longer than MaxTransferHoldDuration
+	now := time.Now()
+	expiresAt := now.Add(server.config.TransferHoldDuration)
+	if req.ExpiresAt != nil {
+		expiresAt = *req.ExpiresAt
+	}
+	if !expiresAt.After(now) {
+		err := errors.New("expires_at must be in the future")
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+	if server.config.MaxTransferHoldDuration > 0 && expiresAt.Sub(now) > server.config.MaxTransferHoldDuration {
+		err := fmt.Errorf("a hold can't last longer than %s", server.config.MaxTransferHoldDuration)
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	arg, ok := server.newTransferTxParams(ctx, req.transferRequest, authPayload)
+	if !ok {
+		return
+	}
+
+	result, err := server.store.AuthorizeTransferTx(ctx, db.AuthorizeTransferTxParams{
+		TransferTxParams: arg,
+		ExpiresAt:        expiresAt,
+	})
+	if err != nil {
+		// API RULE: the from account must have enough money for the hold, and both accounts must be open
+		ctx.JSON(transferErrorResponse(err))
+		return
+	}
+
+	// a transfer between accounts of the same currency isn't converted
+	to := arg.ToAmount.Currency
+	if to == "" {
+		to = arg.Amount.Currency
+	}
+	ctx.JSON(http.StatusOK, newTransferHoldResponse(server.currencies, result, to))
+}
+
+type transferHoldRequest struct {
+	ID int64 `uri:"id" binding:"required,min=1"`
+}
+
+// findTransferAccounts gets the transfer of the URI with its accounts, and sends the error response if it doesn't exist
+func (server *Server) findTransferAccounts(ctx *gin.Context) (transfer db.Transfer, fromAccount db.Account, toAccount db.Account, ok bool) {
+	var req transferHoldRequest
+	if err := ctx.ShouldBindUri(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	transfer, err := server.store.GetTransfer(ctx, req.ID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	if fromAccount, ok = server.findAccount(ctx, transfer.FromAccountID); !ok {
+		return
+	}
+	toAccount, ok = server.findAccount(ctx, transfer.ToAccountID)
+	return
+}
+
+// writeHoldError sends the response of an error of the capture or the release of a hold
+func writeHoldError(ctx *gin.Context, err error) {
+	switch {
+	case errors.Is(err, db.ErrTransferNotPending):
+		ctx.JSON(http.StatusUnprocessableEntity, errorCodeResponse(errCodeTransferNotPending, err))
+	case errors.Is(err, db.ErrHoldExpired):
+		ctx.JSON(http.StatusUnprocessableEntity, errorCodeResponse(errCodeHoldExpired, err))
+	default:
+		ctx.JSON(transferErrorResponse(err))
+	}
+}
+
+// captureTransfer moves the held money of a pending transfer to the to account.
+// The holders of the to account with the transfer permission and the bankers can capture a transfer
+func (server *Server) captureTransfer(ctx *gin.Context) {
+	transfer, _, toAccount, ok := server.findTransferAccounts(ctx)
+	if !ok {
+		return
+	}
+
+	// API RULE: the money is claimed by a holder of the to account with the transfer permission, or by a banker
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	if authPayload.Role != util.BankerRole && !server.authorizeAccount(ctx, authPayload, toAccount, db.PermissionTransfer) {
+		return
+	}
+
+	result, err := server.store.CaptureTransferTx(ctx, transfer.ID)
+	if err != nil {
+		writeHoldError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, newTransferTxResponse(server.currencies, result))
+}
+
+// voidTransfer cancels a pending transfer and makes its held money available again.
+// The holders of either account with the transfer permission and the bankers can void a transfer
+func (server *Server) voidTransfer(ctx *gin.Context) {
+	transfer, fromAccount, toAccount, ok := server.findTransferAccounts(ctx)
+	if !ok {
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	if authPayload.Role != util.BankerRole {
+		allowed, err := server.canAccessAnyAccount(ctx, authPayload, db.PermissionTransfer, fromAccount, toAccount)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+			return
+		}
+		if !allowed {
+			err := errors.New("transfer does not belong to the authenticated user")
+			ctx.JSON(http.StatusUnauthorized, errorResponse(err))
+			return
+		}
+	}
+
+	result, err := server.store.VoidTransferTx(ctx, transfer.ID)
+	if err != nil {
+		writeHoldError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, newTransferHoldResponse(server.currencies, result, toAccount.Currency))
+}
@@ -1,17 +1,29 @@
 package api
 
 import (
-	"db.sqlc.dev/app/util"
+	"reflect"
+
+	"db.sqlc.dev/app/money"
 	"github.com/go-playground/validator/v10"
 )
 
+// validCurrency accepts the currencies of the registry of the server.
 // validator.Func: function that takes a validator.FieldLevel interface as input and return true when validation succeeds
 // validator.FieldLevel: interface that contains all information and helper functions to validate a field.
-var validCurrency validator.Func = func(fieldLevel validator.FieldLevel) bool {
+func (server *Server) validCurrency(fieldLevel validator.FieldLevel) bool {
 	// call fieldLevel.Field().Interface() to get the value of the field as an interface{}
 	// use .(string) to convert value to a string
 	if currency, ok := fieldLevel.Field().Interface().(string); ok {
-		return util.IsSupportedCurrency(currency)
+		return server.currencies.IsSupported(currency)
 	}
 	return false
 }
+
+// amountValue validates a money.Amount by its minor units, e.g. binding:"required,gt=0" requires a positive amount.
+// The currency of an amount is checked against the currency of the account it applies to
+func amountValue(field reflect.Value) interface{} {
+	if amount, ok := field.Interface().(money.Amount); ok {
+		return amount.Minor
+	}
+	return nil
+}
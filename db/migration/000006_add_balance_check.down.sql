@@ -0,0 +1 @@
+ALTER TABLE "accounts" DROP CONSTRAINT IF EXISTS "accounts_balance_check";
@@ -0,0 +1,3 @@
+-- backstop of the insufficient funds check of TransferTx: balances can't go negative.
+-- NOT VALID: only new and updated rows are checked, existing negative balances are kept
+ALTER TABLE "accounts" ADD CONSTRAINT "accounts_balance_check" CHECK ("balance" >= 0) NOT VALID;
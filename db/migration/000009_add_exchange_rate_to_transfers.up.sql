@@ -0,0 +1,9 @@
+-- amount is debited from the from account in its currency,
+-- to_amount = amount * exchange_rate is credited to the to account in its currency
+ALTER TABLE "transfers" ADD COLUMN "to_amount" bigint;
+ALTER TABLE "transfers" ADD COLUMN "exchange_rate" numeric NOT NULL DEFAULT 1;
+
+UPDATE "transfers" SET "to_amount" = "amount";
+ALTER TABLE "transfers" ALTER COLUMN "to_amount" SET NOT NULL;
+
+COMMENT ON COLUMN "transfers"."to_amount" IS 'must be positive only';
@@ -0,0 +1,2 @@
+ALTER TABLE IF EXISTS "transfers" DROP COLUMN IF EXISTS "fee";
+ALTER TABLE IF EXISTS "accounts" DROP COLUMN IF EXISTS "type";
@@ -0,0 +1,7 @@
+-- the account type selects the fee rules of the transfers from the account
+ALTER TABLE "accounts" ADD COLUMN "type" varchar NOT NULL DEFAULT 'checking';
+
+-- fee charged to the from account in its currency on top of amount, and credited to a house fee account
+ALTER TABLE "transfers" ADD COLUMN "fee" bigint NOT NULL DEFAULT 0;
+
+ALTER TABLE "transfers" ADD CONSTRAINT "transfers_fee_check" CHECK ("fee" >= 0);
@@ -0,0 +1,11 @@
+-- a reversal is a transfer in the opposite direction of the transfer it reverses
+ALTER TABLE "transfers" ADD COLUMN "reversal_of" bigint;
+
+-- part of amount already given back to the from account by reversals
+ALTER TABLE "transfers" ADD COLUMN "reversed_amount" bigint NOT NULL DEFAULT 0;
+
+ALTER TABLE "transfers" ADD FOREIGN KEY ("reversal_of") REFERENCES "transfers" ("id");
+
+ALTER TABLE "transfers" ADD CONSTRAINT "transfers_reversed_amount_check" CHECK ("reversed_amount" >= 0 AND "reversed_amount" <= "amount");
+
+CREATE INDEX ON "transfers" ("reversal_of");
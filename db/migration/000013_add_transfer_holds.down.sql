@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS transfer_holds;
+ALTER TABLE IF EXISTS "accounts" DROP COLUMN IF EXISTS "held_balance";
+ALTER TABLE IF EXISTS "transfers" DROP COLUMN IF EXISTS "status";
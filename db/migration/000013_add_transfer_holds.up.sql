@@ -0,0 +1,32 @@
+-- pending: the money is held on the from account until the transfer is captured, voided or expired;
+-- captured: the entries are posted, the transfers made in a single step are captured
+ALTER TABLE "transfers" ADD COLUMN "status" varchar NOT NULL DEFAULT 'captured';
+
+ALTER TABLE "transfers" ADD CONSTRAINT "transfers_status_check" CHECK ("status" IN ('pending', 'captured', 'voided', 'expired'));
+
+-- money reserved by the pending transfers from the account, which can't be spent
+ALTER TABLE "accounts" ADD COLUMN "held_balance" bigint NOT NULL DEFAULT 0;
+
+ALTER TABLE "accounts" ADD CONSTRAINT "accounts_held_balance_check" CHECK ("held_balance" >= 0);
+
+-- hold of a pending transfer, deleted when the transfer is captured, voided or expired
+CREATE TABLE "transfer_holds" (
+  "transfer_id" bigint PRIMARY KEY,
+  "account_id" bigint NOT NULL,
+  -- amount of the transfer with its fee
+  "amount" bigint NOT NULL,
+  -- house account receiving the fee when the transfer is captured
+  "fee_account_id" bigint,
+  "expires_at" timestamptz NOT NULL,
+  "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+CREATE INDEX ON "transfer_holds" ("expires_at");
+
+ALTER TABLE "transfer_holds" ADD CONSTRAINT "transfer_holds_amount_check" CHECK ("amount" > 0);
+
+ALTER TABLE "transfer_holds" ADD FOREIGN KEY ("transfer_id") REFERENCES "transfers" ("id");
+
+ALTER TABLE "transfer_holds" ADD FOREIGN KEY ("account_id") REFERENCES "accounts" ("id");
+
+ALTER TABLE "transfer_holds" ADD FOREIGN KEY ("fee_account_id") REFERENCES "accounts" ("id");
@@ -0,0 +1,5 @@
+-- active: the account can send and receive money;
+-- closed: the account rejects the transfers, it is kept for its history
+ALTER TABLE "accounts" ADD COLUMN "status" varchar NOT NULL DEFAULT 'active';
+
+ALTER TABLE "accounts" ADD CONSTRAINT "accounts_status_check" CHECK ("status" IN ('active', 'closed'));
@@ -0,0 +1,11 @@
+ALTER TABLE IF EXISTS "accounts" DROP COLUMN IF EXISTS "status_changed_at";
+
+ALTER TABLE IF EXISTS "accounts" DROP COLUMN IF EXISTS "status_changed_by";
+
+ALTER TABLE IF EXISTS "accounts" DROP COLUMN IF EXISTS "status_reason";
+
+ALTER TABLE IF EXISTS "accounts" DROP CONSTRAINT IF EXISTS "accounts_status_check";
+
+UPDATE "accounts" SET "status" = 'active' WHERE "status" IN ('debit_frozen', 'frozen');
+
+ALTER TABLE IF EXISTS "accounts" ADD CONSTRAINT "accounts_status_check" CHECK ("status" IN ('active', 'closed'));
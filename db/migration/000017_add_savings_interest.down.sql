@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS interest_accruals;
+ALTER TABLE IF EXISTS "accounts" DROP CONSTRAINT IF EXISTS "accounts_type_check";
@@ -0,0 +1,30 @@
+-- checking: everyday payments and transfers; savings: earns interest
+ALTER TABLE "accounts" ADD CONSTRAINT "accounts_type_check" CHECK ("type" IN ('checking', 'savings'));
+
+-- interest accrued on an account for a day, paid by the monthly posting of the interest
+CREATE TABLE "interest_accruals" (
+  "id" bigserial PRIMARY KEY,
+  "account_id" bigint NOT NULL,
+  "accrual_date" date NOT NULL,
+  -- balance and annual rate of the account the interest of the day is computed on
+  "balance" bigint NOT NULL,
+  "annual_rate" varchar NOT NULL,
+  -- interest of the day in minor units
+  "amount" bigint NOT NULL,
+  -- transfer from the house interest expense account paying the interest, null if the posted interest was zero
+  "transfer_id" bigint,
+  -- null until the interest is posted
+  "posted_at" timestamptz,
+  "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+CREATE UNIQUE INDEX ON "interest_accruals" ("account_id", "accrual_date");
+
+-- the interest job resumes from the last accrued day
+CREATE INDEX ON "interest_accruals" ("accrual_date");
+
+ALTER TABLE "interest_accruals" ADD CONSTRAINT "interest_accruals_amount_check" CHECK ("amount" >= 0);
+
+ALTER TABLE "interest_accruals" ADD FOREIGN KEY ("account_id") REFERENCES "accounts" ("id");
+
+ALTER TABLE "interest_accruals" ADD FOREIGN KEY ("transfer_id") REFERENCES "transfers" ("id");
@@ -0,0 +1,12 @@
+-- a user can only own one account of a currency again: the accounts that break it must be merged by hand first,
+-- closed accounts included
+DO $$
+BEGIN
+  IF EXISTS (SELECT 1 FROM "accounts" GROUP BY "owner", "currency" HAVING count(*) > 1) THEN
+    RAISE EXCEPTION 'cannot restore owner_currency_key: some users own several accounts of a currency';
+  END IF;
+END $$;
+
+DROP INDEX IF EXISTS "accounts_owner_currency_nickname_key";
+ALTER TABLE IF EXISTS "accounts" ADD CONSTRAINT "owner_currency_key" UNIQUE ("owner", "currency");
+ALTER TABLE IF EXISTS "accounts" DROP COLUMN IF EXISTS "nickname";
@@ -0,0 +1,7 @@
+-- a user can hold several accounts of a currency, told apart by their nickname
+ALTER TABLE "accounts" ADD COLUMN "nickname" varchar NOT NULL DEFAULT '';
+
+ALTER TABLE "accounts" DROP CONSTRAINT IF EXISTS "owner_currency_key";
+
+-- the nickname of a closed account can be given to a new account
+CREATE UNIQUE INDEX "accounts_owner_currency_nickname_key" ON "accounts" ("owner", "currency", "nickname") WHERE "status" <> 'closed';
@@ -0,0 +1,23 @@
+-- users sharing an account, each permission includes the lower ones:
+-- view: read the account and its history; transfer: also send money from the account;
+-- manage: also change the holders of the account and close it.
+-- The owner of an account is always a holder with the manage permission
+CREATE TABLE "account_holders" (
+  "account_id" bigint NOT NULL,
+  "username" varchar NOT NULL,
+  "permission" varchar NOT NULL,
+  "created_at" timestamptz NOT NULL DEFAULT (now()),
+  PRIMARY KEY ("account_id", "username")
+);
+
+-- the accounts of a user are listed in the order of their IDs
+CREATE INDEX ON "account_holders" ("username", "account_id");
+
+ALTER TABLE "account_holders" ADD CONSTRAINT "account_holders_permission_check" CHECK ("permission" IN ('view', 'transfer', 'manage'));
+
+ALTER TABLE "account_holders" ADD FOREIGN KEY ("account_id") REFERENCES "accounts" ("id") ON DELETE CASCADE;
+
+ALTER TABLE "account_holders" ADD FOREIGN KEY ("username") REFERENCES "users" ("username");
+
+INSERT INTO "account_holders" ("account_id", "username", "permission")
+SELECT "id", "owner", 'manage' FROM "accounts";
@@ -0,0 +1,2 @@
+-- depositor: a customer; banker: a bank employee, see util/role.go
+ALTER TABLE "users" ADD CONSTRAINT "users_role_check" CHECK ("role" IN ('depositor', 'banker'));
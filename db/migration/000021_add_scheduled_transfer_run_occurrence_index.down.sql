@@ -0,0 +1 @@
+DROP INDEX IF EXISTS "scheduled_transfer_runs_succeeded_key";
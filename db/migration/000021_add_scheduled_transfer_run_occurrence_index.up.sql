@@ -0,0 +1,3 @@
+-- an occurrence of a scheduled transfer succeeds once: its succeeded run is created in the transaction of its transfer,
+-- so that a repeated run of the occurrence can't move the money twice
+CREATE UNIQUE INDEX "scheduled_transfer_runs_succeeded_key" ON "scheduled_transfer_runs" ("scheduled_transfer_id", "run_at") WHERE "status" = 'succeeded';
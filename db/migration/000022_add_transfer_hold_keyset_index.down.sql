@@ -0,0 +1,3 @@
+CREATE INDEX IF NOT EXISTS "transfer_holds_expires_at_idx" ON "transfer_holds" ("expires_at");
+
+DROP INDEX IF EXISTS "transfer_holds_expires_at_transfer_id_idx";
@@ -0,0 +1,4 @@
+-- the expired holds are read in keyset pages ordered by expiry time and transfer ID
+CREATE INDEX "transfer_holds_expires_at_transfer_id_idx" ON "transfer_holds" ("expires_at", "transfer_id");
+
+DROP INDEX IF EXISTS "transfer_holds_expires_at_idx";
@@ -2,9 +2,11 @@
 INSERT INTO accounts (
   owner, 
   balance, 
-  currency
+  currency,
+  type,
+  nickname
 ) VALUES (
-  $1, $2, $3
+  $1, $2, $3, $4, $5
 )
 RETURNING *;
 
@@ -17,12 +19,18 @@ SELECT * FROM accounts
 WHERE id = $1 LIMIT 1
 FOR NO KEY UPDATE;
 
--- name: ListAccounts :many
-SELECT * FROM accounts
+-- name: CountOpenAccounts :one
+SELECT count(*) FROM accounts
 WHERE owner = $1
-ORDER BY id
-LIMIT $2
-OFFSET $3;
+AND status <> 'closed';
+
+-- name: ListAccounts :many
+SELECT accounts.* FROM accounts
+JOIN account_holders ON account_holders.account_id = accounts.id
+WHERE account_holders.username = sqlc.arg(username)
+ORDER BY accounts.id
+LIMIT sqlc.arg('limit')
+OFFSET sqlc.arg('offset');
 
 -- name: UpdateAccount :one
 UPDATE accounts
@@ -36,6 +44,34 @@ set balance = balance + sqlc.arg(amount)
 WHERE id = sqlc.arg(id)
 RETURNING *;
 
+-- name: AddAccountHeldBalance :one
+UPDATE accounts
+set held_balance = held_balance + sqlc.arg(amount)
+WHERE id = sqlc.arg(id)
+RETURNING *;
+
+-- name: UpdateAccountOverdraftLimit :one
+UPDATE accounts
+set overdraft_limit = sqlc.arg(overdraft_limit)
+WHERE id = sqlc.arg(id)
+RETURNING *;
+
+-- name: UpdateAccountStatus :one
+UPDATE accounts
+set status = sqlc.arg(status),
+  status_reason = sqlc.arg(status_reason),
+  status_changed_by = sqlc.arg(status_changed_by),
+  status_changed_at = now()
+WHERE id = sqlc.arg(id)
+RETURNING *;
+
 -- name: DeleteAccount :exec
 DELETE FROM accounts
-WHERE id = $1;
\ No newline at end of file
+WHERE id = $1;
+
+-- name: ListAccountsAfter :many
+SELECT accounts.* FROM accounts
+JOIN account_holders ON account_holders.account_id = accounts.id
+WHERE account_holders.username = sqlc.arg(username) AND accounts.id > sqlc.arg(after_id)
+ORDER BY accounts.id
+LIMIT sqlc.arg('limit');
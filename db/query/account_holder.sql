@@ -0,0 +1,25 @@
+-- name: UpsertAccountHolder :one
+INSERT INTO account_holders (
+  account_id,
+  username,
+  permission
+) VALUES (
+  $1, $2, $3
+)
+ON CONFLICT (account_id, username) DO UPDATE
+SET permission = EXCLUDED.permission
+RETURNING *;
+
+-- name: GetAccountHolder :one
+SELECT * FROM account_holders
+WHERE account_id = $1 AND username = $2 LIMIT 1;
+
+-- name: ListAccountHolders :many
+SELECT * FROM account_holders
+WHERE account_id = $1
+ORDER BY created_at, username;
+
+-- name: DeleteAccountHolder :one
+DELETE FROM account_holders
+WHERE account_id = $1 AND username = $2
+RETURNING *;
@@ -15,4 +15,34 @@ SELECT * FROM entries
 WHERE account_id = $1
 ORDER BY id
 LIMIT $2
-OFFSET $3;
\ No newline at end of file
+OFFSET $3;
+
+-- name: ListAccountEntries :many
+SELECT * FROM entries
+WHERE account_id = sqlc.arg(account_id)
+    -- incoming entries credit the account, outgoing entries debit it
+    AND (sqlc.arg(direction)::varchar = '' OR
+        (sqlc.arg(direction) = 'incoming' AND amount > 0) OR
+        (sqlc.arg(direction) = 'outgoing' AND amount < 0))
+    AND (sqlc.narg(start_time)::timestamptz IS NULL OR created_at >= sqlc.narg(start_time))
+    AND (sqlc.narg(end_time)::timestamptz IS NULL OR created_at < sqlc.narg(end_time))
+    AND (sqlc.narg(min_amount)::bigint IS NULL OR abs(amount) >= sqlc.narg(min_amount))
+    AND (sqlc.narg(max_amount)::bigint IS NULL OR abs(amount) <= sqlc.narg(max_amount))
+ORDER BY id
+LIMIT sqlc.arg('limit')
+OFFSET sqlc.arg('offset');
+
+-- name: ListAccountEntriesAfter :many
+SELECT * FROM entries
+WHERE account_id = sqlc.arg(account_id)
+    -- incoming entries credit the account, outgoing entries debit it
+    AND (sqlc.arg(direction)::varchar = '' OR
+        (sqlc.arg(direction) = 'incoming' AND amount > 0) OR
+        (sqlc.arg(direction) = 'outgoing' AND amount < 0))
+    AND (sqlc.narg(start_time)::timestamptz IS NULL OR created_at >= sqlc.narg(start_time))
+    AND (sqlc.narg(end_time)::timestamptz IS NULL OR created_at < sqlc.narg(end_time))
+    AND (sqlc.narg(min_amount)::bigint IS NULL OR abs(amount) >= sqlc.narg(min_amount))
+    AND (sqlc.narg(max_amount)::bigint IS NULL OR abs(amount) <= sqlc.narg(max_amount))
+    AND id > sqlc.arg(after_id)
+ORDER BY id
+LIMIT sqlc.arg('limit');
@@ -0,0 +1,22 @@
+-- name: CreateIdempotencyKey :one
+-- an expired key of the user is replaced, no row is returned if the key is still in use
+INSERT INTO idempotency_keys (
+  username,
+  idempotency_key,
+  request_hash,
+  response_body,
+  expires_at
+) VALUES (
+  $1, $2, $3, $4, $5
+)
+ON CONFLICT (username, idempotency_key) DO UPDATE
+SET request_hash = EXCLUDED.request_hash,
+  response_body = EXCLUDED.response_body,
+  expires_at = EXCLUDED.expires_at,
+  created_at = now()
+WHERE idempotency_keys.expires_at <= now()
+RETURNING *;
+
+-- name: GetIdempotencyKey :one
+SELECT * FROM idempotency_keys
+WHERE username = $1 AND idempotency_key = $2 LIMIT 1;
@@ -0,0 +1,59 @@
+-- name: CreateInterestAccrual :one
+INSERT INTO interest_accruals (
+  account_id,
+  accrual_date,
+  balance,
+  annual_rate,
+  amount
+) VALUES (
+  $1, $2, $3, $4, $5
+)
+ON CONFLICT (account_id, accrual_date) DO NOTHING
+RETURNING *;
+
+-- name: ListUnpostedInterestAccruals :many
+SELECT * FROM interest_accruals
+WHERE account_id = sqlc.arg(account_id)
+AND posted_at IS NULL
+AND accrual_date < sqlc.arg(before)
+ORDER BY accrual_date;
+
+-- name: PostInterestAccruals :exec
+UPDATE interest_accruals
+set transfer_id = sqlc.narg(transfer_id),
+  posted_at = now()
+WHERE account_id = sqlc.arg(account_id)
+AND posted_at IS NULL
+AND accrual_date < sqlc.arg(before);
+
+-- name: GetLastInterestAccrualDate :one
+SELECT accrual_date FROM interest_accruals
+ORDER BY accrual_date DESC
+LIMIT 1;
+
+-- name: ListInterestAccounts :many
+SELECT * FROM accounts
+WHERE accounts.currency = sqlc.arg(currency)
+AND accounts.type = sqlc.arg(type)
+AND accounts.status <> 'closed'
+AND accounts.id > sqlc.arg(after_id)
+AND NOT EXISTS (
+  SELECT 1 FROM interest_accruals
+  WHERE interest_accruals.account_id = accounts.id
+  AND interest_accruals.accrual_date = sqlc.arg(accrual_date)
+)
+ORDER BY accounts.id
+LIMIT sqlc.arg('limit');
+
+-- name: ListUnpostedInterestAccounts :many
+SELECT * FROM accounts
+WHERE accounts.status IN ('active', 'debit_frozen')
+AND accounts.id > sqlc.arg(after_id)
+AND EXISTS (
+  SELECT 1 FROM interest_accruals
+  WHERE interest_accruals.account_id = accounts.id
+  AND interest_accruals.posted_at IS NULL
+  AND interest_accruals.accrual_date < sqlc.arg(before)
+)
+ORDER BY accounts.id
+LIMIT sqlc.arg('limit');
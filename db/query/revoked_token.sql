@@ -0,0 +1,12 @@
+-- name: CreateRevokedToken :one
+INSERT INTO revoked_tokens (
+  id,
+  username,
+  expires_at
+) VALUES (
+  $1, $2, $3
+) RETURNING *;
+
+-- name: GetRevokedToken :one
+SELECT * FROM revoked_tokens
+WHERE id = $1 LIMIT 1;
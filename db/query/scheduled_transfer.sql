@@ -0,0 +1,87 @@
+-- name: CreateScheduledTransfer :one
+INSERT INTO scheduled_transfers (
+  owner,
+  from_account_id,
+  to_account_id,
+  amount,
+  currency,
+  recurrence,
+  next_run_at,
+  next_attempt_at,
+  end_at
+) VALUES (
+  $1, $2, $3, $4, $5, $6, $7, $7, $8
+) RETURNING *;
+
+-- name: GetScheduledTransfer :one
+SELECT * FROM scheduled_transfers
+WHERE id = $1 LIMIT 1;
+
+-- name: ListScheduledTransfers :many
+SELECT * FROM scheduled_transfers
+WHERE owner = $1
+ORDER BY id
+LIMIT $2
+OFFSET $3;
+
+-- name: UpdateScheduledTransfer :one
+-- the failed attempts are reset, since the next occurrence is executed with the new parameters
+UPDATE scheduled_transfers
+SET amount = sqlc.arg(amount),
+  recurrence = sqlc.arg(recurrence),
+  status = sqlc.arg(status),
+  next_run_at = sqlc.arg(next_run_at),
+  next_attempt_at = sqlc.arg(next_run_at),
+  attempts = 0,
+  end_at = sqlc.arg(end_at)
+WHERE id = sqlc.arg(id)
+RETURNING *;
+
+-- name: DeleteScheduledTransfer :exec
+DELETE FROM scheduled_transfers
+WHERE id = $1;
+
+-- name: ClaimDueScheduledTransfers :many
+-- the claimed transfers aren't due again until lease_until, so that another scheduler doesn't run them concurrently
+UPDATE scheduled_transfers
+SET next_attempt_at = sqlc.arg(lease_until)
+WHERE id IN (
+  SELECT id FROM scheduled_transfers
+  WHERE status = 'active' AND next_attempt_at <= now()
+  ORDER BY next_attempt_at
+  LIMIT sqlc.arg(max_count)
+  FOR UPDATE SKIP LOCKED
+)
+RETURNING *;
+
+-- name: UpdateScheduledTransferSchedule :one
+-- no row is updated if the transfer has been paused, updated or claimed again since it was claimed until claimed_until
+UPDATE scheduled_transfers
+SET status = sqlc.arg(status),
+  next_run_at = sqlc.arg(next_run_at),
+  next_attempt_at = sqlc.arg(next_attempt_at),
+  attempts = sqlc.arg(attempts)
+WHERE id = sqlc.arg(id) AND status = 'active' AND next_attempt_at = sqlc.arg(claimed_until)
+RETURNING *;
+
+-- name: CreateScheduledTransferRun :one
+INSERT INTO scheduled_transfer_runs (
+  scheduled_transfer_id,
+  run_at,
+  attempt,
+  status,
+  transfer_id,
+  error
+) VALUES (
+  $1, $2, $3, $4, $5, $6
+)
+-- no row is returned if the occurrence already has a succeeded run
+ON CONFLICT (scheduled_transfer_id, run_at) WHERE status = 'succeeded' DO NOTHING
+RETURNING *;
+
+-- name: ListScheduledTransferRuns :many
+SELECT * FROM scheduled_transfer_runs
+WHERE scheduled_transfer_id = $1
+ORDER BY id DESC
+LIMIT $2
+OFFSET $3;
@@ -2,15 +2,37 @@
 INSERT INTO transfers (
   from_account_id,
   to_account_id,
-  amount
+  amount,
+  to_amount,
+  exchange_rate,
+  fee,
+  reversal_of,
+  status
 ) VALUES (
-  $1, $2, $3
+  $1, $2, $3, $4, $5, $6, $7, $8
 ) RETURNING *;
 
 -- name: GetTransfer :one
 SELECT * FROM transfers
 WHERE id = $1 LIMIT 1;
 
+-- name: GetTransferForUpdate :one
+SELECT * FROM transfers
+WHERE id = $1 LIMIT 1
+FOR NO KEY UPDATE;
+
+-- name: AddTransferReversedAmount :one
+UPDATE transfers
+SET reversed_amount = reversed_amount + sqlc.arg(amount)
+WHERE id = sqlc.arg(id)
+RETURNING *;
+
+-- name: UpdateTransferStatus :one
+UPDATE transfers
+SET status = sqlc.arg(status)
+WHERE id = sqlc.arg(id)
+RETURNING *;
+
 -- name: ListTransfers :many
 SELECT * FROM transfers
 WHERE 
@@ -18,4 +40,40 @@ WHERE
     to_account_id = $2
 ORDER BY id
 LIMIT $3
-OFFSET $4;
\ No newline at end of file
+OFFSET $4;
+
+-- name: ListAccountTransfers :many
+SELECT * FROM transfers
+WHERE
+    (
+        (from_account_id = sqlc.arg(account_id) AND sqlc.arg(direction)::varchar IN ('', 'outgoing')) OR
+        (to_account_id = sqlc.arg(account_id) AND sqlc.arg(direction)::varchar IN ('', 'incoming'))
+    )
+    AND (sqlc.narg(start_time)::timestamptz IS NULL OR created_at >= sqlc.narg(start_time))
+    AND (sqlc.narg(end_time)::timestamptz IS NULL OR created_at < sqlc.narg(end_time))
+    -- the amounts are compared in the currency of the account: amount if it is the source, to_amount otherwise
+    AND (sqlc.narg(min_amount)::bigint IS NULL OR
+        CASE WHEN from_account_id = sqlc.arg(account_id) THEN amount ELSE to_amount END >= sqlc.narg(min_amount))
+    AND (sqlc.narg(max_amount)::bigint IS NULL OR
+        CASE WHEN from_account_id = sqlc.arg(account_id) THEN amount ELSE to_amount END <= sqlc.narg(max_amount))
+ORDER BY id
+LIMIT sqlc.arg('limit')
+OFFSET sqlc.arg('offset');
+
+-- name: ListAccountTransfersAfter :many
+SELECT * FROM transfers
+WHERE
+    (
+        (from_account_id = sqlc.arg(account_id) AND sqlc.arg(direction)::varchar IN ('', 'outgoing')) OR
+        (to_account_id = sqlc.arg(account_id) AND sqlc.arg(direction)::varchar IN ('', 'incoming'))
+    )
+    AND (sqlc.narg(start_time)::timestamptz IS NULL OR created_at >= sqlc.narg(start_time))
+    AND (sqlc.narg(end_time)::timestamptz IS NULL OR created_at < sqlc.narg(end_time))
+    -- the amounts are compared in the currency of the account: amount if it is the source, to_amount otherwise
+    AND (sqlc.narg(min_amount)::bigint IS NULL OR
+        CASE WHEN from_account_id = sqlc.arg(account_id) THEN amount ELSE to_amount END >= sqlc.narg(min_amount))
+    AND (sqlc.narg(max_amount)::bigint IS NULL OR
+        CASE WHEN from_account_id = sqlc.arg(account_id) THEN amount ELSE to_amount END <= sqlc.narg(max_amount))
+    AND id > sqlc.arg(after_id)
+ORDER BY id
+LIMIT sqlc.arg('limit');
@@ -0,0 +1,28 @@
+-- name: CreateTransferHold :one
+INSERT INTO transfer_holds (
+  transfer_id,
+  account_id,
+  amount,
+  fee_account_id,
+  expires_at
+) VALUES (
+  $1, $2, $3, $4, $5
+) RETURNING *;
+
+-- name: GetTransferHold :one
+SELECT * FROM transfer_holds
+WHERE transfer_id = $1 LIMIT 1;
+
+-- name: DeleteTransferHold :one
+DELETE FROM transfer_holds
+WHERE transfer_id = $1
+RETURNING *;
+
+-- name: ListExpiredTransferHolds :many
+-- keyset page of the expired holds after the hold (after_expires_at, after_transfer_id),
+-- so that the holds that can't be expired don't hide the next ones
+SELECT * FROM transfer_holds
+WHERE expires_at <= sqlc.arg(now)
+  AND (expires_at, transfer_id) > (sqlc.arg(after_expires_at)::timestamptz, sqlc.arg(after_transfer_id)::bigint)
+ORDER BY expires_at, transfer_id
+LIMIT sqlc.arg('limit');
@@ -13,7 +13,7 @@ const addAccountBalance = `-- name: AddAccountBalance :one
 UPDATE accounts
 set balance = balance + $1
 WHERE id = $2
-RETURNING id, owner, balance, currency, created_at
+RETURNING id, owner, balance, currency, created_at, overdraft_limit, type, held_balance, status, status_reason, status_changed_by, status_changed_at, nickname
 `
 
 type AddAccountBalanceParams struct {
@@ -30,29 +30,93 @@ func (q *Queries) AddAccountBalance(ctx context.Context, arg AddAccountBalancePa
 		&i.Balance,
 		&i.Currency,
 		&i.CreatedAt,
+		&i.OverdraftLimit,
+		&i.Type,
+		&i.HeldBalance,
+		&i.Status,
+		&i.StatusReason,
+		&i.StatusChangedBy,
+		&i.StatusChangedAt,
+		&i.Nickname,
 	)
 	return i, err
 }
 
+const addAccountHeldBalance = `-- name: AddAccountHeldBalance :one
+UPDATE accounts
+set held_balance = held_balance + $1
+WHERE id = $2
+RETURNING id, owner, balance, currency, created_at, overdraft_limit, type, held_balance, status, status_reason, status_changed_by, status_changed_at, nickname
+`
+
+type AddAccountHeldBalanceParams struct {
+	Amount int64 `json:"amount"`
+	ID     int64 `json:"id"`
+}
+
+func (q *Queries) AddAccountHeldBalance(ctx context.Context, arg AddAccountHeldBalanceParams) (Account, error) {
+	row := q.db.QueryRowContext(ctx, addAccountHeldBalance, arg.Amount, arg.ID)
+	var i Account
+	err := row.Scan(
+		&i.ID,
+		&i.Owner,
+		&i.Balance,
+		&i.Currency,
+		&i.CreatedAt,
+		&i.OverdraftLimit,
+		&i.Type,
+		&i.HeldBalance,
+		&i.Status,
+		&i.StatusReason,
+		&i.StatusChangedBy,
+		&i.StatusChangedAt,
+		&i.Nickname,
+	)
+	return i, err
+}
+
+const countOpenAccounts = `-- name: CountOpenAccounts :one
+SELECT count(*) FROM accounts
+WHERE owner = $1
+AND status <> 'closed'
+`
+
+func (q *Queries) CountOpenAccounts(ctx context.Context, owner string) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countOpenAccounts, owner)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
 const createAccount = `-- name: CreateAccount :one
 INSERT INTO accounts (
   owner, 
   balance, 
-  currency
+  currency,
+  type,
+  nickname
 ) VALUES (
-  $1, $2, $3
+  $1, $2, $3, $4, $5
 )
-RETURNING id, owner, balance, currency, created_at
+RETURNING id, owner, balance, currency, created_at, overdraft_limit, type, held_balance, status, status_reason, status_changed_by, status_changed_at, nickname
 `
 
 type CreateAccountParams struct {
 	Owner    string `json:"owner"`
 	Balance  int64  `json:"balance"`
 	Currency string `json:"currency"`
+	Type     string `json:"type"`
+	Nickname string `json:"nickname"`
 }
 
 func (q *Queries) CreateAccount(ctx context.Context, arg CreateAccountParams) (Account, error) {
-	row := q.db.QueryRowContext(ctx, createAccount, arg.Owner, arg.Balance, arg.Currency)
+	row := q.db.QueryRowContext(ctx, createAccount,
+		arg.Owner,
+		arg.Balance,
+		arg.Currency,
+		arg.Type,
+		arg.Nickname,
+	)
 	var i Account
 	err := row.Scan(
 		&i.ID,
@@ -60,6 +124,14 @@ func (q *Queries) CreateAccount(ctx context.Context, arg CreateAccountParams) (A
 		&i.Balance,
 		&i.Currency,
 		&i.CreatedAt,
+		&i.OverdraftLimit,
+		&i.Type,
+		&i.HeldBalance,
+		&i.Status,
+		&i.StatusReason,
+		&i.StatusChangedBy,
+		&i.StatusChangedAt,
+		&i.Nickname,
 	)
 	return i, err
 }
@@ -75,7 +147,7 @@ func (q *Queries) DeleteAccount(ctx context.Context, id int64) error {
 }
 
 const getAccount = `-- name: GetAccount :one
-SELECT id, owner, balance, currency, created_at FROM accounts
+SELECT id, owner, balance, currency, created_at, overdraft_limit, type, held_balance, status, status_reason, status_changed_by, status_changed_at, nickname FROM accounts
 WHERE id = $1 LIMIT 1
 `
 
@@ -88,12 +160,20 @@ func (q *Queries) GetAccount(ctx context.Context, id int64) (Account, error) {
 		&i.Balance,
 		&i.Currency,
 		&i.CreatedAt,
+		&i.OverdraftLimit,
+		&i.Type,
+		&i.HeldBalance,
+		&i.Status,
+		&i.StatusReason,
+		&i.StatusChangedBy,
+		&i.StatusChangedAt,
+		&i.Nickname,
 	)
 	return i, err
 }
 
 const getAccountForUpdate = `-- name: GetAccountForUpdate :one
-SELECT id, owner, balance, currency, created_at FROM accounts
+SELECT id, owner, balance, currency, created_at, overdraft_limit, type, held_balance, status, status_reason, status_changed_by, status_changed_at, nickname FROM accounts
 WHERE id = $1 LIMIT 1
 FOR NO KEY UPDATE
 `
@@ -107,26 +187,35 @@ func (q *Queries) GetAccountForUpdate(ctx context.Context, id int64) (Account, e
 		&i.Balance,
 		&i.Currency,
 		&i.CreatedAt,
+		&i.OverdraftLimit,
+		&i.Type,
+		&i.HeldBalance,
+		&i.Status,
+		&i.StatusReason,
+		&i.StatusChangedBy,
+		&i.StatusChangedAt,
+		&i.Nickname,
 	)
 	return i, err
 }
 
 const listAccounts = `-- name: ListAccounts :many
-SELECT id, owner, balance, currency, created_at FROM accounts
-WHERE owner = $1
-ORDER BY id
-LIMIT $2
-OFFSET $3
+SELECT accounts.id, accounts.owner, accounts.balance, accounts.currency, accounts.created_at, accounts.overdraft_limit, accounts.type, accounts.held_balance, accounts.status, accounts.status_reason, accounts.status_changed_by, accounts.status_changed_at, accounts.nickname FROM accounts
+JOIN account_holders ON account_holders.account_id = accounts.id
+WHERE account_holders.username = $1
+ORDER BY accounts.id
+LIMIT $3
+OFFSET $2
 `
 
 type ListAccountsParams struct {
-	Owner  string `json:"owner"`
-	Limit  int32  `json:"limit"`
-	Offset int32  `json:"offset"`
+	Username string `json:"username"`
+	Offset   int32  `json:"offset"`
+	Limit    int32  `json:"limit"`
 }
 
 func (q *Queries) ListAccounts(ctx context.Context, arg ListAccountsParams) ([]Account, error) {
-	rows, err := q.db.QueryContext(ctx, listAccounts, arg.Owner, arg.Limit, arg.Offset)
+	rows, err := q.db.QueryContext(ctx, listAccounts, arg.Username, arg.Offset, arg.Limit)
 	if err != nil {
 		return nil, err
 	}
@@ -140,6 +229,65 @@ func (q *Queries) ListAccounts(ctx context.Context, arg ListAccountsParams) ([]A
 			&i.Balance,
 			&i.Currency,
 			&i.CreatedAt,
+			&i.OverdraftLimit,
+			&i.Type,
+			&i.HeldBalance,
+			&i.Status,
+			&i.StatusReason,
+			&i.StatusChangedBy,
+			&i.StatusChangedAt,
+			&i.Nickname,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listAccountsAfter = `-- name: ListAccountsAfter :many
+SELECT accounts.id, accounts.owner, accounts.balance, accounts.currency, accounts.created_at, accounts.overdraft_limit, accounts.type, accounts.held_balance, accounts.status, accounts.status_reason, accounts.status_changed_by, accounts.status_changed_at, accounts.nickname FROM accounts
+JOIN account_holders ON account_holders.account_id = accounts.id
+WHERE account_holders.username = $1 AND accounts.id > $2
+ORDER BY accounts.id
+LIMIT $3
+`
+
+type ListAccountsAfterParams struct {
+	Username string `json:"username"`
+	AfterID  int64  `json:"after_id"`
+	Limit    int32  `json:"limit"`
+}
+
+func (q *Queries) ListAccountsAfter(ctx context.Context, arg ListAccountsAfterParams) ([]Account, error) {
+	rows, err := q.db.QueryContext(ctx, listAccountsAfter, arg.Username, arg.AfterID, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Account{}
+	for rows.Next() {
+		var i Account
+		if err := rows.Scan(
+			&i.ID,
+			&i.Owner,
+			&i.Balance,
+			&i.Currency,
+			&i.CreatedAt,
+			&i.OverdraftLimit,
+			&i.Type,
+			&i.HeldBalance,
+			&i.Status,
+			&i.StatusReason,
+			&i.StatusChangedBy,
+			&i.StatusChangedAt,
+			&i.Nickname,
 		); err != nil {
 			return nil, err
 		}
@@ -158,7 +306,7 @@ const updateAccount = `-- name: UpdateAccount :one
 UPDATE accounts
 set balance = $2
 WHERE id = $1
-RETURNING id, owner, balance, currency, created_at
+RETURNING id, owner, balance, currency, created_at, overdraft_limit, type, held_balance, status, status_reason, status_changed_by, status_changed_at, nickname
 `
 
 type UpdateAccountParams struct {
@@ -175,6 +323,90 @@ func (q *Queries) UpdateAccount(ctx context.Context, arg UpdateAccountParams) (A
 		&i.Balance,
 		&i.Currency,
 		&i.CreatedAt,
+		&i.OverdraftLimit,
+		&i.Type,
+		&i.HeldBalance,
+		&i.Status,
+		&i.StatusReason,
+		&i.StatusChangedBy,
+		&i.StatusChangedAt,
+		&i.Nickname,
+	)
+	return i, err
+}
+
+const updateAccountOverdraftLimit = `-- name: UpdateAccountOverdraftLimit :one
+UPDATE accounts
+set overdraft_limit = $1
+WHERE id = $2
+RETURNING id, owner, balance, currency, created_at, overdraft_limit, type, held_balance, status, status_reason, status_changed_by, status_changed_at, nickname
+`
+
+type UpdateAccountOverdraftLimitParams struct {
+	OverdraftLimit int64 `json:"overdraft_limit"`
+	ID             int64 `json:"id"`
+}
+
+func (q *Queries) UpdateAccountOverdraftLimit(ctx context.Context, arg UpdateAccountOverdraftLimitParams) (Account, error) {
+	row := q.db.QueryRowContext(ctx, updateAccountOverdraftLimit, arg.OverdraftLimit, arg.ID)
+	var i Account
+	err := row.Scan(
+		&i.ID,
+		&i.Owner,
+		&i.Balance,
+		&i.Currency,
+		&i.CreatedAt,
+		&i.OverdraftLimit,
+		&i.Type,
+		&i.HeldBalance,
+		&i.Status,
+		&i.StatusReason,
+		&i.StatusChangedBy,
+		&i.StatusChangedAt,
+		&i.Nickname,
+	)
+	return i, err
+}
+
+const updateAccountStatus = `-- name: UpdateAccountStatus :one
+UPDATE accounts
+set status = $1,
+  status_reason = $2,
+  status_changed_by = $3,
+  status_changed_at = now()
+WHERE id = $4
+RETURNING id, owner, balance, currency, created_at, overdraft_limit, type, held_balance, status, status_reason, status_changed_by, status_changed_at, nickname
+`
+
+type UpdateAccountStatusParams struct {
+	Status          string `json:"status"`
+	StatusReason    string `json:"status_reason"`
+	StatusChangedBy string `json:"status_changed_by"`
+	ID              int64  `json:"id"`
+}
+
+func (q *Queries) UpdateAccountStatus(ctx context.Context, arg UpdateAccountStatusParams) (Account, error) {
+	row := q.db.QueryRowContext(ctx, updateAccountStatus,
+		arg.Status,
+		arg.StatusReason,
+		arg.StatusChangedBy,
+		arg.ID,
+	)
+	var i Account
+	err := row.Scan(
+		&i.ID,
+		&i.Owner,
+		&i.Balance,
+		&i.Currency,
+		&i.CreatedAt,
+		&i.OverdraftLimit,
+		&i.Type,
+		&i.HeldBalance,
+		&i.Status,
+		&i.StatusReason,
+		&i.StatusChangedBy,
+		&i.StatusChangedAt,
+		&i.Nickname,
 	)
 	return i, err
 }
@@ -0,0 +1,24 @@
+package db
+
+// Permissions of the holders of an account, each permission includes the lower ones
+const (
+	// PermissionView: read the account and its history
+	PermissionView = "view"
+	// PermissionTransfer: also send money from the account
+	PermissionTransfer = "transfer"
+	// PermissionManage: also change the holders of the account and close it
+	PermissionManage = "manage"
+)
+
+// permissionLevels orders the permissions
+var permissionLevels = map[string]int{
+	PermissionView:     1,
+	PermissionTransfer: 2,
+	PermissionManage:   3,
+}
+
+// Allows reports whether the permission of the holder includes the input permission
+func (holder AccountHolder) Allows(permission string) bool {
+	level, ok := permissionLevels[permission]
+	return ok && permissionLevels[holder.Permission] >= level
+}
@@ -0,0 +1,120 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.15.0
+// source: account_holder.sql
+
+package db
+
+import (
+	"context"
+)
+
+const deleteAccountHolder = `-- name: DeleteAccountHolder :one
+DELETE FROM account_holders
+WHERE account_id = $1 AND username = $2
+RETURNING account_id, username, permission, created_at
+`
+
+type DeleteAccountHolderParams struct {
+	AccountID int64  `json:"account_id"`
+	Username  string `json:"username"`
+}
+
+func (q *Queries) DeleteAccountHolder(ctx context.Context, arg DeleteAccountHolderParams) (AccountHolder, error) {
+	row := q.db.QueryRowContext(ctx, deleteAccountHolder, arg.AccountID, arg.Username)
+	var i AccountHolder
+	err := row.Scan(
+		&i.AccountID,
+		&i.Username,
+		&i.Permission,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const getAccountHolder = `-- name: GetAccountHolder :one
+SELECT account_id, username, permission, created_at FROM account_holders
+WHERE account_id = $1 AND username = $2 LIMIT 1
+`
+
+type GetAccountHolderParams struct {
+	AccountID int64  `json:"account_id"`
+	Username  string `json:"username"`
+}
+
+func (q *Queries) GetAccountHolder(ctx context.Context, arg GetAccountHolderParams) (AccountHolder, error) {
+	row := q.db.QueryRowContext(ctx, getAccountHolder, arg.AccountID, arg.Username)
+	var i AccountHolder
+	err := row.Scan(
+		&i.AccountID,
+		&i.Username,
+		&i.Permission,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const listAccountHolders = `-- name: ListAccountHolders :many
+SELECT account_id, username, permission, created_at FROM account_holders
+WHERE account_id = $1
+ORDER BY created_at, username
+`
+
+func (q *Queries) ListAccountHolders(ctx context.Context, accountID int64) ([]AccountHolder, error) {
+	rows, err := q.db.QueryContext(ctx, listAccountHolders, accountID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []AccountHolder{}
+	for rows.Next() {
+		var i AccountHolder
+		if err := rows.Scan(
+			&i.AccountID,
+			&i.Username,
+			&i.Permission,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const upsertAccountHolder = `-- name: UpsertAccountHolder :one
+INSERT INTO account_holders (
+  account_id,
+  username,
+  permission
+) VALUES (
+  $1, $2, $3
+)
+ON CONFLICT (account_id, username) DO UPDATE
+SET permission = EXCLUDED.permission
+RETURNING account_id, username, permission, created_at
+`
+
+type UpsertAccountHolderParams struct {
+	AccountID  int64  `json:"account_id"`
+	Username   string `json:"username"`
+	Permission string `json:"permission"`
+}
+
+func (q *Queries) UpsertAccountHolder(ctx context.Context, arg UpsertAccountHolderParams) (AccountHolder, error) {
+	row := q.db.QueryRowContext(ctx, upsertAccountHolder, arg.AccountID, arg.Username, arg.Permission)
+	var i AccountHolder
+	err := row.Scan(
+		&i.AccountID,
+		&i.Username,
+		&i.Permission,
+		&i.CreatedAt,
+	)
+	return i, err
+}
@@ -0,0 +1,85 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAccountHolders(t *testing.T) {
+	account := createRandomAccount(t)
+	user := createRandomUser(t)
+
+	holder, err := testQueries.UpsertAccountHolder(context.Background(), UpsertAccountHolderParams{
+		AccountID:  account.ID,
+		Username:   user.Username,
+		Permission: PermissionView,
+	})
+	require.NoError(t, err)
+	require.Equal(t, PermissionView, holder.Permission)
+
+	// the permission of an existing holder is replaced
+	holder, err = testQueries.UpsertAccountHolder(context.Background(), UpsertAccountHolderParams{
+		AccountID:  account.ID,
+		Username:   user.Username,
+		Permission: PermissionTransfer,
+	})
+	require.NoError(t, err)
+	require.Equal(t, PermissionTransfer, holder.Permission)
+
+	got, err := testQueries.GetAccountHolder(context.Background(), GetAccountHolderParams{
+		AccountID: account.ID,
+		Username:  user.Username,
+	})
+	require.NoError(t, err)
+	require.Equal(t, holder, got)
+
+	holders, err := testQueries.ListAccountHolders(context.Background(), account.ID)
+	require.NoError(t, err)
+	require.Len(t, holders, 2)
+	require.Equal(t, account.Owner, holders[0].Username)
+	require.Equal(t, PermissionManage, holders[0].Permission)
+
+	// a joint account is listed with the accounts of each holder
+	accounts, err := testQueries.ListAccounts(context.Background(), ListAccountsParams{
+		Username: user.Username,
+		Limit:    5,
+	})
+	require.NoError(t, err)
+	require.Len(t, accounts, 1)
+	require.Equal(t, account.ID, accounts[0].ID)
+
+	_, err = testQueries.DeleteAccountHolder(context.Background(), DeleteAccountHolderParams{
+		AccountID: account.ID,
+		Username:  user.Username,
+	})
+	require.NoError(t, err)
+
+	_, err = testQueries.GetAccountHolder(context.Background(), GetAccountHolderParams{
+		AccountID: account.ID,
+		Username:  user.Username,
+	})
+	require.ErrorIs(t, err, sql.ErrNoRows)
+}
+
+func TestAccountHolderAllows(t *testing.T) {
+	testCases := []struct {
+		holder     string
+		permission string
+		expected   bool
+	}{
+		{holder: PermissionView, permission: PermissionView, expected: true},
+		{holder: PermissionView, permission: PermissionTransfer, expected: false},
+		{holder: PermissionTransfer, permission: PermissionView, expected: true},
+		{holder: PermissionTransfer, permission: PermissionManage, expected: false},
+		{holder: PermissionManage, permission: PermissionTransfer, expected: true},
+		{holder: PermissionManage, permission: "admin", expected: false},
+	}
+
+	for _, tc := range testCases {
+		holder := AccountHolder{Permission: tc.holder}
+		require.Equal(t, tc.expected, holder.Allows(tc.permission), "%s allows %s", tc.holder, tc.permission)
+	}
+}
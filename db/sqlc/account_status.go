@@ -0,0 +1,54 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// ErrInvalidAccountStatus is returned by UpdateAccountStatusTx if the account can't be given the status
+var ErrInvalidAccountStatus = errors.New("invalid account status")
+
+// UpdateAccountStatusTxParams contains the input parameters of the change of status of an account
+type UpdateAccountStatusTxParams struct {
+	AccountID int64 `json:"account_id"`
+	// Status: AccountActive, AccountDebitFrozen or AccountFrozen, an account is closed by CloseAccountTx
+	Status string `json:"status"`
+	Reason string `json:"reason"`
+	// Actor: username of the user changing the status
+	Actor string `json:"actor"`
+}
+
+// UpdateAccountStatusTx freezes or unfreezes an account within a single db transaction:
+// the account is locked so the status can't change while a transfer of the account is running
+func (store *SQLStore) UpdateAccountStatusTx(ctx context.Context, arg UpdateAccountStatusTxParams) (Account, error) {
+	var account Account
+
+	switch arg.Status {
+	case AccountActive, AccountDebitFrozen, AccountFrozen:
+	default:
+		return account, fmt.Errorf("%w: %s", ErrInvalidAccountStatus, arg.Status)
+	}
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		var err error
+		account, err = q.GetAccountForUpdate(ctx, arg.AccountID)
+		if err != nil {
+			return err
+		}
+		// a closed account can't be opened again
+		if account.Status == AccountClosed {
+			return fmt.Errorf("%w: account [%d]", ErrAccountClosed, account.ID)
+		}
+
+		account, err = q.UpdateAccountStatus(ctx, UpdateAccountStatusParams{
+			ID:              account.ID,
+			Status:          arg.Status,
+			StatusReason:    arg.Reason,
+			StatusChangedBy: arg.Actor,
+		})
+		return err
+	})
+
+	return account, err
+}
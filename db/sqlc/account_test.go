@@ -11,13 +11,19 @@ import (
 )
 
 func createRandomAccount(t *testing.T) Account {
+	return createRandomAccountWithCurrency(t, util.RandomCurrency())
+}
+
+// createRandomAccountWithCurrency creates a random account of the input currency
+func createRandomAccountWithCurrency(t *testing.T, currency string) Account {
 	user := createRandomUser(t)
 
 	arg := CreateAccountParams{
 		// Link account.Owner with user.Username
 		Owner:    user.Username,
 		Balance:  util.RandomMoney(),
-		Currency: util.RandomCurrency(),
+		Currency: currency,
+		Type:     util.CheckingAccount,
 	}
 
 	// call CreateAccount method defined in account.sql.go
@@ -30,12 +36,22 @@ func createRandomAccount(t *testing.T) Account {
 	require.Equal(t, arg.Owner, account.Owner)
 	require.Equal(t, arg.Balance, account.Balance)
 	require.Equal(t, arg.Currency, account.Currency)
+	require.Equal(t, arg.Type, account.Type)
+	require.Equal(t, AccountActive, account.Status)
 
 	// check account ID is automatically generated by Postgres
 	require.NotZero(t, account.ID)
 	// check if timestamp is created
 	require.NotZero(t, account.CreatedAt)
 
+	// the owner holds the account, as with CreateAccountTx
+	_, err = testQueries.UpsertAccountHolder(context.Background(), UpsertAccountHolderParams{
+		AccountID:  account.ID,
+		Username:   account.Owner,
+		Permission: PermissionManage,
+	})
+	require.NoError(t, err)
+
 	return account
 }
 
@@ -78,6 +94,42 @@ func TestUpdateAccount(t *testing.T) {
 
 }
 
+func TestUpdateAccountOverdraftLimit(t *testing.T) {
+	account1 := createRandomAccount(t)
+	require.Zero(t, account1.OverdraftLimit)
+
+	arg := UpdateAccountOverdraftLimitParams{
+		ID:             account1.ID,
+		OverdraftLimit: util.RandomInt(1, 1000),
+	}
+
+	account2, err := testQueries.UpdateAccountOverdraftLimit(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, account1.ID, account2.ID)
+	require.Equal(t, account1.Balance, account2.Balance)
+	require.Equal(t, arg.OverdraftLimit, account2.OverdraftLimit)
+
+	// the balance can go negative down to -overdraft_limit
+	account2, err = testQueries.UpdateAccount(context.Background(), UpdateAccountParams{
+		ID:      account1.ID,
+		Balance: -arg.OverdraftLimit,
+	})
+	require.NoError(t, err)
+
+	_, err = testQueries.UpdateAccount(context.Background(), UpdateAccountParams{
+		ID:      account1.ID,
+		Balance: -arg.OverdraftLimit - 1,
+	})
+	require.Error(t, err)
+
+	// the limit can't be lower than the current overdraft
+	_, err = testQueries.UpdateAccountOverdraftLimit(context.Background(), UpdateAccountOverdraftLimitParams{
+		ID:             account1.ID,
+		OverdraftLimit: arg.OverdraftLimit - 1,
+	})
+	require.Error(t, err)
+}
+
 func TestDeleteAccount(t *testing.T) {
 	account1 := createRandomAccount(t)
 	err := testQueries.DeleteAccount(context.Background(), account1.ID)
@@ -98,9 +150,9 @@ func TestListAccounts(t *testing.T) {
 
 	// skip first 6 account and list the rest 4 accounts
 	arg := ListAccountsParams{
-		Owner:  lastAccount.Owner,
-		Limit:  5,
-		Offset: 0,
+		Username: lastAccount.Owner,
+		Limit:    5,
+		Offset:   0,
 	}
 
 	accounts, err := testQueries.ListAccounts(context.Background(), arg)
@@ -112,3 +164,37 @@ func TestListAccounts(t *testing.T) {
 		require.Equal(t, lastAccount.Owner, account.Owner)
 	}
 }
+
+func TestListAccountsAfter(t *testing.T) {
+	user := createRandomUser(t)
+
+	var accounts []Account
+	for _, currency := range []string{util.USD, util.EUR, util.CAD} {
+		account, err := NewStore(testDB).CreateAccountTx(context.Background(), CreateAccountTxParams{
+			CreateAccountParams: CreateAccountParams{
+				Owner:    user.Username,
+				Currency: currency,
+				Type:     util.CheckingAccount,
+			},
+		})
+		require.NoError(t, err)
+		accounts = append(accounts, account)
+	}
+
+	// the accounts are listed in the order of their IDs, after the last account of the previous page
+	page, err := testQueries.ListAccountsAfter(context.Background(), ListAccountsAfterParams{
+		Username: user.Username,
+		AfterID:  0,
+		Limit:    2,
+	})
+	require.NoError(t, err)
+	require.Equal(t, accounts[:2], page)
+
+	page, err = testQueries.ListAccountsAfter(context.Background(), ListAccountsAfterParams{
+		Username: user.Username,
+		AfterID:  page[1].ID,
+		Limit:    2,
+	})
+	require.NoError(t, err)
+	require.Equal(t, accounts[2:], page)
+}
@@ -0,0 +1,70 @@
+package db
+
+import (
+	"context"
+	"fmt"
+)
+
+// BatchTransferError is returned by BatchTransferTx with the error of the transfer that rolled back the batch
+type BatchTransferError struct {
+	// Index: position of the failed transfer in the batch
+	Index int
+	Err   error
+}
+
+func (e *BatchTransferError) Error() string {
+	return fmt.Sprintf("transfer %d of the batch: %v", e.Index, e.Err)
+}
+
+func (e *BatchTransferError) Unwrap() error {
+	return e.Err
+}
+
+// BatchTransferTx performs a batch of transfers all-or-nothing within a single db transaction:
+// if a transfer fails, none of the transfers of the batch is made.
+// The transfers are made in the order of the batch, the idempotency keys of their parameters are not supported
+func (store *SQLStore) BatchTransferTx(ctx context.Context, args []TransferTxParams) ([]TransferTxResult, error) {
+	results := make([]TransferTxResult, len(args))
+	transfers := make([]TransferTxParams, len(args))
+	for i, arg := range args {
+		transfers[i] = arg.withDefaults()
+	}
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		// lock all the accounts of the batch first, in the order of their IDs like addMoney:
+		// a batch can't deadlock with another batch or transfer locking the same accounts
+		var accountIDs []int64
+		for _, arg := range transfers {
+			accountIDs = append(accountIDs, arg.FromAccountID, arg.ToAccountID)
+			if !arg.Fee.IsZero() {
+				accountIDs = append(accountIDs, arg.FeeAccountID)
+			}
+		}
+		if _, err := lockAccounts(ctx, q, accountIDs...); err != nil {
+			return err
+		}
+
+		for i, arg := range transfers {
+			// the accounts are already locked, this reads the balances updated by the previous transfers
+			debit, err := lockTransferAccounts(ctx, q, arg)
+			if err != nil {
+				return &BatchTransferError{Index: i, Err: err}
+			}
+
+			results[i].Transfer, err = insertTransfer(ctx, q, arg, TransferCaptured)
+			if err != nil {
+				return &BatchTransferError{Index: i, Err: err}
+			}
+
+			if err := postTransfer(ctx, q, arg, debit, &results[i]); err != nil {
+				return &BatchTransferError{Index: i, Err: err}
+			}
+		}
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
@@ -0,0 +1,97 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// ErrAccountNotSettled is returned by CloseAccountTx if the account still has money that isn't swept,
+// a negative balance or money held by pending transfers
+var ErrAccountNotSettled = errors.New("account is not settled")
+
+// CloseAccountTxParams contains the input parameters of the closing of an account
+type CloseAccountTxParams struct {
+	AccountID int64 `json:"account_id"`
+	// Reason and Actor are recorded as the reason and the actor of the change of status of the account
+	Reason string `json:"reason"`
+	Actor  string `json:"actor"`
+	// Sweep: optional transfer of the whole balance from the account before it is closed,
+	// its Amount must be the balance of the account
+	Sweep *TransferTxParams `json:"sweep"`
+}
+
+// CloseAccountTxResult is the result of the closing of an account
+type CloseAccountTxResult struct {
+	Account Account `json:"account"`
+	// Sweep: transfer of the balance of the account, zero value if the balance was already zero
+	Sweep TransferTxResult `json:"sweep"`
+}
+
+// CloseAccountTx closes an account after sweeping its balance within a single db transaction:
+// a closed account rejects the transfers, but it is kept with its entries and transfers
+func (store *SQLStore) CloseAccountTx(ctx context.Context, arg CloseAccountTxParams) (CloseAccountTxResult, error) {
+	var result CloseAccountTxResult
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		accountIDs := []int64{arg.AccountID}
+		if arg.Sweep != nil {
+			accountIDs = append(accountIDs, arg.Sweep.ToAccountID)
+		}
+		accounts, err := lockAccounts(ctx, q, accountIDs...)
+		if err != nil {
+			return err
+		}
+
+		account := accounts[arg.AccountID]
+		// a frozen account can't be closed, its money must stay on it.
+		// A debit frozen account without money has nothing to keep and is closed without sweep
+		emptyDebitFrozen := account.Status == AccountDebitFrozen && account.Balance == 0 && arg.Sweep == nil
+		if !emptyDebitFrozen {
+			if err := checkDebitStatus(account); err != nil {
+				return err
+			}
+		}
+		if account.HeldBalance != 0 {
+			return fmt.Errorf("%w: account [%d] has pending transfers", ErrAccountNotSettled, account.ID)
+		}
+
+		if arg.Sweep == nil {
+			if account.Balance != 0 {
+				return fmt.Errorf("%w: account [%d] balance is %s", ErrAccountNotSettled, account.ID, account.BalanceAmount())
+			}
+		} else {
+			sweep := arg.Sweep.withDefaults()
+			if sweep.FromAccountID != account.ID {
+				return fmt.Errorf("sweep transfer must be from account [%d]", account.ID)
+			}
+			// the balance may have changed since the sweep has been priced
+			if sweep.Amount.Minor <= 0 || sweep.Amount.Minor != account.Balance {
+				return fmt.Errorf("%w: account [%d] balance %s is not the swept amount %s",
+					ErrAccountNotSettled, account.ID, account.BalanceAmount(), sweep.Amount)
+			}
+
+			debit, err := lockTransferAccounts(ctx, q, sweep)
+			if err != nil {
+				return err
+			}
+			result.Sweep.Transfer, err = insertTransfer(ctx, q, sweep, TransferCaptured)
+			if err != nil {
+				return err
+			}
+			if err := postTransfer(ctx, q, sweep, debit, &result.Sweep); err != nil {
+				return err
+			}
+		}
+
+		result.Account, err = q.UpdateAccountStatus(ctx, UpdateAccountStatusParams{
+			ID:              account.ID,
+			Status:          AccountClosed,
+			StatusReason:    arg.Reason,
+			StatusChangedBy: arg.Actor,
+		})
+		return err
+	})
+
+	return result, err
+}
@@ -0,0 +1,57 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// ErrAccountLimitReached is returned by CreateAccountTx if the owner already owns the maximum number of open accounts
+var ErrAccountLimitReached = errors.New("account limit reached")
+
+// CreateAccountTxParams contains the input parameters of the creation of an account
+type CreateAccountTxParams struct {
+	CreateAccountParams
+	// MaxAccounts: maximum number of open accounts owned by the owner, 0 if the accounts aren't capped.
+	// The accounts of other owners the owner is a joint holder of don't count:
+	// they are shared by their owners, who could otherwise use up the accounts of the owner
+	MaxAccounts int64 `json:"max_accounts"`
+}
+
+// CreateAccountTx creates an account held by its owner unless the owner already owns MaxAccounts open accounts,
+// within a single db transaction: the owner is locked so that concurrent creations can't exceed the cap
+func (store *SQLStore) CreateAccountTx(ctx context.Context, arg CreateAccountTxParams) (Account, error) {
+	var account Account
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		if arg.MaxAccounts > 0 {
+			if _, err := q.GetUserForUpdate(ctx, arg.Owner); err != nil {
+				return err
+			}
+
+			count, err := q.CountOpenAccounts(ctx, arg.Owner)
+			if err != nil {
+				return err
+			}
+			if count >= arg.MaxAccounts {
+				return fmt.Errorf("%w: %s owns %d open accounts", ErrAccountLimitReached, arg.Owner, count)
+			}
+		}
+
+		var err error
+		account, err = q.CreateAccount(ctx, arg.CreateAccountParams)
+		if err != nil {
+			return err
+		}
+
+		// the owner of an account is always a holder with the manage permission
+		_, err = q.UpsertAccountHolder(ctx, UpsertAccountHolderParams{
+			AccountID:  account.ID,
+			Username:   account.Owner,
+			Permission: PermissionManage,
+		})
+		return err
+	})
+
+	return account, err
+}
@@ -2,6 +2,7 @@ package db
 
 import (
 	"context"
+	"database/sql"
 	"testing"
 	"time"
 
@@ -68,3 +69,59 @@ func TestListEntries(t *testing.T) {
 		require.Equal(t, entry.AccountID, arg.AccountID)
 	}
 }
+
+func TestListAccountEntries(t *testing.T) {
+	account := createRandomAccount(t)
+	for i := 0; i < 5; i++ {
+		_, err := testQueries.CreateEntry(context.Background(), CreateEntryParams{
+			AccountID: account.ID,
+			Amount:    util.RandomInt(1, 1000),
+		})
+		require.NoError(t, err)
+	}
+	debit, err := testQueries.CreateEntry(context.Background(), CreateEntryParams{AccountID: account.ID, Amount: -50})
+	require.NoError(t, err)
+
+	arg := ListAccountEntriesParams{
+		AccountID: account.ID,
+		Direction: "outgoing",
+		MinAmount: sql.NullInt64{Int64: 50, Valid: true},
+		MaxAmount: sql.NullInt64{Int64: 50, Valid: true},
+		StartTime: sql.NullTime{Time: time.Now().Add(-time.Minute), Valid: true},
+		Limit:     10,
+	}
+	entries, err := testQueries.ListAccountEntries(context.Background(), arg)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	require.Equal(t, debit.ID, entries[0].ID)
+
+	arg = ListAccountEntriesParams{
+		AccountID: account.ID,
+		Direction: "incoming",
+		Limit:     10,
+	}
+	entries, err = testQueries.ListAccountEntries(context.Background(), arg)
+	require.NoError(t, err)
+	require.Len(t, entries, 5)
+	for _, entry := range entries {
+		require.Positive(t, entry.Amount)
+	}
+}
+
+func TestListAccountEntriesAfter(t *testing.T) {
+	account := createRandomAccount(t)
+
+	var entries []Entry
+	for i := 0; i < 5; i++ {
+		entries = append(entries, createRandomEntry(t, account))
+	}
+
+	arg := ListAccountEntriesAfterParams{
+		AccountID: account.ID,
+		AfterID:   entries[1].ID,
+		Limit:     10,
+	}
+	page, err := testQueries.ListAccountEntriesAfter(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, entries[2:], page)
+}
@@ -0,0 +1,92 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"db.sqlc.dev/app/util"
+	"github.com/stretchr/testify/require"
+)
+
+func createRandomIdempotencyKey(t *testing.T, expiresAt time.Time) IdempotencyKey {
+	user := createRandomUser(t)
+
+	arg := CreateIdempotencyKeyParams{
+		Username:       user.Username,
+		IdempotencyKey: util.RandomString(16),
+		RequestHash:    util.RandomString(64),
+		ResponseBody:   json.RawMessage(`{"amount":10}`),
+		ExpiresAt:      expiresAt,
+	}
+
+	key, err := testQueries.CreateIdempotencyKey(context.Background(), arg)
+	require.NoError(t, err)
+	require.NotEmpty(t, key)
+
+	require.Equal(t, arg.Username, key.Username)
+	require.Equal(t, arg.IdempotencyKey, key.IdempotencyKey)
+	require.Equal(t, arg.RequestHash, key.RequestHash)
+	require.JSONEq(t, string(arg.ResponseBody), string(key.ResponseBody))
+	require.WithinDuration(t, arg.ExpiresAt, key.ExpiresAt, time.Second)
+	require.NotZero(t, key.CreatedAt)
+
+	return key
+}
+
+func TestCreateIdempotencyKey(t *testing.T) {
+	createRandomIdempotencyKey(t, time.Now().Add(time.Minute))
+}
+
+func TestCreateIdempotencyKeyInUse(t *testing.T) {
+	key1 := createRandomIdempotencyKey(t, time.Now().Add(time.Minute))
+
+	// the key can't be stored again until it expires
+	_, err := testQueries.CreateIdempotencyKey(context.Background(), CreateIdempotencyKeyParams{
+		Username:       key1.Username,
+		IdempotencyKey: key1.IdempotencyKey,
+		RequestHash:    util.RandomString(64),
+		ResponseBody:   json.RawMessage(`{}`),
+		ExpiresAt:      time.Now().Add(time.Minute),
+	})
+	require.EqualError(t, err, sql.ErrNoRows.Error())
+}
+
+func TestCreateIdempotencyKeyExpired(t *testing.T) {
+	key1 := createRandomIdempotencyKey(t, time.Now().Add(-time.Minute))
+
+	// an expired key is replaced
+	arg := CreateIdempotencyKeyParams{
+		Username:       key1.Username,
+		IdempotencyKey: key1.IdempotencyKey,
+		RequestHash:    util.RandomString(64),
+		ResponseBody:   json.RawMessage(`{}`),
+		ExpiresAt:      time.Now().Add(time.Minute),
+	}
+	key2, err := testQueries.CreateIdempotencyKey(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, arg.RequestHash, key2.RequestHash)
+	require.WithinDuration(t, arg.ExpiresAt, key2.ExpiresAt, time.Second)
+}
+
+func TestGetIdempotencyKey(t *testing.T) {
+	key1 := createRandomIdempotencyKey(t, time.Now().Add(time.Minute))
+
+	key2, err := testQueries.GetIdempotencyKey(context.Background(), GetIdempotencyKeyParams{
+		Username:       key1.Username,
+		IdempotencyKey: key1.IdempotencyKey,
+	})
+	require.NoError(t, err)
+	require.Equal(t, key1.RequestHash, key2.RequestHash)
+	require.JSONEq(t, string(key1.ResponseBody), string(key2.ResponseBody))
+
+	// keys are scoped by user
+	other := createRandomUser(t)
+	_, err = testQueries.GetIdempotencyKey(context.Background(), GetIdempotencyKeyParams{
+		Username:       other.Username,
+		IdempotencyKey: key1.IdempotencyKey,
+	})
+	require.EqualError(t, err, sql.ErrNoRows.Error())
+}
@@ -0,0 +1,170 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"db.sqlc.dev/app/interest"
+	"db.sqlc.dev/app/money"
+)
+
+// ErrInterestAccrued is returned by AccrueInterestTx if the interest of the day has already been accrued
+var ErrInterestAccrued = errors.New("interest already accrued")
+
+// ErrExpenseAccountUnavailable is returned by PostInterestTx if the interest expense account is frozen or closed
+var ErrExpenseAccountUnavailable = errors.New("interest expense account can't pay")
+
+// AccrueInterestTxParams contains the input parameters of the accrual of the interest of an account for a day
+type AccrueInterestTxParams struct {
+	AccountID int64 `json:"account_id"`
+	// Date: day of the accrual, the interest is computed on the balance of the account when it is accrued,
+	// which is today's balance for a past day
+	Date       time.Time `json:"date"`
+	AnnualRate string    `json:"annual_rate"`
+}
+
+// AccrueInterestTx records the interest of an account for a day within a single db transaction.
+// The interest of a month is accrued day after day as a running total rounded to a minor unit
+// (see interest.Accrued), so the rounding error of the month stays under half a minor unit
+func (store *SQLStore) AccrueInterestTx(ctx context.Context, arg AccrueInterestTxParams) (InterestAccrual, error) {
+	var accrual InterestAccrual
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		// the balance can't change while the interest is computed on it
+		account, err := q.GetAccountForUpdate(ctx, arg.AccountID)
+		if err != nil {
+			return err
+		}
+		// a frozen account still earns interest, but a closed account doesn't
+		if account.Status == AccountClosed {
+			return fmt.Errorf("%w: account [%d]", ErrAccountClosed, account.ID)
+		}
+
+		accruals, err := q.ListUnpostedInterestAccruals(ctx, ListUnpostedInterestAccrualsParams{
+			AccountID: account.ID,
+			Before:    arg.Date,
+		})
+		if err != nil {
+			return err
+		}
+
+		// running total of the interest of the month of the day
+		var days []interest.Day
+		var accrued int64
+		for _, previous := range accruals {
+			if !sameMonth(previous.AccrualDate, arg.Date) {
+				continue
+			}
+			days = append(days, interest.Day{Balance: previous.Balance, AnnualRate: previous.AnnualRate})
+			accrued += previous.Amount
+		}
+		days = append(days, interest.Day{Balance: account.Balance, AnnualRate: arg.AnnualRate})
+		total, err := interest.Accrued(days)
+		if err != nil {
+			return err
+		}
+
+		accrual, err = q.CreateInterestAccrual(ctx, CreateInterestAccrualParams{
+			AccountID:   account.ID,
+			AccrualDate: arg.Date,
+			Balance:     account.Balance,
+			AnnualRate:  arg.AnnualRate,
+			Amount:      total - accrued,
+		})
+		if err == sql.ErrNoRows {
+			return fmt.Errorf("%w: account [%d] on %s", ErrInterestAccrued, account.ID, arg.Date.Format("2006-01-02"))
+		}
+		return err
+	})
+
+	return accrual, err
+}
+
+// sameMonth reports whether both dates are in the same month of the same year
+func sameMonth(a time.Time, b time.Time) bool {
+	return a.Year() == b.Year() && a.Month() == b.Month()
+}
+
+// PostInterestTxParams contains the input parameters of the posting of the interest accrued on an account
+type PostInterestTxParams struct {
+	AccountID int64 `json:"account_id"`
+	// ExpenseAccountID: house account paying the interest, in the currency of the account
+	ExpenseAccountID int64 `json:"expense_account_id"`
+	// Before: the interest accrued before this date is posted, e.g. the first day of the current month
+	Before time.Time `json:"before"`
+}
+
+// PostInterestTxResult is the result of the posting of the interest of an account
+type PostInterestTxResult struct {
+	// Amount: interest paid in minor units, the transfer is the zero value if no interest was paid
+	Amount int64 `json:"amount"`
+	TransferTxResult
+}
+
+// PostInterestTx pays the accrued interest of an account with a transfer from the house interest expense account
+// within a single db transaction. The expense account pays the interest out of its overdraft:
+// it must be given an overdraft limit covering the interest paid, or the posting fails with ErrInsufficientFunds
+func (store *SQLStore) PostInterestTx(ctx context.Context, arg PostInterestTxParams) (PostInterestTxResult, error) {
+	var result PostInterestTxResult
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		accounts, err := lockAccounts(ctx, q, arg.AccountID, arg.ExpenseAccountID)
+		if err != nil {
+			return err
+		}
+		account := accounts[arg.AccountID]
+		expenseAccount := accounts[arg.ExpenseAccountID]
+		if expenseAccount.Currency != account.Currency {
+			return fmt.Errorf("%w: expense account [%d] pays %s interest to account [%d] in %s",
+				money.ErrCurrencyMismatch, expenseAccount.ID, expenseAccount.Currency, account.ID, account.Currency)
+		}
+
+		accruals, err := q.ListUnpostedInterestAccruals(ctx, ListUnpostedInterestAccrualsParams{
+			AccountID: account.ID,
+			Before:    arg.Before,
+		})
+		if err != nil {
+			return err
+		}
+		for _, accrual := range accruals {
+			result.Amount += accrual.Amount
+		}
+
+		var transferID sql.NullInt64
+		if result.Amount > 0 {
+			// a frozen account keeps its interest until it can receive money again
+			if err := checkCreditStatus(account); err != nil {
+				return err
+			}
+			// a frozen or closed expense account can't pay, reported apart from the status of the account
+			if err := checkDebitStatus(expenseAccount); err != nil {
+				return fmt.Errorf("%w: %v", ErrExpenseAccountUnavailable, err)
+			}
+
+			transfer := TransferTxParams{
+				FromAccountID: expenseAccount.ID,
+				ToAccountID:   account.ID,
+				Amount:        money.New(result.Amount, account.Currency),
+			}.withDefaults()
+			result.Transfer, err = insertTransfer(ctx, q, transfer, TransferCaptured)
+			if err != nil {
+				return err
+			}
+			if err := postTransfer(ctx, q, transfer, transfer.Amount, &result.TransferTxResult); err != nil {
+				return err
+			}
+			transferID = sql.NullInt64{Int64: result.Transfer.ID, Valid: true}
+		}
+
+		return q.PostInterestAccruals(ctx, PostInterestAccrualsParams{
+			TransferID: transferID,
+			AccountID:  account.ID,
+			Before:     arg.Before,
+		})
+	})
+
+	return result, err
+}
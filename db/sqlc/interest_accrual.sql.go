@@ -0,0 +1,259 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.15.0
+// source: interest_accrual.sql
+
+package db
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+const createInterestAccrual = `-- name: CreateInterestAccrual :one
+INSERT INTO interest_accruals (
+  account_id,
+  accrual_date,
+  balance,
+  annual_rate,
+  amount
+) VALUES (
+  $1, $2, $3, $4, $5
+)
+ON CONFLICT (account_id, accrual_date) DO NOTHING
+RETURNING id, account_id, accrual_date, balance, annual_rate, amount, transfer_id, posted_at, created_at
+`
+
+type CreateInterestAccrualParams struct {
+	AccountID   int64     `json:"account_id"`
+	AccrualDate time.Time `json:"accrual_date"`
+	Balance     int64     `json:"balance"`
+	AnnualRate  string    `json:"annual_rate"`
+	Amount      int64     `json:"amount"`
+}
+
+func (q *Queries) CreateInterestAccrual(ctx context.Context, arg CreateInterestAccrualParams) (InterestAccrual, error) {
+	row := q.db.QueryRowContext(ctx, createInterestAccrual,
+		arg.AccountID,
+		arg.AccrualDate,
+		arg.Balance,
+		arg.AnnualRate,
+		arg.Amount,
+	)
+	var i InterestAccrual
+	err := row.Scan(
+		&i.ID,
+		&i.AccountID,
+		&i.AccrualDate,
+		&i.Balance,
+		&i.AnnualRate,
+		&i.Amount,
+		&i.TransferID,
+		&i.PostedAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const getLastInterestAccrualDate = `-- name: GetLastInterestAccrualDate :one
+SELECT accrual_date FROM interest_accruals
+ORDER BY accrual_date DESC
+LIMIT 1
+`
+
+func (q *Queries) GetLastInterestAccrualDate(ctx context.Context) (time.Time, error) {
+	row := q.db.QueryRowContext(ctx, getLastInterestAccrualDate)
+	var accrual_date time.Time
+	err := row.Scan(&accrual_date)
+	return accrual_date, err
+}
+
+const listInterestAccounts = `-- name: ListInterestAccounts :many
+SELECT id, owner, balance, currency, created_at, overdraft_limit, type, held_balance, status, status_reason, status_changed_by, status_changed_at, nickname FROM accounts
+WHERE accounts.currency = $1
+AND accounts.type = $2
+AND accounts.status <> 'closed'
+AND accounts.id > $3
+AND NOT EXISTS (
+  SELECT 1 FROM interest_accruals
+  WHERE interest_accruals.account_id = accounts.id
+  AND interest_accruals.accrual_date = $4
+)
+ORDER BY accounts.id
+LIMIT $5
+`
+
+type ListInterestAccountsParams struct {
+	Currency    string    `json:"currency"`
+	Type        string    `json:"type"`
+	AfterID     int64     `json:"after_id"`
+	AccrualDate time.Time `json:"accrual_date"`
+	Limit       int32     `json:"limit"`
+}
+
+func (q *Queries) ListInterestAccounts(ctx context.Context, arg ListInterestAccountsParams) ([]Account, error) {
+	rows, err := q.db.QueryContext(ctx, listInterestAccounts,
+		arg.Currency,
+		arg.Type,
+		arg.AfterID,
+		arg.AccrualDate,
+		arg.Limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Account{}
+	for rows.Next() {
+		var i Account
+		if err := rows.Scan(
+			&i.ID,
+			&i.Owner,
+			&i.Balance,
+			&i.Currency,
+			&i.CreatedAt,
+			&i.OverdraftLimit,
+			&i.Type,
+			&i.HeldBalance,
+			&i.Status,
+			&i.StatusReason,
+			&i.StatusChangedBy,
+			&i.StatusChangedAt,
+			&i.Nickname,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listUnpostedInterestAccounts = `-- name: ListUnpostedInterestAccounts :many
+SELECT id, owner, balance, currency, created_at, overdraft_limit, type, held_balance, status, status_reason, status_changed_by, status_changed_at, nickname FROM accounts
+WHERE accounts.status IN ('active', 'debit_frozen')
+AND accounts.id > $1
+AND EXISTS (
+  SELECT 1 FROM interest_accruals
+  WHERE interest_accruals.account_id = accounts.id
+  AND interest_accruals.posted_at IS NULL
+  AND interest_accruals.accrual_date < $2
+)
+ORDER BY accounts.id
+LIMIT $3
+`
+
+type ListUnpostedInterestAccountsParams struct {
+	AfterID int64     `json:"after_id"`
+	Before  time.Time `json:"before"`
+	Limit   int32     `json:"limit"`
+}
+
+func (q *Queries) ListUnpostedInterestAccounts(ctx context.Context, arg ListUnpostedInterestAccountsParams) ([]Account, error) {
+	rows, err := q.db.QueryContext(ctx, listUnpostedInterestAccounts, arg.AfterID, arg.Before, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Account{}
+	for rows.Next() {
+		var i Account
+		if err := rows.Scan(
+			&i.ID,
+			&i.Owner,
+			&i.Balance,
+			&i.Currency,
+			&i.CreatedAt,
+			&i.OverdraftLimit,
+			&i.Type,
+			&i.HeldBalance,
+			&i.Status,
+			&i.StatusReason,
+			&i.StatusChangedBy,
+			&i.StatusChangedAt,
+			&i.Nickname,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listUnpostedInterestAccruals = `-- name: ListUnpostedInterestAccruals :many
+SELECT id, account_id, accrual_date, balance, annual_rate, amount, transfer_id, posted_at, created_at FROM interest_accruals
+WHERE account_id = $1
+AND posted_at IS NULL
+AND accrual_date < $2
+ORDER BY accrual_date
+`
+
+type ListUnpostedInterestAccrualsParams struct {
+	AccountID int64     `json:"account_id"`
+	Before    time.Time `json:"before"`
+}
+
+func (q *Queries) ListUnpostedInterestAccruals(ctx context.Context, arg ListUnpostedInterestAccrualsParams) ([]InterestAccrual, error) {
+	rows, err := q.db.QueryContext(ctx, listUnpostedInterestAccruals, arg.AccountID, arg.Before)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []InterestAccrual{}
+	for rows.Next() {
+		var i InterestAccrual
+		if err := rows.Scan(
+			&i.ID,
+			&i.AccountID,
+			&i.AccrualDate,
+			&i.Balance,
+			&i.AnnualRate,
+			&i.Amount,
+			&i.TransferID,
+			&i.PostedAt,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const postInterestAccruals = `-- name: PostInterestAccruals :exec
+UPDATE interest_accruals
+set transfer_id = $1,
+  posted_at = now()
+WHERE account_id = $2
+AND posted_at IS NULL
+AND accrual_date < $3
+`
+
+type PostInterestAccrualsParams struct {
+	TransferID sql.NullInt64 `json:"transfer_id"`
+	AccountID  int64         `json:"account_id"`
+	Before     time.Time     `json:"before"`
+}
+
+func (q *Queries) PostInterestAccruals(ctx context.Context, arg PostInterestAccrualsParams) error {
+	_, err := q.db.ExecContext(ctx, postInterestAccruals, arg.TransferID, arg.AccountID, arg.Before)
+	return err
+}
@@ -0,0 +1,239 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"db.sqlc.dev/app/util"
+	"github.com/stretchr/testify/require"
+)
+
+// createSavingsAccount creates a savings account in USD with the input balance
+func createSavingsAccount(t *testing.T, balance int64) Account {
+	user := createRandomUser(t)
+
+	account, err := testQueries.CreateAccount(context.Background(), CreateAccountParams{
+		Owner:    user.Username,
+		Balance:  balance,
+		Currency: util.USD,
+		Type:     util.SavingsAccount,
+	})
+	require.NoError(t, err)
+	require.Equal(t, util.SavingsAccount, account.Type)
+	return account
+}
+
+// createExpenseAccount creates a house interest expense account in USD, which pays the interest out of its overdraft
+func createExpenseAccount(t *testing.T) Account {
+	account := createFundedAccount(t, 0)
+
+	account, err := testQueries.UpdateAccountOverdraftLimit(context.Background(), UpdateAccountOverdraftLimitParams{
+		ID:             account.ID,
+		OverdraftLimit: 1000000,
+	})
+	require.NoError(t, err)
+	return account
+}
+
+func date(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+func TestAccrueInterestTx(t *testing.T) {
+	store := NewStore(testDB)
+	account := createSavingsAccount(t, 10000)
+
+	// 10000 * 0.02 / 365 = 0.548 a day: the running total of the month is rounded, not the interest of each day
+	expected := []int64{1, 0, 1}
+	for i, amount := range expected {
+		accrual, err := store.AccrueInterestTx(context.Background(), AccrueInterestTxParams{
+			AccountID:  account.ID,
+			Date:       date(2023, time.January, 29+i),
+			AnnualRate: "0.02",
+		})
+		require.NoError(t, err)
+		require.Equal(t, account.ID, accrual.AccountID)
+		require.Equal(t, account.Balance, accrual.Balance)
+		require.Equal(t, amount, accrual.Amount)
+		require.False(t, accrual.PostedAt.Valid)
+	}
+
+	_, err := store.AccrueInterestTx(context.Background(), AccrueInterestTxParams{
+		AccountID:  account.ID,
+		Date:       date(2023, time.January, 29),
+		AnnualRate: "0.02",
+	})
+	require.ErrorIs(t, err, ErrInterestAccrued)
+
+	// the running total starts again with each month
+	accrual, err := store.AccrueInterestTx(context.Background(), AccrueInterestTxParams{
+		AccountID:  account.ID,
+		Date:       date(2023, time.February, 1),
+		AnnualRate: "0.02",
+	})
+	require.NoError(t, err)
+	require.Equal(t, int64(1), accrual.Amount)
+}
+
+func TestPostInterestTx(t *testing.T) {
+	store := NewStore(testDB)
+	account := createSavingsAccount(t, 10000)
+	expenseAccount := createExpenseAccount(t)
+
+	for _, day := range []time.Time{date(2023, time.March, 30), date(2023, time.March, 31), date(2023, time.April, 1)} {
+		_, err := store.AccrueInterestTx(context.Background(), AccrueInterestTxParams{
+			AccountID:  account.ID,
+			Date:       day,
+			AnnualRate: "0.0365",
+		})
+		require.NoError(t, err)
+	}
+
+	// the interest of April isn't posted yet
+	arg := PostInterestTxParams{
+		AccountID:        account.ID,
+		ExpenseAccountID: expenseAccount.ID,
+		Before:           date(2023, time.April, 1),
+	}
+	result, err := store.PostInterestTx(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, int64(2), result.Amount)
+	require.Equal(t, expenseAccount.ID, result.Transfer.FromAccountID)
+	require.Equal(t, account.ID, result.Transfer.ToAccountID)
+	require.Equal(t, account.Balance+2, result.ToAccount.Balance)
+	require.Equal(t, int64(-2), result.FromAccount.Balance)
+	require.Equal(t, int64(2), result.ToEntry.Amount)
+
+	accruals, err := testQueries.ListUnpostedInterestAccruals(context.Background(), ListUnpostedInterestAccrualsParams{
+		AccountID: account.ID,
+		Before:    date(2023, time.May, 1),
+	})
+	require.NoError(t, err)
+	require.Len(t, accruals, 1)
+	require.Equal(t, date(2023, time.April, 1), accruals[0].AccrualDate.UTC())
+
+	// the posted interest isn't paid twice
+	result, err = store.PostInterestTx(context.Background(), arg)
+	require.NoError(t, err)
+	require.Zero(t, result.Amount)
+	require.Zero(t, result.Transfer.ID)
+}
+
+func TestPostInterestTxFrozenAccount(t *testing.T) {
+	store := NewStore(testDB)
+	account := createSavingsAccount(t, 10000)
+	expenseAccount := createExpenseAccount(t)
+
+	_, err := store.AccrueInterestTx(context.Background(), AccrueInterestTxParams{
+		AccountID:  account.ID,
+		Date:       date(2023, time.May, 31),
+		AnnualRate: "0.0365",
+	})
+	require.NoError(t, err)
+
+	_, err = store.UpdateAccountStatusTx(context.Background(), UpdateAccountStatusTxParams{
+		AccountID: account.ID,
+		Status:    AccountFrozen,
+	})
+	require.NoError(t, err)
+
+	// the interest stays accrued until the account can receive money again
+	arg := PostInterestTxParams{
+		AccountID:        account.ID,
+		ExpenseAccountID: expenseAccount.ID,
+		Before:           date(2023, time.June, 1),
+	}
+	_, err = store.PostInterestTx(context.Background(), arg)
+	require.ErrorIs(t, err, ErrAccountFrozen)
+
+	_, err = store.UpdateAccountStatusTx(context.Background(), UpdateAccountStatusTxParams{
+		AccountID: account.ID,
+		Status:    AccountActive,
+	})
+	require.NoError(t, err)
+
+	result, err := store.PostInterestTx(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, int64(1), result.Amount)
+}
+
+func TestPostInterestTxFrozenExpenseAccount(t *testing.T) {
+	store := NewStore(testDB)
+	account := createSavingsAccount(t, 10000)
+	expenseAccount := createExpenseAccount(t)
+
+	_, err := store.AccrueInterestTx(context.Background(), AccrueInterestTxParams{
+		AccountID:  account.ID,
+		Date:       date(2023, time.July, 31),
+		AnnualRate: "0.0365",
+	})
+	require.NoError(t, err)
+
+	_, err = store.UpdateAccountStatusTx(context.Background(), UpdateAccountStatusTxParams{
+		AccountID: expenseAccount.ID,
+		Status:    AccountDebitFrozen,
+	})
+	require.NoError(t, err)
+
+	// the interest stays accrued until the expense account can pay again
+	arg := PostInterestTxParams{
+		AccountID:        account.ID,
+		ExpenseAccountID: expenseAccount.ID,
+		Before:           date(2023, time.August, 1),
+	}
+	_, err = store.PostInterestTx(context.Background(), arg)
+	require.ErrorIs(t, err, ErrExpenseAccountUnavailable)
+	require.NotErrorIs(t, err, ErrAccountFrozen)
+
+	_, err = store.UpdateAccountStatusTx(context.Background(), UpdateAccountStatusTxParams{
+		AccountID: expenseAccount.ID,
+		Status:    AccountActive,
+	})
+	require.NoError(t, err)
+
+	result, err := store.PostInterestTx(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, int64(1), result.Amount)
+}
+
+func TestInterestAccountsFrozenAccount(t *testing.T) {
+	store := NewStore(testDB)
+	account := createSavingsAccount(t, 10000)
+
+	_, err := store.AccrueInterestTx(context.Background(), AccrueInterestTxParams{
+		AccountID:  account.ID,
+		Date:       date(2023, time.July, 30),
+		AnnualRate: "0.0365",
+	})
+	require.NoError(t, err)
+
+	_, err = store.UpdateAccountStatusTx(context.Background(), UpdateAccountStatusTxParams{
+		AccountID: account.ID,
+		Status:    AccountFrozen,
+	})
+	require.NoError(t, err)
+
+	// a frozen account still earns interest
+	accounts, err := testQueries.ListInterestAccounts(context.Background(), ListInterestAccountsParams{
+		Currency:    util.USD,
+		Type:        util.SavingsAccount,
+		AfterID:     account.ID - 1,
+		AccrualDate: date(2023, time.July, 31),
+		Limit:       1,
+	})
+	require.NoError(t, err)
+	require.Len(t, accounts, 1)
+	require.Equal(t, account.ID, accounts[0].ID)
+
+	// but its interest isn't posted while it can't receive money
+	accounts, err = testQueries.ListUnpostedInterestAccounts(context.Background(), ListUnpostedInterestAccountsParams{
+		AfterID: account.ID - 1,
+		Before:  date(2023, time.August, 1),
+		Limit:   1,
+	})
+	require.NoError(t, err)
+	for _, listed := range accounts {
+		require.NotEqual(t, account.ID, listed.ID)
+	}
+}
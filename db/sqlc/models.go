@@ -5,15 +5,34 @@
 package db
 
 import (
+	"database/sql"
+	"encoding/json"
 	"time"
+
+	"github.com/google/uuid"
 )
 
 type Account struct {
-	ID        int64     `json:"id"`
-	Owner     string    `json:"owner"`
-	Balance   int64     `json:"balance"`
-	Currency  string    `json:"currency"`
-	CreatedAt time.Time `json:"created_at"`
+	ID              int64     `json:"id"`
+	Owner           string    `json:"owner"`
+	Balance         int64     `json:"balance"`
+	Currency        string    `json:"currency"`
+	CreatedAt       time.Time `json:"created_at"`
+	OverdraftLimit  int64     `json:"overdraft_limit"`
+	Type            string    `json:"type"`
+	HeldBalance     int64     `json:"held_balance"`
+	Status          string    `json:"status"`
+	StatusReason    string    `json:"status_reason"`
+	StatusChangedBy string    `json:"status_changed_by"`
+	StatusChangedAt time.Time `json:"status_changed_at"`
+	Nickname        string    `json:"nickname"`
+}
+
+type AccountHolder struct {
+	AccountID  int64     `json:"account_id"`
+	Username   string    `json:"username"`
+	Permission string    `json:"permission"`
+	CreatedAt  time.Time `json:"created_at"`
 }
 
 type Entry struct {
@@ -23,6 +42,73 @@ type Entry struct {
 	CreatedAt time.Time `json:"created_at"`
 }
 
+type IdempotencyKey struct {
+	Username       string          `json:"username"`
+	IdempotencyKey string          `json:"idempotency_key"`
+	RequestHash    string          `json:"request_hash"`
+	ResponseBody   json.RawMessage `json:"response_body"`
+	ExpiresAt      time.Time       `json:"expires_at"`
+	CreatedAt      time.Time       `json:"created_at"`
+}
+
+type InterestAccrual struct {
+	ID          int64         `json:"id"`
+	AccountID   int64         `json:"account_id"`
+	AccrualDate time.Time     `json:"accrual_date"`
+	Balance     int64         `json:"balance"`
+	AnnualRate  string        `json:"annual_rate"`
+	Amount      int64         `json:"amount"`
+	TransferID  sql.NullInt64 `json:"transfer_id"`
+	PostedAt    sql.NullTime  `json:"posted_at"`
+	CreatedAt   time.Time     `json:"created_at"`
+}
+
+type RevokedToken struct {
+	ID        uuid.UUID `json:"id"`
+	Username  string    `json:"username"`
+	ExpiresAt time.Time `json:"expires_at"`
+	RevokedAt time.Time `json:"revoked_at"`
+}
+
+type ScheduledTransfer struct {
+	ID            int64  `json:"id"`
+	Owner         string `json:"owner"`
+	FromAccountID int64  `json:"from_account_id"`
+	ToAccountID   int64  `json:"to_account_id"`
+	// must be positive only
+	Amount        int64        `json:"amount"`
+	Currency      string       `json:"currency"`
+	Recurrence    string       `json:"recurrence"`
+	Status        string       `json:"status"`
+	NextRunAt     time.Time    `json:"next_run_at"`
+	NextAttemptAt time.Time    `json:"next_attempt_at"`
+	Attempts      int32        `json:"attempts"`
+	EndAt         sql.NullTime `json:"end_at"`
+	CreatedAt     time.Time    `json:"created_at"`
+}
+
+type ScheduledTransferRun struct {
+	ID                  int64         `json:"id"`
+	ScheduledTransferID int64         `json:"scheduled_transfer_id"`
+	RunAt               time.Time     `json:"run_at"`
+	Attempt             int32         `json:"attempt"`
+	Status              string        `json:"status"`
+	TransferID          sql.NullInt64 `json:"transfer_id"`
+	Error               string        `json:"error"`
+	CreatedAt           time.Time     `json:"created_at"`
+}
+
+type Session struct {
+	ID           uuid.UUID `json:"id"`
+	Username     string    `json:"username"`
+	RefreshToken string    `json:"refresh_token"`
+	UserAgent    string    `json:"user_agent"`
+	ClientIp     string    `json:"client_ip"`
+	IsBlocked    bool      `json:"is_blocked"`
+	ExpiresAt    time.Time `json:"expires_at"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
 type Transfer struct {
 	ID            int64 `json:"id"`
 	FromAccountID int64 `json:"from_account_id"`
@@ -30,6 +116,22 @@ type Transfer struct {
 	// must be positive only
 	Amount    int64     `json:"amount"`
 	CreatedAt time.Time `json:"created_at"`
+	// must be positive only
+	ToAmount       int64         `json:"to_amount"`
+	ExchangeRate   string        `json:"exchange_rate"`
+	Fee            int64         `json:"fee"`
+	ReversalOf     sql.NullInt64 `json:"reversal_of"`
+	ReversedAmount int64         `json:"reversed_amount"`
+	Status         string        `json:"status"`
+}
+
+type TransferHold struct {
+	TransferID   int64         `json:"transfer_id"`
+	AccountID    int64         `json:"account_id"`
+	Amount       int64         `json:"amount"`
+	FeeAccountID sql.NullInt64 `json:"fee_account_id"`
+	ExpiresAt    time.Time     `json:"expires_at"`
+	CreatedAt    time.Time     `json:"created_at"`
 }
 
 type User struct {
@@ -39,4 +141,6 @@ type User struct {
 	Email             string    `json:"email"`
 	PasswordChangedAt time.Time `json:"password_changed_at"`
 	CreatedAt         time.Time `json:"created_at"`
+	TokensRevokedAt   time.Time `json:"tokens_revoked_at"`
+	Role              string    `json:"role"`
 }
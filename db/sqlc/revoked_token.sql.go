@@ -0,0 +1,58 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.15.0
+// source: revoked_token.sql
+
+package db
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const createRevokedToken = `-- name: CreateRevokedToken :one
+INSERT INTO revoked_tokens (
+  id,
+  username,
+  expires_at
+) VALUES (
+  $1, $2, $3
+) RETURNING id, username, expires_at, revoked_at
+`
+
+type CreateRevokedTokenParams struct {
+	ID        uuid.UUID `json:"id"`
+	Username  string    `json:"username"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+func (q *Queries) CreateRevokedToken(ctx context.Context, arg CreateRevokedTokenParams) (RevokedToken, error) {
+	row := q.db.QueryRowContext(ctx, createRevokedToken, arg.ID, arg.Username, arg.ExpiresAt)
+	var i RevokedToken
+	err := row.Scan(
+		&i.ID,
+		&i.Username,
+		&i.ExpiresAt,
+		&i.RevokedAt,
+	)
+	return i, err
+}
+
+const getRevokedToken = `-- name: GetRevokedToken :one
+SELECT id, username, expires_at, revoked_at FROM revoked_tokens
+WHERE id = $1 LIMIT 1
+`
+
+func (q *Queries) GetRevokedToken(ctx context.Context, id uuid.UUID) (RevokedToken, error) {
+	row := q.db.QueryRowContext(ctx, getRevokedToken, id)
+	var i RevokedToken
+	err := row.Scan(
+		&i.ID,
+		&i.Username,
+		&i.ExpiresAt,
+		&i.RevokedAt,
+	)
+	return i, err
+}
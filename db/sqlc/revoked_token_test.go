@@ -0,0 +1,52 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func createRandomRevokedToken(t *testing.T) RevokedToken {
+	user := createRandomUser(t)
+
+	arg := CreateRevokedTokenParams{
+		ID:        uuid.New(),
+		Username:  user.Username,
+		ExpiresAt: time.Now().Add(time.Minute),
+	}
+
+	revokedToken, err := testQueries.CreateRevokedToken(context.Background(), arg)
+	require.NoError(t, err)
+	require.NotEmpty(t, revokedToken)
+
+	require.Equal(t, arg.ID, revokedToken.ID)
+	require.Equal(t, arg.Username, revokedToken.Username)
+	require.WithinDuration(t, arg.ExpiresAt, revokedToken.ExpiresAt, time.Second)
+	require.NotZero(t, revokedToken.RevokedAt)
+
+	return revokedToken
+}
+
+func TestCreateRevokedToken(t *testing.T) {
+	createRandomRevokedToken(t)
+}
+
+func TestGetRevokedToken(t *testing.T) {
+	revokedToken1 := createRandomRevokedToken(t)
+	revokedToken2, err := testQueries.GetRevokedToken(context.Background(), revokedToken1.ID)
+	require.NoError(t, err)
+	require.NotEmpty(t, revokedToken2)
+
+	require.Equal(t, revokedToken1.ID, revokedToken2.ID)
+	require.Equal(t, revokedToken1.Username, revokedToken2.Username)
+	require.WithinDuration(t, revokedToken1.ExpiresAt, revokedToken2.ExpiresAt, time.Second)
+	require.WithinDuration(t, revokedToken1.RevokedAt, revokedToken2.RevokedAt, time.Second)
+
+	_, err = testQueries.GetRevokedToken(context.Background(), uuid.New())
+	require.Error(t, err)
+	require.EqualError(t, err, sql.ErrNoRows.Error())
+}
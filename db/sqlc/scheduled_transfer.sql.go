@@ -0,0 +1,405 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.15.0
+// source: scheduled_transfer.sql
+
+package db
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+const claimDueScheduledTransfers = `-- name: ClaimDueScheduledTransfers :many
+UPDATE scheduled_transfers
+SET next_attempt_at = $1
+WHERE id IN (
+  SELECT id FROM scheduled_transfers
+  WHERE status = 'active' AND next_attempt_at <= now()
+  ORDER BY next_attempt_at
+  LIMIT $2
+  FOR UPDATE SKIP LOCKED
+)
+RETURNING id, owner, from_account_id, to_account_id, amount, currency, recurrence, status, next_run_at, next_attempt_at, attempts, end_at, created_at
+`
+
+type ClaimDueScheduledTransfersParams struct {
+	LeaseUntil time.Time `json:"lease_until"`
+	MaxCount   int32     `json:"max_count"`
+}
+
+// the claimed transfers aren't due again until lease_until, so that another scheduler doesn't run them concurrently
+func (q *Queries) ClaimDueScheduledTransfers(ctx context.Context, arg ClaimDueScheduledTransfersParams) ([]ScheduledTransfer, error) {
+	rows, err := q.db.QueryContext(ctx, claimDueScheduledTransfers, arg.LeaseUntil, arg.MaxCount)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ScheduledTransfer{}
+	for rows.Next() {
+		var i ScheduledTransfer
+		if err := rows.Scan(
+			&i.ID,
+			&i.Owner,
+			&i.FromAccountID,
+			&i.ToAccountID,
+			&i.Amount,
+			&i.Currency,
+			&i.Recurrence,
+			&i.Status,
+			&i.NextRunAt,
+			&i.NextAttemptAt,
+			&i.Attempts,
+			&i.EndAt,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const createScheduledTransfer = `-- name: CreateScheduledTransfer :one
+INSERT INTO scheduled_transfers (
+  owner,
+  from_account_id,
+  to_account_id,
+  amount,
+  currency,
+  recurrence,
+  next_run_at,
+  next_attempt_at,
+  end_at
+) VALUES (
+  $1, $2, $3, $4, $5, $6, $7, $7, $8
+) RETURNING id, owner, from_account_id, to_account_id, amount, currency, recurrence, status, next_run_at, next_attempt_at, attempts, end_at, created_at
+`
+
+type CreateScheduledTransferParams struct {
+	Owner         string       `json:"owner"`
+	FromAccountID int64        `json:"from_account_id"`
+	ToAccountID   int64        `json:"to_account_id"`
+	Amount        int64        `json:"amount"`
+	Currency      string       `json:"currency"`
+	Recurrence    string       `json:"recurrence"`
+	NextRunAt     time.Time    `json:"next_run_at"`
+	EndAt         sql.NullTime `json:"end_at"`
+}
+
+func (q *Queries) CreateScheduledTransfer(ctx context.Context, arg CreateScheduledTransferParams) (ScheduledTransfer, error) {
+	row := q.db.QueryRowContext(ctx, createScheduledTransfer,
+		arg.Owner,
+		arg.FromAccountID,
+		arg.ToAccountID,
+		arg.Amount,
+		arg.Currency,
+		arg.Recurrence,
+		arg.NextRunAt,
+		arg.EndAt,
+	)
+	var i ScheduledTransfer
+	err := row.Scan(
+		&i.ID,
+		&i.Owner,
+		&i.FromAccountID,
+		&i.ToAccountID,
+		&i.Amount,
+		&i.Currency,
+		&i.Recurrence,
+		&i.Status,
+		&i.NextRunAt,
+		&i.NextAttemptAt,
+		&i.Attempts,
+		&i.EndAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const createScheduledTransferRun = `-- name: CreateScheduledTransferRun :one
+INSERT INTO scheduled_transfer_runs (
+  scheduled_transfer_id,
+  run_at,
+  attempt,
+  status,
+  transfer_id,
+  error
+) VALUES (
+  $1, $2, $3, $4, $5, $6
+)
+ON CONFLICT (scheduled_transfer_id, run_at) WHERE status = 'succeeded' DO NOTHING
+RETURNING id, scheduled_transfer_id, run_at, attempt, status, transfer_id, error, created_at
+`
+
+type CreateScheduledTransferRunParams struct {
+	ScheduledTransferID int64         `json:"scheduled_transfer_id"`
+	RunAt               time.Time     `json:"run_at"`
+	Attempt             int32         `json:"attempt"`
+	Status              string        `json:"status"`
+	TransferID          sql.NullInt64 `json:"transfer_id"`
+	Error               string        `json:"error"`
+}
+
+// no row is returned if the occurrence already has a succeeded run
+func (q *Queries) CreateScheduledTransferRun(ctx context.Context, arg CreateScheduledTransferRunParams) (ScheduledTransferRun, error) {
+	row := q.db.QueryRowContext(ctx, createScheduledTransferRun,
+		arg.ScheduledTransferID,
+		arg.RunAt,
+		arg.Attempt,
+		arg.Status,
+		arg.TransferID,
+		arg.Error,
+	)
+	var i ScheduledTransferRun
+	err := row.Scan(
+		&i.ID,
+		&i.ScheduledTransferID,
+		&i.RunAt,
+		&i.Attempt,
+		&i.Status,
+		&i.TransferID,
+		&i.Error,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const deleteScheduledTransfer = `-- name: DeleteScheduledTransfer :exec
+DELETE FROM scheduled_transfers
+WHERE id = $1
+`
+
+func (q *Queries) DeleteScheduledTransfer(ctx context.Context, id int64) error {
+	_, err := q.db.ExecContext(ctx, deleteScheduledTransfer, id)
+	return err
+}
+
+const getScheduledTransfer = `-- name: GetScheduledTransfer :one
+SELECT id, owner, from_account_id, to_account_id, amount, currency, recurrence, status, next_run_at, next_attempt_at, attempts, end_at, created_at FROM scheduled_transfers
+WHERE id = $1 LIMIT 1
+`
+
+func (q *Queries) GetScheduledTransfer(ctx context.Context, id int64) (ScheduledTransfer, error) {
+	row := q.db.QueryRowContext(ctx, getScheduledTransfer, id)
+	var i ScheduledTransfer
+	err := row.Scan(
+		&i.ID,
+		&i.Owner,
+		&i.FromAccountID,
+		&i.ToAccountID,
+		&i.Amount,
+		&i.Currency,
+		&i.Recurrence,
+		&i.Status,
+		&i.NextRunAt,
+		&i.NextAttemptAt,
+		&i.Attempts,
+		&i.EndAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const listScheduledTransferRuns = `-- name: ListScheduledTransferRuns :many
+SELECT id, scheduled_transfer_id, run_at, attempt, status, transfer_id, error, created_at FROM scheduled_transfer_runs
+WHERE scheduled_transfer_id = $1
+ORDER BY id DESC
+LIMIT $2
+OFFSET $3
+`
+
+type ListScheduledTransferRunsParams struct {
+	ScheduledTransferID int64 `json:"scheduled_transfer_id"`
+	Limit               int32 `json:"limit"`
+	Offset              int32 `json:"offset"`
+}
+
+func (q *Queries) ListScheduledTransferRuns(ctx context.Context, arg ListScheduledTransferRunsParams) ([]ScheduledTransferRun, error) {
+	rows, err := q.db.QueryContext(ctx, listScheduledTransferRuns, arg.ScheduledTransferID, arg.Limit, arg.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ScheduledTransferRun{}
+	for rows.Next() {
+		var i ScheduledTransferRun
+		if err := rows.Scan(
+			&i.ID,
+			&i.ScheduledTransferID,
+			&i.RunAt,
+			&i.Attempt,
+			&i.Status,
+			&i.TransferID,
+			&i.Error,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listScheduledTransfers = `-- name: ListScheduledTransfers :many
+SELECT id, owner, from_account_id, to_account_id, amount, currency, recurrence, status, next_run_at, next_attempt_at, attempts, end_at, created_at FROM scheduled_transfers
+WHERE owner = $1
+ORDER BY id
+LIMIT $2
+OFFSET $3
+`
+
+type ListScheduledTransfersParams struct {
+	Owner  string `json:"owner"`
+	Limit  int32  `json:"limit"`
+	Offset int32  `json:"offset"`
+}
+
+func (q *Queries) ListScheduledTransfers(ctx context.Context, arg ListScheduledTransfersParams) ([]ScheduledTransfer, error) {
+	rows, err := q.db.QueryContext(ctx, listScheduledTransfers, arg.Owner, arg.Limit, arg.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ScheduledTransfer{}
+	for rows.Next() {
+		var i ScheduledTransfer
+		if err := rows.Scan(
+			&i.ID,
+			&i.Owner,
+			&i.FromAccountID,
+			&i.ToAccountID,
+			&i.Amount,
+			&i.Currency,
+			&i.Recurrence,
+			&i.Status,
+			&i.NextRunAt,
+			&i.NextAttemptAt,
+			&i.Attempts,
+			&i.EndAt,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const updateScheduledTransfer = `-- name: UpdateScheduledTransfer :one
+UPDATE scheduled_transfers
+SET amount = $1,
+  recurrence = $2,
+  status = $3,
+  next_run_at = $4,
+  next_attempt_at = $4,
+  attempts = 0,
+  end_at = $5
+WHERE id = $6
+RETURNING id, owner, from_account_id, to_account_id, amount, currency, recurrence, status, next_run_at, next_attempt_at, attempts, end_at, created_at
+`
+
+type UpdateScheduledTransferParams struct {
+	Amount     int64        `json:"amount"`
+	Recurrence string       `json:"recurrence"`
+	Status     string       `json:"status"`
+	NextRunAt  time.Time    `json:"next_run_at"`
+	EndAt      sql.NullTime `json:"end_at"`
+	ID         int64        `json:"id"`
+}
+
+// the failed attempts are reset, since the next occurrence is executed with the new parameters
+func (q *Queries) UpdateScheduledTransfer(ctx context.Context, arg UpdateScheduledTransferParams) (ScheduledTransfer, error) {
+	row := q.db.QueryRowContext(ctx, updateScheduledTransfer,
+		arg.Amount,
+		arg.Recurrence,
+		arg.Status,
+		arg.NextRunAt,
+		arg.EndAt,
+		arg.ID,
+	)
+	var i ScheduledTransfer
+	err := row.Scan(
+		&i.ID,
+		&i.Owner,
+		&i.FromAccountID,
+		&i.ToAccountID,
+		&i.Amount,
+		&i.Currency,
+		&i.Recurrence,
+		&i.Status,
+		&i.NextRunAt,
+		&i.NextAttemptAt,
+		&i.Attempts,
+		&i.EndAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const updateScheduledTransferSchedule = `-- name: UpdateScheduledTransferSchedule :one
+UPDATE scheduled_transfers
+SET status = $1,
+  next_run_at = $2,
+  next_attempt_at = $3,
+  attempts = $4
+WHERE id = $5 AND status = 'active' AND next_attempt_at = $6
+RETURNING id, owner, from_account_id, to_account_id, amount, currency, recurrence, status, next_run_at, next_attempt_at, attempts, end_at, created_at
+`
+
+type UpdateScheduledTransferScheduleParams struct {
+	Status        string    `json:"status"`
+	NextRunAt     time.Time `json:"next_run_at"`
+	NextAttemptAt time.Time `json:"next_attempt_at"`
+	Attempts      int32     `json:"attempts"`
+	ID            int64     `json:"id"`
+	ClaimedUntil  time.Time `json:"claimed_until"`
+}
+
+// no row is updated if the transfer has been paused, updated or claimed again since it was claimed until claimed_until
+func (q *Queries) UpdateScheduledTransferSchedule(ctx context.Context, arg UpdateScheduledTransferScheduleParams) (ScheduledTransfer, error) {
+	row := q.db.QueryRowContext(ctx, updateScheduledTransferSchedule,
+		arg.Status,
+		arg.NextRunAt,
+		arg.NextAttemptAt,
+		arg.Attempts,
+		arg.ID,
+		arg.ClaimedUntil,
+	)
+	var i ScheduledTransfer
+	err := row.Scan(
+		&i.ID,
+		&i.Owner,
+		&i.FromAccountID,
+		&i.ToAccountID,
+		&i.Amount,
+		&i.Currency,
+		&i.Recurrence,
+		&i.Status,
+		&i.NextRunAt,
+		&i.NextAttemptAt,
+		&i.Attempts,
+		&i.EndAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
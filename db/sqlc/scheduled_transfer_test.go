@@ -0,0 +1,212 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"db.sqlc.dev/app/util"
+	"github.com/stretchr/testify/require"
+)
+
+func createRandomScheduledTransfer(t *testing.T, nextRunAt time.Time) ScheduledTransfer {
+	accountFrom := createRandomAccount(t)
+	accountTo := createRandomAccountWithCurrency(t, accountFrom.Currency)
+
+	arg := CreateScheduledTransferParams{
+		Owner:         accountFrom.Owner,
+		FromAccountID: accountFrom.ID,
+		ToAccountID:   accountTo.ID,
+		Amount:        util.RandomMoney() + 1,
+		Currency:      accountFrom.Currency,
+		Recurrence:    "@every 24h",
+		NextRunAt:     nextRunAt,
+		EndAt:         sql.NullTime{Time: nextRunAt.Add(30 * 24 * time.Hour), Valid: true},
+	}
+
+	scheduled, err := testQueries.CreateScheduledTransfer(context.Background(), arg)
+	require.NoError(t, err)
+	require.NotEmpty(t, scheduled)
+
+	require.Equal(t, arg.Owner, scheduled.Owner)
+	require.Equal(t, arg.FromAccountID, scheduled.FromAccountID)
+	require.Equal(t, arg.ToAccountID, scheduled.ToAccountID)
+	require.Equal(t, arg.Amount, scheduled.Amount)
+	require.Equal(t, arg.Currency, scheduled.Currency)
+	require.Equal(t, arg.Recurrence, scheduled.Recurrence)
+	require.Equal(t, "active", scheduled.Status)
+	require.WithinDuration(t, arg.NextRunAt, scheduled.NextRunAt, time.Second)
+	require.WithinDuration(t, arg.NextRunAt, scheduled.NextAttemptAt, time.Second)
+	require.Zero(t, scheduled.Attempts)
+	require.True(t, scheduled.EndAt.Valid)
+	require.NotZero(t, scheduled.ID)
+	require.NotZero(t, scheduled.CreatedAt)
+
+	return scheduled
+}
+
+func TestCreateScheduledTransfer(t *testing.T) {
+	createRandomScheduledTransfer(t, time.Now().Add(time.Hour))
+}
+
+func TestGetScheduledTransfer(t *testing.T) {
+	scheduled1 := createRandomScheduledTransfer(t, time.Now().Add(time.Hour))
+
+	scheduled2, err := testQueries.GetScheduledTransfer(context.Background(), scheduled1.ID)
+	require.NoError(t, err)
+	require.Equal(t, scheduled1.ID, scheduled2.ID)
+	require.Equal(t, scheduled1.Owner, scheduled2.Owner)
+	require.Equal(t, scheduled1.Amount, scheduled2.Amount)
+	require.WithinDuration(t, scheduled1.NextRunAt, scheduled2.NextRunAt, time.Second)
+}
+
+func TestListScheduledTransfers(t *testing.T) {
+	scheduled := createRandomScheduledTransfer(t, time.Now().Add(time.Hour))
+
+	list, err := testQueries.ListScheduledTransfers(context.Background(), ListScheduledTransfersParams{
+		Owner: scheduled.Owner,
+		Limit: 5,
+	})
+	require.NoError(t, err)
+	require.Len(t, list, 1)
+	require.Equal(t, scheduled.ID, list[0].ID)
+}
+
+func TestUpdateScheduledTransfer(t *testing.T) {
+	scheduled1 := createRandomScheduledTransfer(t, time.Now().Add(time.Hour))
+
+	arg := UpdateScheduledTransferParams{
+		ID:         scheduled1.ID,
+		Amount:     scheduled1.Amount + 1,
+		Recurrence: "0 9 1 * *",
+		Status:     "paused",
+		NextRunAt:  time.Now().Add(2 * time.Hour),
+	}
+	scheduled2, err := testQueries.UpdateScheduledTransfer(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, arg.Amount, scheduled2.Amount)
+	require.Equal(t, arg.Recurrence, scheduled2.Recurrence)
+	require.Equal(t, arg.Status, scheduled2.Status)
+	require.WithinDuration(t, arg.NextRunAt, scheduled2.NextRunAt, time.Second)
+	require.WithinDuration(t, arg.NextRunAt, scheduled2.NextAttemptAt, time.Second)
+	require.False(t, scheduled2.EndAt.Valid)
+}
+
+func TestDeleteScheduledTransfer(t *testing.T) {
+	scheduled1 := createRandomScheduledTransfer(t, time.Now().Add(time.Hour))
+
+	err := testQueries.DeleteScheduledTransfer(context.Background(), scheduled1.ID)
+	require.NoError(t, err)
+
+	_, err = testQueries.GetScheduledTransfer(context.Background(), scheduled1.ID)
+	require.ErrorIs(t, err, sql.ErrNoRows)
+}
+
+func TestClaimDueScheduledTransfers(t *testing.T) {
+	due := createRandomScheduledTransfer(t, time.Now().Add(-time.Minute))
+	notDue := createRandomScheduledTransfer(t, time.Now().Add(time.Hour))
+
+	leaseUntil := time.Now().Add(5 * time.Minute)
+	claimed, err := testQueries.ClaimDueScheduledTransfers(context.Background(), ClaimDueScheduledTransfersParams{
+		LeaseUntil: leaseUntil,
+		MaxCount:   1000,
+	})
+	require.NoError(t, err)
+
+	ids := make(map[int64]ScheduledTransfer)
+	for _, scheduled := range claimed {
+		ids[scheduled.ID] = scheduled
+	}
+	require.Contains(t, ids, due.ID)
+	require.NotContains(t, ids, notDue.ID)
+	require.WithinDuration(t, leaseUntil, ids[due.ID].NextAttemptAt, time.Second)
+	// the occurrence to execute doesn't change
+	require.WithinDuration(t, due.NextRunAt, ids[due.ID].NextRunAt, time.Second)
+
+	// a claimed transfer isn't due again until the end of the lease
+	claimed, err = testQueries.ClaimDueScheduledTransfers(context.Background(), ClaimDueScheduledTransfersParams{
+		LeaseUntil: leaseUntil,
+		MaxCount:   1000,
+	})
+	require.NoError(t, err)
+	for _, scheduled := range claimed {
+		require.NotEqual(t, due.ID, scheduled.ID)
+	}
+}
+
+func TestUpdateScheduledTransferSchedule(t *testing.T) {
+	scheduled1 := createRandomScheduledTransfer(t, time.Now().Add(-time.Minute))
+
+	arg := UpdateScheduledTransferScheduleParams{
+		ID:            scheduled1.ID,
+		Status:        "active",
+		NextRunAt:     scheduled1.NextRunAt,
+		NextAttemptAt: time.Now().Add(time.Minute),
+		Attempts:      1,
+		ClaimedUntil:  scheduled1.NextAttemptAt,
+	}
+	scheduled2, err := testQueries.UpdateScheduledTransferSchedule(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, arg.Attempts, scheduled2.Attempts)
+	require.WithinDuration(t, arg.NextAttemptAt, scheduled2.NextAttemptAt, time.Second)
+
+	// the transfer isn't claimed until arg.ClaimedUntil anymore
+	_, err = testQueries.UpdateScheduledTransferSchedule(context.Background(), arg)
+	require.ErrorIs(t, err, sql.ErrNoRows)
+}
+
+func TestUpdateScheduledTransferScheduleAfterPause(t *testing.T) {
+	scheduled1 := createRandomScheduledTransfer(t, time.Now().Add(-time.Minute))
+
+	_, err := testQueries.UpdateScheduledTransfer(context.Background(), UpdateScheduledTransferParams{
+		ID:         scheduled1.ID,
+		Amount:     scheduled1.Amount,
+		Recurrence: scheduled1.Recurrence,
+		Status:     "paused",
+		NextRunAt:  scheduled1.NextRunAt,
+		EndAt:      scheduled1.EndAt,
+	})
+	require.NoError(t, err)
+
+	// the run of the transfer doesn't overwrite the pause
+	_, err = testQueries.UpdateScheduledTransferSchedule(context.Background(), UpdateScheduledTransferScheduleParams{
+		ID:            scheduled1.ID,
+		Status:        "active",
+		NextRunAt:     scheduled1.NextRunAt.Add(24 * time.Hour),
+		NextAttemptAt: scheduled1.NextRunAt.Add(24 * time.Hour),
+		ClaimedUntil:  scheduled1.NextAttemptAt,
+	})
+	require.ErrorIs(t, err, sql.ErrNoRows)
+
+	scheduled2, err := testQueries.GetScheduledTransfer(context.Background(), scheduled1.ID)
+	require.NoError(t, err)
+	require.Equal(t, "paused", scheduled2.Status)
+}
+
+func TestScheduledTransferRuns(t *testing.T) {
+	scheduled := createRandomScheduledTransfer(t, time.Now())
+
+	arg := CreateScheduledTransferRunParams{
+		ScheduledTransferID: scheduled.ID,
+		RunAt:               scheduled.NextRunAt,
+		Attempt:             1,
+		Status:              "failed",
+		Error:               "insufficient funds",
+	}
+	run, err := testQueries.CreateScheduledTransferRun(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, arg.ScheduledTransferID, run.ScheduledTransferID)
+	require.Equal(t, arg.Attempt, run.Attempt)
+	require.Equal(t, arg.Status, run.Status)
+	require.Equal(t, arg.Error, run.Error)
+	require.False(t, run.TransferID.Valid)
+
+	runs, err := testQueries.ListScheduledTransferRuns(context.Background(), ListScheduledTransferRunsParams{
+		ScheduledTransferID: scheduled.ID,
+		Limit:               5,
+	})
+	require.NoError(t, err)
+	require.Len(t, runs, 1)
+	require.Equal(t, run.ID, runs[0].ID)
+}
@@ -3,9 +3,81 @@ package db
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"math/big"
+	"sort"
+	"time"
+
+	"db.sqlc.dev/app/fx"
+	"db.sqlc.dev/app/money"
+	"github.com/lib/pq"
+)
+
+// ErrIdempotencyKeyInUse is returned by TransferTx if the idempotency key has already been stored
+// by another transfer and hasn't expired yet
+var ErrIdempotencyKeyInUse = errors.New("idempotency key is already in use")
+
+// ErrOccurrenceExecuted is returned by TransferTx if the occurrence of the scheduled transfer
+// already has a succeeded run
+var ErrOccurrenceExecuted = errors.New("occurrence of the scheduled transfer has already been executed")
+
+// ErrInsufficientFunds is returned by TransferTx if the available balance of the source account,
+// including its overdraft limit, is lower than the amount
+var ErrInsufficientFunds = errors.New("insufficient funds")
+
+// ErrReversalExceedsTransfer is returned by ReverseTransferTx if the reversal amount is larger
+// than the part of the transfer that hasn't been reversed yet
+var ErrReversalExceedsTransfer = errors.New("reversal amount exceeds the amount left to reverse")
+
+// ErrTransferNotReversible is returned by ReverseTransferTx for a transfer that is itself a reversal
+var ErrTransferNotReversible = errors.New("transfer can't be reversed")
+
+// ErrTransferNotPending is returned by CaptureTransferTx, VoidTransferTx and ExpireTransferTx
+// if the transfer has already been captured, voided or expired, or has been made without hold
+var ErrTransferNotPending = errors.New("transfer is not pending")
+
+// ErrHoldExpired is returned by CaptureTransferTx if the hold of the transfer has expired
+var ErrHoldExpired = errors.New("hold of the transfer has expired")
+
+// Statuses of a transfer
+const (
+	// TransferPending: the money is held on the from account until the transfer is captured, voided or expired
+	TransferPending = "pending"
+	// TransferCaptured: the entries of the transfer are posted
+	TransferCaptured = "captured"
+	// TransferVoided: the hold has been released without moving the money
+	TransferVoided = "voided"
+	// TransferExpired: the hold has been released because the transfer wasn't captured in time
+	TransferExpired = "expired"
+)
+
+// ScheduledRunSucceeded: status of the run of a scheduled transfer whose transfer has been made
+const ScheduledRunSucceeded = "succeeded"
+
+// ErrAccountClosed is returned by the transfer transactions if an account of the transfer is closed
+var ErrAccountClosed = errors.New("account is closed")
+
+// ErrAccountFrozen is returned by the transfer transactions if the from account of the transfer can't be debited,
+// or its to account can't be credited, because of its status
+var ErrAccountFrozen = errors.New("account is frozen")
+
+// Statuses of an account
+const (
+	AccountActive = "active"
+	// AccountDebitFrozen: the account can receive money, but it can't send any
+	AccountDebitFrozen = "debit_frozen"
+	// AccountFrozen: the account can neither send nor receive money
+	AccountFrozen = "frozen"
+	// AccountClosed: the account rejects the transfers, it is kept for its history
+	AccountClosed = "closed"
 )
 
+// ErrReversalTooSmall is returned by ReverseTransferTx if the reversal amount converts to less than 1 minor unit
+// of the currency of the to account
+var ErrReversalTooSmall = errors.New("reversal amount is too small")
+
 // Store interface should have all functions of the Queries struct,
 // and one more function to execute the transfer money transaction
 type Store interface {
@@ -13,6 +85,25 @@ type Store interface {
 	Querier
 	// add func TransferTx to enable money transfer between accounts
 	TransferTx(ctx context.Context, arg TransferTxParams) (TransferTxResult, error)
+	// add func ReverseTransferTx to give back the money of a transfer
+	ReverseTransferTx(ctx context.Context, arg ReverseTransferTxParams) (ReverseTransferTxResult, error)
+	// add func BatchTransferTx to make several transfers all-or-nothing
+	BatchTransferTx(ctx context.Context, args []TransferTxParams) ([]TransferTxResult, error)
+	// add func CreateAccountTx to create an account within the account limit of its owner
+	CreateAccountTx(ctx context.Context, arg CreateAccountTxParams) (Account, error)
+	// add func CloseAccountTx to sweep the balance of an account and close it
+	CloseAccountTx(ctx context.Context, arg CloseAccountTxParams) (CloseAccountTxResult, error)
+	// add func UpdateAccountStatusTx to freeze or unfreeze an account
+	UpdateAccountStatusTx(ctx context.Context, arg UpdateAccountStatusTxParams) (Account, error)
+	// add func AccrueInterestTx to record the interest of an account for a day
+	AccrueInterestTx(ctx context.Context, arg AccrueInterestTxParams) (InterestAccrual, error)
+	// add func PostInterestTx to pay the accrued interest of an account
+	PostInterestTx(ctx context.Context, arg PostInterestTxParams) (PostInterestTxResult, error)
+	// two-phase transfers: hold the money, then capture the transfer or release the hold
+	AuthorizeTransferTx(ctx context.Context, arg AuthorizeTransferTxParams) (TransferHoldTxResult, error)
+	CaptureTransferTx(ctx context.Context, transferID int64) (TransferTxResult, error)
+	VoidTransferTx(ctx context.Context, transferID int64) (TransferHoldTxResult, error)
+	ExpireTransferTx(ctx context.Context, arg ExpireTransferTxParams) (TransferHoldTxResult, error)
 }
 
 // SQLStore is a concrete type that have methods required by Store interface
@@ -64,7 +155,39 @@ func (store *SQLStore) execTx(ctx context.Context, fn func(*Queries) error) erro
 type TransferTxParams struct {
 	FromAccountID int64 `json:"from_account_id"`
 	ToAccountID   int64 `json:"to_account_id"`
-	Amount        int64 `json:"amount"`
+	// Amount: money taken from the from account, in its currency
+	Amount money.Amount `json:"amount"`
+	// ToAmount: money given to the to account in its currency,
+	// zero value for a transfer between accounts of the same currency
+	ToAmount money.Amount `json:"to_amount"`
+	// ExchangeRate: decimal rate applied to convert Amount into ToAmount, empty for a transfer in a single currency
+	ExchangeRate string `json:"exchange_rate"`
+	// Fee: optional fee taken from the from account in its currency on top of Amount,
+	// and credited to the house account FeeAccountID
+	Fee          money.Amount `json:"fee"`
+	FeeAccountID int64        `json:"fee_account_id"`
+	// IdempotencyKey: optional key of the client request, stored with the result in the same transaction
+	IdempotencyKey *TransferIdempotencyKey `json:"-"`
+	// ScheduledRun: optional occurrence of a scheduled transfer made by the transfer,
+	// recorded as a succeeded run in the same transaction
+	ScheduledRun *ScheduledTransferOccurrence `json:"-"`
+}
+
+// ScheduledTransferOccurrence identifies the attempt of an occurrence of a scheduled transfer,
+// so that a repeated run doesn't move money twice
+type ScheduledTransferOccurrence struct {
+	ScheduledTransferID int64
+	RunAt               time.Time
+	Attempt             int32
+}
+
+// TransferIdempotencyKey identifies a transfer request of a user, so that retries don't move money twice
+type TransferIdempotencyKey struct {
+	Username string
+	Key      string
+	// RequestHash: hash of the request body, a key can't be reused with a different request
+	RequestHash string
+	ExpiresAt   time.Time
 }
 
 // The TransferTxResult struct contains the result of the transfer transaction
@@ -74,6 +197,10 @@ type TransferTxResult struct {
 	ToAccount   Account  `json:"to_account"`
 	FromEntry   Entry    `json:"from_entry"`
 	ToEntry     Entry    `json:"to_entry"`
+	// FeeEntry: entry of the fee taken from the from account, zero value if the transfer has no fee
+	FeeEntry Entry `json:"fee_entry"`
+	// AvailableBalance: money that can still be taken from the from account, including its overdraft limit
+	AvailableBalance int64 `json:"available_balance"`
 }
 
 // declare variable txKey of type empty(struct{})
@@ -84,6 +211,7 @@ type TransferTxResult struct {
 // It creates a transfer record, add account entries, and update accounts' balance within a single db transaction
 func (store *SQLStore) TransferTx(ctx context.Context, arg TransferTxParams) (TransferTxResult, error) {
 	var result TransferTxResult
+	arg = arg.withDefaults()
 
 	err := store.execTx(ctx, func(q *Queries) error {
 		// implement the callback function: use queries object q to call individual CRUD function
@@ -92,71 +220,500 @@ func (store *SQLStore) TransferTx(ctx context.Context, arg TransferTxParams) (Tr
 		// get transaction name from context
 		// txName := ctx.Value(txKey)
 
+		// step 0. lock the accounts and check the available balance of the source account
+		debit, err := lockTransferAccounts(ctx, q, arg)
+		if err != nil {
+			return err
+		}
+
 		// fmt.Println(txName, "create transfer")
 		// step 1. create transfer and return err if err != nil
+		result.Transfer, err = insertTransfer(ctx, q, arg, TransferCaptured)
+		if err != nil {
+			return err
+		}
+
+		// step 2. and 3. create the entries and update the balances of the accounts
+		if err := postTransfer(ctx, q, arg, debit, &result); err != nil {
+			return err
+		}
+
+		// step 4. store the result with the idempotency key, the transfer is rolled back if the key is in use
+		if arg.IdempotencyKey != nil {
+			if err := saveIdempotencyKey(ctx, q, arg.IdempotencyKey, result); err != nil {
+				return err
+			}
+		}
+
+		// step 5. record the run of the scheduled transfer, the transfer is rolled back if the occurrence has been executed
+		if arg.ScheduledRun != nil {
+			return saveScheduledRun(ctx, q, arg.ScheduledRun, result.Transfer.ID)
+		}
+		return nil
+	})
+
+	// return the result and the error of the execTx() call
+	return result, err
+}
+
+// withDefaults returns the parameters with the default values of the optional fields
+func (arg TransferTxParams) withDefaults() TransferTxParams {
+	// a transfer between accounts of the same currency gives the same amount to the to account
+	if arg.ToAmount == (money.Amount{}) {
+		arg.ToAmount = arg.Amount
+	}
+	if arg.ExchangeRate == "" {
+		arg.ExchangeRate = "1"
+	}
+	if arg.Fee == (money.Amount{}) {
+		arg.Fee = money.New(0, arg.Amount.Currency)
+	}
+	return arg
+}
+
+// lockTransferAccounts locks the accounts of a transfer, then checks the available balance of the source account:
+// no concurrent transfer can change the balance before it is updated. It returns the money taken from the source account
+func lockTransferAccounts(ctx context.Context, q *Queries, arg TransferTxParams) (money.Amount, error) {
+	// Avoid deadlock by making sure the accounts are locked in the order of their IDs
+	accountIDs := []int64{arg.FromAccountID, arg.ToAccountID}
+	if !arg.Fee.IsZero() {
+		accountIDs = append(accountIDs, arg.FeeAccountID)
+	}
+	accounts, err := lockAccounts(ctx, q, accountIDs...)
+	if err != nil {
+		return money.Amount{}, err
+	}
+	fromAccount := accounts[arg.FromAccountID]
+	toAccount := accounts[arg.ToAccountID]
+	if err := checkAccountsStatus(fromAccount, toAccount); err != nil {
+		return money.Amount{}, err
+	}
+
+	// the from account pays the amount and the fee
+	debit, err := arg.Amount.Add(arg.Fee)
+	if err != nil {
+		return money.Amount{}, err
+	}
+
+	// the balance can go negative down to -overdraft_limit, and the held money can't be spent.
+	// Comparing the amounts fails if the amount isn't in the currency of the account
+	available, err := fromAccount.AvailableBalance()
+	if err != nil {
+		return money.Amount{}, err
+	}
+	cmp, err := available.Cmp(debit)
+	if err != nil {
+		return money.Amount{}, fmt.Errorf("account [%d]: %w", fromAccount.ID, err)
+	}
+	if cmp < 0 {
+		return money.Amount{}, fmt.Errorf("%w: account [%d] available balance %s is lower than amount %s with fee %s",
+			ErrInsufficientFunds, fromAccount.ID, available, arg.Amount, arg.Fee)
+	}
+
+	// the to amount must be in the currency of the to account, and its new balance must not overflow
+	if _, err := toAccount.BalanceAmount().Add(arg.ToAmount); err != nil {
+		return money.Amount{}, fmt.Errorf("account [%d]: %w", toAccount.ID, err)
+	}
+	if !arg.Fee.IsZero() {
+		// the fee is credited to the fee account, which must accept money like the to account
+		feeAccount := accounts[arg.FeeAccountID]
+		if err := checkCreditStatus(feeAccount); err != nil {
+			return money.Amount{}, fmt.Errorf("fee %w", err)
+		}
+		if _, err := feeAccount.BalanceAmount().Add(arg.Fee); err != nil {
+			return money.Amount{}, fmt.Errorf("fee account [%d]: %w", feeAccount.ID, err)
+		}
+	}
+	return debit, nil
+}
+
+// insertTransfer creates the transfer record of the parameters with the input status
+func insertTransfer(ctx context.Context, q *Queries, arg TransferTxParams, status string) (Transfer, error) {
+	return q.CreateTransfer(ctx, CreateTransferParams{
+		FromAccountID: arg.FromAccountID,
+		ToAccountID:   arg.ToAccountID,
+		Amount:        arg.Amount.Minor,
+		ToAmount:      arg.ToAmount.Minor,
+		ExchangeRate:  arg.ExchangeRate,
+		Fee:           arg.Fee.Minor,
+		Status:        status,
+	})
+}
+
+// postTransfer creates the entries of a transfer and updates the balances of its accounts,
+// debit is the money taken from the from account: the amount and the fee
+func postTransfer(ctx context.Context, q *Queries, arg TransferTxParams, debit money.Amount, result *TransferTxResult) error {
+	var err error
+
+	// step 2. create the FromEntry and ToEntry and return err if err != nil
+	// fmt.Println(txName, "create fromEntry")
+	result.FromEntry, err = q.CreateEntry(ctx, CreateEntryParams{
+		AccountID: arg.FromAccountID,
+		Amount:    -arg.Amount.Minor,
+	})
+	if err != nil {
+		return err
+	}
+
+	// fmt.Println(txName, "create toEntry")
+	result.ToEntry, err = q.CreateEntry(ctx, CreateEntryParams{
+		AccountID: arg.ToAccountID,
+		Amount:    arg.ToAmount.Minor,
+	})
+	if err != nil {
+		return err
+	}
+
+	// the fee is a separate entry of the from account, and a credit of the house fee account
+	amounts := map[int64]int64{
+		arg.FromAccountID: -debit.Minor,
+	}
+	amounts[arg.ToAccountID] += arg.ToAmount.Minor
+	if !arg.Fee.IsZero() {
+		result.FeeEntry, err = q.CreateEntry(ctx, CreateEntryParams{
+			AccountID: arg.FromAccountID,
+			Amount:    -arg.Fee.Minor,
+		})
+		if err != nil {
+			return err
+		}
+
+		_, err = q.CreateEntry(ctx, CreateEntryParams{
+			AccountID: arg.FeeAccountID,
+			Amount:    arg.Fee.Minor,
+		})
+		if err != nil {
+			return err
+		}
+
+		amounts[arg.FeeAccountID] += arg.Fee.Minor
+	}
+
+	// step 3. update the balances of the accounts and return err if err != nil
+	// It involves locking and preventing potential deadlock
+	// Avoid deadlock by making sure the account with smaller ID is updated first
+	accounts, err := addMoney(ctx, q, amounts)
+
+	// the balance check constraint of the accounts table is a backstop of the check of step 0.
+	if isBalanceCheckViolation(err) {
+		return fmt.Errorf("%w: %v", ErrInsufficientFunds, err)
+	}
+	if err != nil {
+		return err
+	}
+	result.FromAccount = accounts[arg.FromAccountID]
+	result.ToAccount = accounts[arg.ToAccountID]
+
+	availableBalance, err := result.FromAccount.AvailableBalance()
+	if err != nil {
+		return err
+	}
+	result.AvailableBalance = availableBalance.Minor
+	return nil
+}
+
+// ReverseTransferTxParams contains the input parameters of the reversal of a transfer
+type ReverseTransferTxParams struct {
+	TransferID int64 `json:"transfer_id"`
+	// Amount: money given back to the from account of the transfer in its currency,
+	// 0 to reverse the whole amount that hasn't been reversed yet
+	Amount int64 `json:"amount"`
+}
+
+// ReverseTransferTxResult is the result of the reversal transaction
+type ReverseTransferTxResult struct {
+	// the reversal is a transfer from the to account of the original transfer to its from account
+	TransferTxResult
+	// OriginalTransfer: reversed transfer with its updated reversed amount
+	OriginalTransfer Transfer `json:"original_transfer"`
+}
+
+// ReverseTransferTx gives back all or part of the amount of a transfer to its from account:
+// it creates a reversal transfer linked to the original transfer with the compensating entries,
+// and updates the balances of the accounts within a single db transaction.
+// The to account gives back the same share of the to amount of a transfer between currencies,
+// so that the original exchange rate applies. The fee of the transfer is not refunded
+func (store *SQLStore) ReverseTransferTx(ctx context.Context, arg ReverseTransferTxParams) (ReverseTransferTxResult, error) {
+	var result ReverseTransferTxResult
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		// lock the original transfer first, so that concurrent reversals can't reverse more than its amount
+		original, err := q.GetTransferForUpdate(ctx, arg.TransferID)
+		if err != nil {
+			return err
+		}
+		if original.ReversalOf.Valid {
+			return fmt.Errorf("%w: transfer [%d] is a reversal", ErrTransferNotReversible, original.ID)
+		}
+		// only the posted money can be given back, a pending transfer is voided instead
+		if original.Status != TransferCaptured {
+			return fmt.Errorf("%w: transfer [%d] is %s", ErrTransferNotReversible, original.ID, original.Status)
+		}
+
+		remaining := original.Amount - original.ReversedAmount
+		amount := arg.Amount
+		if amount == 0 {
+			amount = remaining
+		}
+		if amount <= 0 || amount > remaining {
+			return fmt.Errorf("%w: transfer [%d] can be reversed by at most %d", ErrReversalExceedsTransfer, original.ID, remaining)
+		}
+
+		// the reversal goes from the to account back to the from account
+		accounts, err := lockAccounts(ctx, q, original.FromAccountID, original.ToAccountID)
+		if err != nil {
+			return err
+		}
+		fromAccount := accounts[original.ToAccountID]
+		toAccount := accounts[original.FromAccountID]
+		if err := checkAccountsStatus(fromAccount, toAccount); err != nil {
+			return err
+		}
+
+		toAmount := money.New(amount, toAccount.Currency)
+		fromAmount := money.New(reversedShare(original, original.ReversedAmount+amount)-reversedShare(original, original.ReversedAmount), fromAccount.Currency)
+		if !fromAmount.IsPositive() {
+			return fmt.Errorf("%w: %s converts to %s", ErrReversalTooSmall, toAmount, fromAmount)
+		}
+
+		exchangeRate, err := reversalExchangeRate(original, fromAccount.Currency, toAccount.Currency)
+		if err != nil {
+			return err
+		}
+
+		available, err := fromAccount.AvailableBalance()
+		if err != nil {
+			return err
+		}
+		if available.Minor < fromAmount.Minor {
+			return fmt.Errorf("%w: account [%d] available balance %s is lower than reversal amount %s",
+				ErrInsufficientFunds, fromAccount.ID, available, fromAmount)
+		}
+		if _, err := toAccount.BalanceAmount().Add(toAmount); err != nil {
+			return fmt.Errorf("account [%d]: %w", toAccount.ID, err)
+		}
+
+		result.OriginalTransfer, err = q.AddTransferReversedAmount(ctx, AddTransferReversedAmountParams{
+			ID:     original.ID,
+			Amount: amount,
+		})
+		if err != nil {
+			return err
+		}
+
 		result.Transfer, err = q.CreateTransfer(ctx, CreateTransferParams{
-			FromAccountID: arg.FromAccountID,
-			ToAccountID:   arg.ToAccountID,
-			Amount:        arg.Amount,
+			FromAccountID: fromAccount.ID,
+			ToAccountID:   toAccount.ID,
+			Amount:        fromAmount.Minor,
+			ToAmount:      toAmount.Minor,
+			ExchangeRate:  exchangeRate,
+			ReversalOf:    sql.NullInt64{Int64: original.ID, Valid: true},
+			Status:        TransferCaptured,
 		})
 		if err != nil {
 			return err
 		}
 
-		// step 2. create the FromEntry and ToEntry and return err if err != nil
-		// fmt.Println(txName, "create fromEntry")
 		result.FromEntry, err = q.CreateEntry(ctx, CreateEntryParams{
-			AccountID: arg.FromAccountID,
-			Amount:    -arg.Amount,
+			AccountID: fromAccount.ID,
+			Amount:    -fromAmount.Minor,
 		})
 		if err != nil {
 			return err
 		}
 
-		// fmt.Println(txName, "create toEntry")
 		result.ToEntry, err = q.CreateEntry(ctx, CreateEntryParams{
-			AccountID: arg.ToAccountID,
-			Amount:    arg.Amount,
+			AccountID: toAccount.ID,
+			Amount:    toAmount.Minor,
 		})
 		if err != nil {
 			return err
 		}
 
-		// step 3. update the FromAccount and ToAccount and return err if err != nil
-		// It involves locking and preventing potential deadlock
-		// Avoid deadlock by making sure the account with smaller ID is updated first
-		if arg.FromAccountID < arg.ToAccountID {
-			result.FromAccount, result.ToAccount, err = addMoney(ctx, q, arg.FromAccountID, -arg.Amount, arg.ToAccountID, arg.Amount)
-		} else {
-			result.ToAccount, result.FromAccount, err = addMoney(ctx, q, arg.ToAccountID, arg.Amount, arg.FromAccountID, -arg.Amount)
+		accounts, err = addMoney(ctx, q, map[int64]int64{
+			fromAccount.ID: -fromAmount.Minor,
+			toAccount.ID:   toAmount.Minor,
+		})
+		if isBalanceCheckViolation(err) {
+			return fmt.Errorf("%w: %v", ErrInsufficientFunds, err)
+		}
+		if err != nil {
+			return err
 		}
+		result.FromAccount = accounts[fromAccount.ID]
+		result.ToAccount = accounts[toAccount.ID]
 
-		return err
+		availableBalance, err := result.FromAccount.AvailableBalance()
+		if err != nil {
+			return err
+		}
+		result.AvailableBalance = availableBalance.Minor
+		return nil
 	})
 
-	// return the result and the error of the execTx() call
 	return result, err
 }
 
-func addMoney(
-	ctx context.Context,
-	q *Queries,
-	accountID1 int64,
-	amount1 int64,
-	accountID2 int64,
-	amount2 int64,
-) (account1 Account, account2 Account, err error) {
-	account1, err = q.AddAccountBalance(ctx, AddAccountBalanceParams{
-		ID:     accountID1,
-		Amount: amount1,
-	})
+// reversedShare returns the part of the to amount of a transfer matching the reversed part of its amount,
+// rounded down so that reversing the whole amount gives back exactly the to amount
+func reversedShare(transfer Transfer, reversedAmount int64) int64 {
+	share := new(big.Int).Mul(big.NewInt(transfer.ToAmount), big.NewInt(reversedAmount))
+	return share.Quo(share, big.NewInt(transfer.Amount)).Int64()
+}
+
+// reversalExchangeRate returns the exchange rate of the reversal of a transfer: the inverse of its rate
+func reversalExchangeRate(transfer Transfer, from string, to string) (string, error) {
+	if from == to {
+		return "1", nil
+	}
+
+	rate, err := fx.ParseRate(to, from, transfer.ExchangeRate)
 	if err != nil {
-		return
+		return "", err
+	}
+	inverse, err := rate.Inverse()
+	if err != nil {
+		return "", err
+	}
+	return inverse.String(), nil
+}
+
+// addMoney adds the amounts to the balances of the accounts (account ID -> amount),
+// in the order of the account IDs
+func addMoney(ctx context.Context, q *Queries, amounts map[int64]int64) (map[int64]Account, error) {
+	ids := make([]int64, 0, len(amounts))
+	for id := range amounts {
+		ids = append(ids, id)
+	}
+
+	accounts := make(map[int64]Account, len(amounts))
+	for _, id := range sortedAccountIDs(ids) {
+		account, err := q.AddAccountBalance(ctx, AddAccountBalanceParams{
+			ID:     id,
+			Amount: amounts[id],
+		})
+		if err != nil {
+			return nil, err
+		}
+		accounts[id] = account
 	}
+	return accounts, nil
+}
+
+// lockAccounts locks the rows of the accounts until the end of the transaction, in the order of their IDs
+func lockAccounts(ctx context.Context, q *Queries, accountIDs ...int64) (map[int64]Account, error) {
+	accounts := make(map[int64]Account, len(accountIDs))
+	for _, id := range sortedAccountIDs(accountIDs) {
+		account, err := q.GetAccountForUpdate(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		accounts[id] = account
+	}
+	return accounts, nil
+}
+
+// sortedAccountIDs returns the distinct account IDs in increasing order
+func sortedAccountIDs(accountIDs []int64) []int64 {
+	ids := append([]int64(nil), accountIDs...)
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
 
-	account2, err = q.AddAccountBalance(ctx, AddAccountBalanceParams{
-		ID:     accountID2,
-		Amount: amount2,
+	distinct := make([]int64, 0, len(ids))
+	for _, id := range ids {
+		if len(distinct) == 0 || id != distinct[len(distinct)-1] {
+			distinct = append(distinct, id)
+		}
+	}
+	return distinct
+}
+
+// saveIdempotencyKey stores the serialized result of the transfer with its idempotency key
+func saveIdempotencyKey(ctx context.Context, q *Queries, key *TransferIdempotencyKey, result TransferTxResult) error {
+	body, err := json.Marshal(result)
+	if err != nil {
+		return err
+	}
+
+	_, err = q.CreateIdempotencyKey(ctx, CreateIdempotencyKeyParams{
+		Username:       key.Username,
+		IdempotencyKey: key.Key,
+		RequestHash:    key.RequestHash,
+		ResponseBody:   body,
+		ExpiresAt:      key.ExpiresAt,
 	})
-	return
+	// no row is returned if the key is stored and hasn't expired, e.g. by a concurrent retry of the request
+	if err == sql.ErrNoRows {
+		return ErrIdempotencyKeyInUse
+	}
+	return err
+}
+
+// saveScheduledRun creates the succeeded run of the occurrence of a scheduled transfer made by the transfer
+func saveScheduledRun(ctx context.Context, q *Queries, occurrence *ScheduledTransferOccurrence, transferID int64) error {
+	_, err := q.CreateScheduledTransferRun(ctx, CreateScheduledTransferRunParams{
+		ScheduledTransferID: occurrence.ScheduledTransferID,
+		RunAt:               occurrence.RunAt,
+		Attempt:             occurrence.Attempt,
+		Status:              ScheduledRunSucceeded,
+		TransferID:          sql.NullInt64{Int64: transferID, Valid: true},
+	})
+	// no row is returned if the occurrence already has a succeeded run, e.g. made by a run repeated after a crash
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("%w: scheduled transfer [%d] at %s", ErrOccurrenceExecuted, occurrence.ScheduledTransferID, occurrence.RunAt)
+	}
+	return err
+}
+
+// BalanceAmount returns the balance of the account in its currency
+func (account Account) BalanceAmount() money.Amount {
+	return money.New(account.Balance, account.Currency)
+}
+
+// AvailableBalance returns the money that can be taken from the account, including its overdraft limit
+// and without the money held by its pending transfers
+func (account Account) AvailableBalance() (money.Amount, error) {
+	available, err := account.BalanceAmount().Add(money.New(account.OverdraftLimit, account.Currency))
+	if err != nil {
+		return money.Amount{}, err
+	}
+	return available.Sub(money.New(account.HeldBalance, account.Currency))
+}
+
+// checkAccountsStatus checks that the status of the debited account allows it to send money,
+// and that the status of the credited account allows it to receive money
+func checkAccountsStatus(debited Account, credited Account) error {
+	if err := checkDebitStatus(debited); err != nil {
+		return err
+	}
+	return checkCreditStatus(credited)
+}
+
+// checkDebitStatus returns ErrAccountClosed or ErrAccountFrozen if money can't be taken from the account
+func checkDebitStatus(account Account) error {
+	switch account.Status {
+	case AccountClosed:
+		return fmt.Errorf("%w: account [%d]", ErrAccountClosed, account.ID)
+	case AccountDebitFrozen, AccountFrozen:
+		return fmt.Errorf("%w: account [%d] is %s", ErrAccountFrozen, account.ID, account.Status)
+	}
+	return nil
+}
+
+// checkCreditStatus returns ErrAccountClosed or ErrAccountFrozen if money can't be added to the account
+func checkCreditStatus(account Account) error {
+	switch account.Status {
+	case AccountClosed:
+		return fmt.Errorf("%w: account [%d]", ErrAccountClosed, account.ID)
+	case AccountFrozen:
+		return fmt.Errorf("%w: account [%d] is %s", ErrAccountFrozen, account.ID, account.Status)
+	}
+	return nil
+}
+
+// isBalanceCheckViolation reports whether err is a violation of the balance check constraint of the accounts table
+func isBalanceCheckViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) &&
+		pqErr.Code.Name() == "check_violation" &&
+		pqErr.Constraint == "accounts_balance_check"
 }
@@ -2,22 +2,40 @@ package db
 
 import (
 	"context"
+	"database/sql"
+	"encoding/json"
 	"fmt"
 	"testing"
+	"time"
 
+	"db.sqlc.dev/app/money"
+	"db.sqlc.dev/app/util"
+	"github.com/lib/pq"
 	"github.com/stretchr/testify/require"
 )
 
+// createFundedAccount creates a random USD account with the input balance
+func createFundedAccount(t *testing.T, balance int64) Account {
+	account := createRandomAccountWithCurrency(t, util.USD)
+
+	account, err := testQueries.UpdateAccount(context.Background(), UpdateAccountParams{
+		ID:      account.ID,
+		Balance: balance,
+	})
+	require.NoError(t, err)
+	return account
+}
+
 func TestTransferTx(t *testing.T) {
 	store := NewStore(testDB)
 
-	accountFrom := createRandomAccount(t)
-	accountTo := createRandomAccount(t)
-	fmt.Println(">> before:", accountFrom.Balance, accountTo.Balance)
-
 	n := 5
 	amount := int64(10)
 
+	accountFrom := createFundedAccount(t, util.RandomInt(int64(n)*amount, 1000))
+	accountTo := createRandomAccountWithCurrency(t, accountFrom.Currency)
+	fmt.Println(">> before:", accountFrom.Balance, accountTo.Balance)
+
 	// Channel is designed to connect concurrent Go routines,
 	// and allow them to safely share data with each other without explicit locking.
 	errs := make(chan error)
@@ -33,7 +51,7 @@ func TestTransferTx(t *testing.T) {
 			result, err := store.TransferTx(ctx, TransferTxParams{
 				FromAccountID: accountFrom.ID,
 				ToAccountID:   accountTo.ID,
-				Amount:        amount,
+				Amount:        money.New(amount, accountFrom.Currency),
 			})
 
 			// Inside the go routine, send err to the errs channel using this arrow operator <-
@@ -131,11 +149,12 @@ func TestTransferTx(t *testing.T) {
 func TestTransferTxDeadlock(t *testing.T) {
 	store := NewStore(testDB)
 
-	accountFrom := createRandomAccount(t)
-	accountTo := createRandomAccount(t)
-
 	n := 10
 	amount := int64(10)
+
+	// both accounts must be able to pay all the transfers, whatever their order
+	accountFrom := createFundedAccount(t, util.RandomInt(int64(n)*amount, 1000))
+	accountTo := createFundedAccount(t, util.RandomInt(int64(n)*amount, 1000))
 	errs := make(chan error)
 
 	// run n concurrent transfer transactions
@@ -152,7 +171,7 @@ func TestTransferTxDeadlock(t *testing.T) {
 			_, err := store.TransferTx(context.Background(), TransferTxParams{
 				FromAccountID: fromAccountID,
 				ToAccountID:   toAccountID,
-				Amount:        amount,
+				Amount:        money.New(amount, accountFrom.Currency),
 			})
 
 			// Inside the go routine, send err to the errs channel using this arrow operator <-
@@ -179,3 +198,948 @@ func TestTransferTxDeadlock(t *testing.T) {
 	require.Equal(t, accountFrom.Balance, updatedAccount1.Balance)
 	require.Equal(t, accountTo.Balance, updatedAccount2.Balance)
 }
+
+func TestTransferTxInsufficientFunds(t *testing.T) {
+	store := NewStore(testDB)
+
+	accountFrom := createFundedAccount(t, 10)
+	accountTo := createRandomAccountWithCurrency(t, accountFrom.Currency)
+
+	_, err := store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: accountFrom.ID,
+		ToAccountID:   accountTo.ID,
+		Amount:        money.New(11, accountFrom.Currency),
+	})
+	require.ErrorIs(t, err, ErrInsufficientFunds)
+
+	// nothing has been written
+	updatedAccountFrom, err := store.GetAccount(context.Background(), accountFrom.ID)
+	require.NoError(t, err)
+	require.Equal(t, accountFrom.Balance, updatedAccountFrom.Balance)
+
+	updatedAccountTo, err := store.GetAccount(context.Background(), accountTo.ID)
+	require.NoError(t, err)
+	require.Equal(t, accountTo.Balance, updatedAccountTo.Balance)
+
+	transfers, err := store.ListTransfers(context.Background(), ListTransfersParams{
+		FromAccountID: accountFrom.ID,
+		ToAccountID:   accountFrom.ID,
+		Limit:         5,
+	})
+	require.NoError(t, err)
+	require.Empty(t, transfers)
+
+	// the whole balance can be transferred
+	result, err := store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: accountFrom.ID,
+		ToAccountID:   accountTo.ID,
+		Amount:        money.New(10, accountFrom.Currency),
+	})
+	require.NoError(t, err)
+	require.Zero(t, result.FromAccount.Balance)
+}
+
+// concurrent transfers can't overdraw the account together
+func TestTransferTxConcurrentInsufficientFunds(t *testing.T) {
+	store := NewStore(testDB)
+
+	n := 5
+	amount := int64(10)
+
+	accountFrom := createFundedAccount(t, 3*amount)
+	accountTo := createRandomAccountWithCurrency(t, accountFrom.Currency)
+
+	errs := make(chan error)
+	for i := 0; i < n; i++ {
+		go func() {
+			_, err := store.TransferTx(context.Background(), TransferTxParams{
+				FromAccountID: accountFrom.ID,
+				ToAccountID:   accountTo.ID,
+				Amount:        money.New(amount, accountFrom.Currency),
+			})
+			errs <- err
+		}()
+	}
+
+	failed := 0
+	for i := 0; i < n; i++ {
+		err := <-errs
+		if err != nil {
+			require.ErrorIs(t, err, ErrInsufficientFunds)
+			failed++
+		}
+	}
+	require.Equal(t, n-3, failed)
+
+	updatedAccountFrom, err := store.GetAccount(context.Background(), accountFrom.ID)
+	require.NoError(t, err)
+	require.Zero(t, updatedAccountFrom.Balance)
+}
+
+func TestTransferTxOverdraft(t *testing.T) {
+	store := NewStore(testDB)
+
+	accountFrom := createFundedAccount(t, 10)
+	accountFrom, err := store.UpdateAccountOverdraftLimit(context.Background(), UpdateAccountOverdraftLimitParams{
+		ID:             accountFrom.ID,
+		OverdraftLimit: 50,
+	})
+	require.NoError(t, err)
+	accountTo := createRandomAccountWithCurrency(t, accountFrom.Currency)
+
+	// the balance can go negative down to -overdraft_limit
+	result, err := store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: accountFrom.ID,
+		ToAccountID:   accountTo.ID,
+		Amount:        money.New(40, accountFrom.Currency),
+	})
+	require.NoError(t, err)
+	require.Equal(t, int64(-30), result.FromAccount.Balance)
+	require.Equal(t, int64(20), result.AvailableBalance)
+
+	_, err = store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: accountFrom.ID,
+		ToAccountID:   accountTo.ID,
+		Amount:        money.New(21, accountFrom.Currency),
+	})
+	require.ErrorIs(t, err, ErrInsufficientFunds)
+
+	result, err = store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: accountFrom.ID,
+		ToAccountID:   accountTo.ID,
+		Amount:        money.New(20, accountFrom.Currency),
+	})
+	require.NoError(t, err)
+	require.Equal(t, int64(-50), result.FromAccount.Balance)
+	require.Zero(t, result.AvailableBalance)
+}
+
+func TestTransferTxIdempotencyKey(t *testing.T) {
+	store := NewStore(testDB)
+
+	accountFrom := createFundedAccount(t, 100)
+	accountTo := createRandomAccountWithCurrency(t, accountFrom.Currency)
+
+	arg := TransferTxParams{
+		FromAccountID: accountFrom.ID,
+		ToAccountID:   accountTo.ID,
+		Amount:        money.New(10, accountFrom.Currency),
+		IdempotencyKey: &TransferIdempotencyKey{
+			Username:    accountFrom.Owner,
+			Key:         util.RandomString(16),
+			RequestHash: util.RandomString(64),
+			ExpiresAt:   time.Now().Add(time.Minute),
+		},
+	}
+
+	result, err := store.TransferTx(context.Background(), arg)
+	require.NoError(t, err)
+
+	// the result is stored with the key
+	key, err := store.GetIdempotencyKey(context.Background(), GetIdempotencyKeyParams{
+		Username:       arg.IdempotencyKey.Username,
+		IdempotencyKey: arg.IdempotencyKey.Key,
+	})
+	require.NoError(t, err)
+	require.Equal(t, arg.IdempotencyKey.RequestHash, key.RequestHash)
+
+	var stored TransferTxResult
+	require.NoError(t, json.Unmarshal(key.ResponseBody, &stored))
+	require.Equal(t, result.Transfer.ID, stored.Transfer.ID)
+
+	// a second transfer with the same key is rolled back
+	_, err = store.TransferTx(context.Background(), arg)
+	require.ErrorIs(t, err, ErrIdempotencyKeyInUse)
+
+	updatedAccountFrom, err := store.GetAccount(context.Background(), accountFrom.ID)
+	require.NoError(t, err)
+	require.Equal(t, accountFrom.Balance-arg.Amount.Minor, updatedAccountFrom.Balance)
+}
+
+func TestTransferTxCrossCurrency(t *testing.T) {
+	store := NewStore(testDB)
+
+	accountFrom := createFundedAccount(t, 100)
+	accountTo := createRandomAccountWithCurrency(t, util.EUR)
+
+	// each entry is in the currency of its account
+	result, err := store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: accountFrom.ID,
+		ToAccountID:   accountTo.ID,
+		Amount:        money.New(50, accountFrom.Currency),
+		ToAmount:      money.New(46, accountTo.Currency),
+		ExchangeRate:  "0.92",
+	})
+	require.NoError(t, err)
+
+	require.Equal(t, int64(50), result.Transfer.Amount)
+	require.Equal(t, int64(46), result.Transfer.ToAmount)
+	require.Equal(t, "0.92", result.Transfer.ExchangeRate)
+	require.Equal(t, int64(-50), result.FromEntry.Amount)
+	require.Equal(t, int64(46), result.ToEntry.Amount)
+	require.Equal(t, accountFrom.Balance-50, result.FromAccount.Balance)
+	require.Equal(t, accountTo.Balance+46, result.ToAccount.Balance)
+
+	// a transfer in a single currency records the same amounts and a rate of 1
+	accountTo = createRandomAccountWithCurrency(t, accountFrom.Currency)
+	result, err = store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: accountFrom.ID,
+		ToAccountID:   accountTo.ID,
+		Amount:        money.New(10, accountFrom.Currency),
+	})
+	require.NoError(t, err)
+	require.Equal(t, int64(10), result.Transfer.ToAmount)
+	require.Equal(t, "1", result.Transfer.ExchangeRate)
+	require.Equal(t, int64(10), result.ToEntry.Amount)
+}
+
+func TestTransferTxCurrencyMismatch(t *testing.T) {
+	store := NewStore(testDB)
+
+	accountFrom := createFundedAccount(t, 100)
+	accountTo := createRandomAccountWithCurrency(t, util.EUR)
+
+	// the amount must be in the currency of the from account
+	_, err := store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: accountFrom.ID,
+		ToAccountID:   accountTo.ID,
+		Amount:        money.New(10, util.EUR),
+	})
+	require.ErrorIs(t, err, money.ErrCurrencyMismatch)
+
+	// the to amount must be in the currency of the to account
+	_, err = store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: accountFrom.ID,
+		ToAccountID:   accountTo.ID,
+		Amount:        money.New(10, accountFrom.Currency),
+	})
+	require.ErrorIs(t, err, money.ErrCurrencyMismatch)
+
+	updatedAccountFrom, err := store.GetAccount(context.Background(), accountFrom.ID)
+	require.NoError(t, err)
+	require.Equal(t, accountFrom.Balance, updatedAccountFrom.Balance)
+}
+
+func TestBalanceCheckConstraint(t *testing.T) {
+	account := createRandomAccount(t)
+
+	_, err := testQueries.AddAccountBalance(context.Background(), AddAccountBalanceParams{
+		ID:     account.ID,
+		Amount: -account.Balance - 1,
+	})
+	require.True(t, isBalanceCheckViolation(err))
+}
+
+func TestTransferTxScheduledRun(t *testing.T) {
+	store := NewStore(testDB)
+
+	scheduled := createRandomScheduledTransfer(t, time.Now())
+	accountFrom := createFundedAccount(t, 100)
+	accountTo := createRandomAccountWithCurrency(t, accountFrom.Currency)
+
+	arg := TransferTxParams{
+		FromAccountID: accountFrom.ID,
+		ToAccountID:   accountTo.ID,
+		Amount:        money.New(10, accountFrom.Currency),
+		ScheduledRun: &ScheduledTransferOccurrence{
+			ScheduledTransferID: scheduled.ID,
+			RunAt:               scheduled.NextRunAt,
+			Attempt:             1,
+		},
+	}
+	result, err := store.TransferTx(context.Background(), arg)
+	require.NoError(t, err)
+
+	runs, err := testQueries.ListScheduledTransferRuns(context.Background(), ListScheduledTransferRunsParams{
+		ScheduledTransferID: scheduled.ID,
+		Limit:               5,
+	})
+	require.NoError(t, err)
+	require.Len(t, runs, 1)
+	require.Equal(t, ScheduledRunSucceeded, runs[0].Status)
+	require.Equal(t, result.Transfer.ID, runs[0].TransferID.Int64)
+
+	// a repeated run of the occurrence is rolled back
+	arg.ScheduledRun.Attempt = 2
+	_, err = store.TransferTx(context.Background(), arg)
+	require.ErrorIs(t, err, ErrOccurrenceExecuted)
+
+	updatedAccountFrom, err := testQueries.GetAccount(context.Background(), accountFrom.ID)
+	require.NoError(t, err)
+	require.Equal(t, int64(90), updatedAccountFrom.Balance)
+}
+
+func TestTransferTxFee(t *testing.T) {
+	store := NewStore(testDB)
+
+	accountFrom := createFundedAccount(t, 100)
+	accountTo := createRandomAccountWithCurrency(t, accountFrom.Currency)
+	feeAccount := createRandomAccountWithCurrency(t, accountFrom.Currency)
+
+	// the fee is taken on top of the amount and credited to the fee account
+	result, err := store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: accountFrom.ID,
+		ToAccountID:   accountTo.ID,
+		Amount:        money.New(50, accountFrom.Currency),
+		Fee:           money.New(5, accountFrom.Currency),
+		FeeAccountID:  feeAccount.ID,
+	})
+	require.NoError(t, err)
+
+	require.Equal(t, int64(5), result.Transfer.Fee)
+	require.Equal(t, int64(-50), result.FromEntry.Amount)
+	require.Equal(t, int64(50), result.ToEntry.Amount)
+	require.Equal(t, accountFrom.ID, result.FeeEntry.AccountID)
+	require.Equal(t, int64(-5), result.FeeEntry.Amount)
+	require.Equal(t, int64(45), result.FromAccount.Balance)
+	require.Equal(t, accountTo.Balance+50, result.ToAccount.Balance)
+
+	updatedFeeAccount, err := store.GetAccount(context.Background(), feeAccount.ID)
+	require.NoError(t, err)
+	require.Equal(t, feeAccount.Balance+5, updatedFeeAccount.Balance)
+
+	// the available balance must cover the amount and the fee
+	_, err = store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: accountFrom.ID,
+		ToAccountID:   accountTo.ID,
+		Amount:        money.New(41, accountFrom.Currency),
+		Fee:           money.New(5, accountFrom.Currency),
+		FeeAccountID:  feeAccount.ID,
+	})
+	require.ErrorIs(t, err, ErrInsufficientFunds)
+
+	// a transfer without fee has no fee entry
+	result, err = store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: accountFrom.ID,
+		ToAccountID:   accountTo.ID,
+		Amount:        money.New(10, accountFrom.Currency),
+	})
+	require.NoError(t, err)
+	require.Zero(t, result.Transfer.Fee)
+	require.Zero(t, result.FeeEntry.ID)
+}
+
+func TestTransferTxFrozenFeeAccount(t *testing.T) {
+	store := NewStore(testDB)
+
+	accountFrom := createFundedAccount(t, 100)
+	accountTo := createRandomAccountWithCurrency(t, accountFrom.Currency)
+	feeAccount := createRandomAccountWithCurrency(t, accountFrom.Currency)
+
+	arg := AuthorizeTransferTxParams{
+		TransferTxParams: TransferTxParams{
+			FromAccountID: accountFrom.ID,
+			ToAccountID:   accountTo.ID,
+			Amount:        money.New(50, accountFrom.Currency),
+			Fee:           money.New(5, accountFrom.Currency),
+			FeeAccountID:  feeAccount.ID,
+		},
+		ExpiresAt: time.Now().Add(time.Hour),
+	}
+	hold, err := store.AuthorizeTransferTx(context.Background(), arg)
+	require.NoError(t, err)
+
+	_, err = store.UpdateAccountStatusTx(context.Background(), UpdateAccountStatusTxParams{
+		AccountID: feeAccount.ID,
+		Status:    AccountFrozen,
+	})
+	require.NoError(t, err)
+
+	// the fee can't be credited to a frozen fee account
+	_, err = store.TransferTx(context.Background(), arg.TransferTxParams)
+	require.ErrorIs(t, err, ErrAccountFrozen)
+
+	_, err = store.CaptureTransferTx(context.Background(), hold.Transfer.ID)
+	require.ErrorIs(t, err, ErrAccountFrozen)
+
+	// a debit-frozen fee account still receives the fee
+	_, err = store.UpdateAccountStatusTx(context.Background(), UpdateAccountStatusTxParams{
+		AccountID: feeAccount.ID,
+		Status:    AccountDebitFrozen,
+	})
+	require.NoError(t, err)
+
+	result, err := store.CaptureTransferTx(context.Background(), hold.Transfer.ID)
+	require.NoError(t, err)
+	require.Equal(t, int64(5), result.Transfer.Fee)
+}
+
+func TestReverseTransferTx(t *testing.T) {
+	store := NewStore(testDB)
+
+	accountFrom := createFundedAccount(t, 100)
+	accountTo := createRandomAccountWithCurrency(t, accountFrom.Currency)
+
+	transfer, err := store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: accountFrom.ID,
+		ToAccountID:   accountTo.ID,
+		Amount:        money.New(50, accountFrom.Currency),
+	})
+	require.NoError(t, err)
+
+	// a partial refund goes from the to account back to the from account
+	result, err := store.ReverseTransferTx(context.Background(), ReverseTransferTxParams{
+		TransferID: transfer.Transfer.ID,
+		Amount:     20,
+	})
+	require.NoError(t, err)
+
+	require.Equal(t, accountTo.ID, result.Transfer.FromAccountID)
+	require.Equal(t, accountFrom.ID, result.Transfer.ToAccountID)
+	require.Equal(t, int64(20), result.Transfer.Amount)
+	require.Equal(t, int64(20), result.Transfer.ToAmount)
+	require.True(t, result.Transfer.ReversalOf.Valid)
+	require.Equal(t, transfer.Transfer.ID, result.Transfer.ReversalOf.Int64)
+	require.Equal(t, int64(20), result.OriginalTransfer.ReversedAmount)
+	require.Equal(t, int64(-20), result.FromEntry.Amount)
+	require.Equal(t, int64(20), result.ToEntry.Amount)
+	require.Equal(t, transfer.ToAccount.Balance-20, result.FromAccount.Balance)
+	require.Equal(t, transfer.FromAccount.Balance+20, result.ToAccount.Balance)
+
+	// the reversals can't exceed the amount of the transfer
+	_, err = store.ReverseTransferTx(context.Background(), ReverseTransferTxParams{
+		TransferID: transfer.Transfer.ID,
+		Amount:     31,
+	})
+	require.ErrorIs(t, err, ErrReversalExceedsTransfer)
+
+	// a reversal without amount reverses the rest of the transfer
+	result2, err := store.ReverseTransferTx(context.Background(), ReverseTransferTxParams{
+		TransferID: transfer.Transfer.ID,
+	})
+	require.NoError(t, err)
+	require.Equal(t, int64(30), result2.Transfer.Amount)
+	require.Equal(t, int64(50), result2.OriginalTransfer.ReversedAmount)
+	require.Equal(t, accountFrom.Balance, result2.ToAccount.Balance)
+
+	_, err = store.ReverseTransferTx(context.Background(), ReverseTransferTxParams{
+		TransferID: transfer.Transfer.ID,
+	})
+	require.ErrorIs(t, err, ErrReversalExceedsTransfer)
+
+	// a reversal can't be reversed
+	_, err = store.ReverseTransferTx(context.Background(), ReverseTransferTxParams{
+		TransferID: result.Transfer.ID,
+	})
+	require.ErrorIs(t, err, ErrTransferNotReversible)
+}
+
+func TestReverseTransferTxCrossCurrency(t *testing.T) {
+	store := NewStore(testDB)
+
+	accountFrom := createFundedAccount(t, 100)
+	accountTo := createRandomAccountWithCurrency(t, util.EUR)
+
+	transfer, err := store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: accountFrom.ID,
+		ToAccountID:   accountTo.ID,
+		Amount:        money.New(50, accountFrom.Currency),
+		ToAmount:      money.New(46, accountTo.Currency),
+		ExchangeRate:  "0.92",
+	})
+	require.NoError(t, err)
+
+	// the to account gives back its share of the to amount at the original rate, rounded down
+	result, err := store.ReverseTransferTx(context.Background(), ReverseTransferTxParams{
+		TransferID: transfer.Transfer.ID,
+		Amount:     25,
+	})
+	require.NoError(t, err)
+	require.Equal(t, int64(23), result.Transfer.Amount)
+	require.Equal(t, int64(25), result.Transfer.ToAmount)
+	require.Equal(t, "1.0869565217", result.Transfer.ExchangeRate)
+
+	result, err = store.ReverseTransferTx(context.Background(), ReverseTransferTxParams{
+		TransferID: transfer.Transfer.ID,
+		Amount:     24,
+	})
+	require.NoError(t, err)
+	require.Equal(t, int64(22), result.Transfer.Amount)
+
+	// the last reversal gives back the rest of the to amount
+	result, err = store.ReverseTransferTx(context.Background(), ReverseTransferTxParams{
+		TransferID: transfer.Transfer.ID,
+	})
+	require.NoError(t, err)
+	require.Equal(t, int64(1), result.Transfer.Amount)
+	require.Equal(t, int64(1), result.Transfer.ToAmount)
+	require.Equal(t, transfer.ToAccount.Balance-46, result.FromAccount.Balance)
+}
+
+func TestReverseTransferTxInsufficientFunds(t *testing.T) {
+	store := NewStore(testDB)
+
+	accountFrom := createFundedAccount(t, 100)
+	accountTo := createRandomAccountWithCurrency(t, accountFrom.Currency)
+	other := createRandomAccountWithCurrency(t, accountFrom.Currency)
+
+	transfer, err := store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: accountFrom.ID,
+		ToAccountID:   accountTo.ID,
+		Amount:        money.New(50, accountFrom.Currency),
+	})
+	require.NoError(t, err)
+
+	// the to account has spent the money, so it can't be given back
+	_, err = store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: accountTo.ID,
+		ToAccountID:   other.ID,
+		Amount:        money.New(transfer.ToAccount.Balance, accountTo.Currency),
+	})
+	require.NoError(t, err)
+
+	_, err = store.ReverseTransferTx(context.Background(), ReverseTransferTxParams{
+		TransferID: transfer.Transfer.ID,
+	})
+	require.ErrorIs(t, err, ErrInsufficientFunds)
+
+	original, err := store.GetTransfer(context.Background(), transfer.Transfer.ID)
+	require.NoError(t, err)
+	require.Zero(t, original.ReversedAmount)
+}
+
+func TestAuthorizeAndCaptureTransferTx(t *testing.T) {
+	store := NewStore(testDB)
+
+	accountFrom := createFundedAccount(t, 100)
+	accountTo := createRandomAccountWithCurrency(t, accountFrom.Currency)
+	feeAccount := createRandomAccountWithCurrency(t, accountFrom.Currency)
+
+	// the amount and the fee are held, without moving the money
+	authorized, err := store.AuthorizeTransferTx(context.Background(), AuthorizeTransferTxParams{
+		TransferTxParams: TransferTxParams{
+			FromAccountID: accountFrom.ID,
+			ToAccountID:   accountTo.ID,
+			Amount:        money.New(60, accountFrom.Currency),
+			Fee:           money.New(5, accountFrom.Currency),
+			FeeAccountID:  feeAccount.ID,
+		},
+		ExpiresAt: time.Now().Add(time.Hour),
+	})
+	require.NoError(t, err)
+	require.Equal(t, TransferPending, authorized.Transfer.Status)
+	require.Equal(t, int64(65), authorized.Hold.Amount)
+	require.Equal(t, feeAccount.ID, authorized.Hold.FeeAccountID.Int64)
+	require.Equal(t, int64(100), authorized.FromAccount.Balance)
+	require.Equal(t, int64(65), authorized.FromAccount.HeldBalance)
+	require.Equal(t, int64(35), authorized.AvailableBalance)
+
+	// the held money can't be spent by other transfers
+	_, err = store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: accountFrom.ID,
+		ToAccountID:   accountTo.ID,
+		Amount:        money.New(36, accountFrom.Currency),
+	})
+	require.ErrorIs(t, err, ErrInsufficientFunds)
+
+	// the capture posts the entries and releases the hold
+	captured, err := store.CaptureTransferTx(context.Background(), authorized.Transfer.ID)
+	require.NoError(t, err)
+	require.Equal(t, TransferCaptured, captured.Transfer.Status)
+	require.Equal(t, int64(-60), captured.FromEntry.Amount)
+	require.Equal(t, int64(60), captured.ToEntry.Amount)
+	require.Equal(t, int64(-5), captured.FeeEntry.Amount)
+	require.Equal(t, int64(35), captured.FromAccount.Balance)
+	require.Zero(t, captured.FromAccount.HeldBalance)
+	require.Equal(t, accountTo.Balance+60, captured.ToAccount.Balance)
+	require.Equal(t, int64(35), captured.AvailableBalance)
+
+	updatedFeeAccount, err := store.GetAccount(context.Background(), feeAccount.ID)
+	require.NoError(t, err)
+	require.Equal(t, feeAccount.Balance+5, updatedFeeAccount.Balance)
+
+	// a transfer is captured once
+	_, err = store.CaptureTransferTx(context.Background(), authorized.Transfer.ID)
+	require.ErrorIs(t, err, ErrTransferNotPending)
+	_, err = store.VoidTransferTx(context.Background(), authorized.Transfer.ID)
+	require.ErrorIs(t, err, ErrTransferNotPending)
+}
+
+func TestVoidTransferTx(t *testing.T) {
+	store := NewStore(testDB)
+
+	accountFrom := createFundedAccount(t, 100)
+	accountTo := createRandomAccountWithCurrency(t, accountFrom.Currency)
+
+	authorized, err := store.AuthorizeTransferTx(context.Background(), AuthorizeTransferTxParams{
+		TransferTxParams: TransferTxParams{
+			FromAccountID: accountFrom.ID,
+			ToAccountID:   accountTo.ID,
+			Amount:        money.New(100, accountFrom.Currency),
+		},
+		ExpiresAt: time.Now().Add(time.Hour),
+	})
+	require.NoError(t, err)
+	require.Zero(t, authorized.AvailableBalance)
+
+	// a pending transfer can't be reversed, it is voided
+	_, err = store.ReverseTransferTx(context.Background(), ReverseTransferTxParams{TransferID: authorized.Transfer.ID})
+	require.ErrorIs(t, err, ErrTransferNotReversible)
+
+	voided, err := store.VoidTransferTx(context.Background(), authorized.Transfer.ID)
+	require.NoError(t, err)
+	require.Equal(t, TransferVoided, voided.Transfer.Status)
+	require.Equal(t, int64(100), voided.FromAccount.Balance)
+	require.Zero(t, voided.FromAccount.HeldBalance)
+	require.Equal(t, int64(100), voided.AvailableBalance)
+
+	_, err = store.CaptureTransferTx(context.Background(), authorized.Transfer.ID)
+	require.ErrorIs(t, err, ErrTransferNotPending)
+
+	_, err = testQueries.GetTransferHold(context.Background(), authorized.Transfer.ID)
+	require.ErrorIs(t, err, sql.ErrNoRows)
+}
+
+func TestExpireTransferTx(t *testing.T) {
+	store := NewStore(testDB)
+
+	accountFrom := createFundedAccount(t, 100)
+	accountTo := createRandomAccountWithCurrency(t, accountFrom.Currency)
+
+	arg := AuthorizeTransferTxParams{
+		TransferTxParams: TransferTxParams{
+			FromAccountID: accountFrom.ID,
+			ToAccountID:   accountTo.ID,
+			Amount:        money.New(40, accountFrom.Currency),
+		},
+		ExpiresAt: time.Now().Add(time.Hour),
+	}
+	pending, err := store.AuthorizeTransferTx(context.Background(), arg)
+	require.NoError(t, err)
+
+	// a hold can't expire before its time
+	_, err = store.ExpireTransferTx(context.Background(), ExpireTransferTxParams{TransferID: pending.Transfer.ID, Now: time.Now()})
+	require.Error(t, err)
+
+	arg.ExpiresAt = time.Now().Add(-time.Second)
+	stale, err := store.AuthorizeTransferTx(context.Background(), arg)
+	require.NoError(t, err)
+
+	now := time.Now()
+	holds, err := testQueries.ListExpiredTransferHolds(context.Background(), ListExpiredTransferHoldsParams{Now: now, Limit: 1000})
+	require.NoError(t, err)
+	ids := make([]int64, len(holds))
+	for i, hold := range holds {
+		ids[i] = hold.TransferID
+	}
+	require.Contains(t, ids, stale.Transfer.ID)
+	require.NotContains(t, ids, pending.Transfer.ID)
+
+	// an expired transfer can't be captured
+	_, err = store.CaptureTransferTx(context.Background(), stale.Transfer.ID)
+	require.ErrorIs(t, err, ErrHoldExpired)
+
+	expired, err := store.ExpireTransferTx(context.Background(), ExpireTransferTxParams{TransferID: stale.Transfer.ID, Now: now})
+	require.NoError(t, err)
+	require.Equal(t, TransferExpired, expired.Transfer.Status)
+	require.Equal(t, int64(40), expired.FromAccount.HeldBalance)
+	require.Equal(t, int64(60), expired.AvailableBalance)
+}
+
+func TestBatchTransferTx(t *testing.T) {
+	store := NewStore(testDB)
+
+	accountFrom := createFundedAccount(t, 100)
+	accountTo1 := createRandomAccountWithCurrency(t, accountFrom.Currency)
+	accountTo2 := createRandomAccountWithCurrency(t, accountFrom.Currency)
+
+	args := []TransferTxParams{
+		{FromAccountID: accountFrom.ID, ToAccountID: accountTo1.ID, Amount: money.New(30, accountFrom.Currency)},
+		{FromAccountID: accountFrom.ID, ToAccountID: accountTo2.ID, Amount: money.New(50, accountFrom.Currency)},
+	}
+	results, err := store.BatchTransferTx(context.Background(), args)
+	require.NoError(t, err)
+	require.Len(t, results, 2)
+
+	// each transfer sees the balance left by the previous ones
+	require.Equal(t, int64(70), results[0].FromAccount.Balance)
+	require.Equal(t, int64(20), results[1].FromAccount.Balance)
+	require.Equal(t, accountTo1.Balance+30, results[0].ToAccount.Balance)
+	require.Equal(t, accountTo2.Balance+50, results[1].ToAccount.Balance)
+	require.Equal(t, TransferCaptured, results[1].Transfer.Status)
+}
+
+func TestBatchTransferTxRollback(t *testing.T) {
+	store := NewStore(testDB)
+
+	accountFrom := createFundedAccount(t, 100)
+	accountTo := createRandomAccountWithCurrency(t, accountFrom.Currency)
+
+	// the second transfer exceeds the balance left by the first one
+	args := []TransferTxParams{
+		{FromAccountID: accountFrom.ID, ToAccountID: accountTo.ID, Amount: money.New(60, accountFrom.Currency)},
+		{FromAccountID: accountFrom.ID, ToAccountID: accountTo.ID, Amount: money.New(60, accountFrom.Currency)},
+	}
+	_, err := store.BatchTransferTx(context.Background(), args)
+	require.ErrorIs(t, err, ErrInsufficientFunds)
+
+	var batchErr *BatchTransferError
+	require.ErrorAs(t, err, &batchErr)
+	require.Equal(t, 1, batchErr.Index)
+
+	// the first transfer has been rolled back
+	account, err := testQueries.GetAccount(context.Background(), accountFrom.ID)
+	require.NoError(t, err)
+	require.Equal(t, int64(100), account.Balance)
+}
+
+func TestCloseAccountTx(t *testing.T) {
+	store := NewStore(testDB)
+
+	account := createFundedAccount(t, 0)
+	other := createFundedAccount(t, 100)
+
+	result, err := store.CloseAccountTx(context.Background(), CloseAccountTxParams{AccountID: account.ID})
+	require.NoError(t, err)
+	require.Equal(t, AccountClosed, result.Account.Status)
+	require.Zero(t, result.Sweep.Transfer.ID)
+
+	// a closed account can't send nor receive money, and can't be closed again
+	_, err = store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: other.ID,
+		ToAccountID:   account.ID,
+		Amount:        money.New(10, account.Currency),
+	})
+	require.ErrorIs(t, err, ErrAccountClosed)
+
+	_, err = store.CloseAccountTx(context.Background(), CloseAccountTxParams{AccountID: account.ID})
+	require.ErrorIs(t, err, ErrAccountClosed)
+}
+
+func TestCloseAccountTxSweep(t *testing.T) {
+	store := NewStore(testDB)
+
+	account := createFundedAccount(t, 100)
+	sweepAccount := createRandomAccountWithCurrency(t, account.Currency)
+
+	// the account has money left, it must be swept
+	_, err := store.CloseAccountTx(context.Background(), CloseAccountTxParams{AccountID: account.ID})
+	require.ErrorIs(t, err, ErrAccountNotSettled)
+
+	sweep := TransferTxParams{
+		FromAccountID: account.ID,
+		ToAccountID:   sweepAccount.ID,
+		Amount:        money.New(60, account.Currency),
+	}
+	_, err = store.CloseAccountTx(context.Background(), CloseAccountTxParams{AccountID: account.ID, Sweep: &sweep})
+	require.ErrorIs(t, err, ErrAccountNotSettled)
+
+	sweep.Amount = money.New(100, account.Currency)
+	result, err := store.CloseAccountTx(context.Background(), CloseAccountTxParams{AccountID: account.ID, Sweep: &sweep})
+	require.NoError(t, err)
+	require.Equal(t, AccountClosed, result.Account.Status)
+	require.Zero(t, result.Account.Balance)
+	require.Equal(t, sweepAccount.Balance+100, result.Sweep.ToAccount.Balance)
+	require.Equal(t, int64(-100), result.Sweep.FromEntry.Amount)
+}
+
+func TestCloseAccountTxDebitFrozen(t *testing.T) {
+	store := NewStore(testDB)
+
+	account := createFundedAccount(t, 100)
+	sweepAccount := createRandomAccountWithCurrency(t, account.Currency)
+
+	_, err := store.UpdateAccountStatusTx(context.Background(), UpdateAccountStatusTxParams{
+		AccountID: account.ID,
+		Status:    AccountDebitFrozen,
+	})
+	require.NoError(t, err)
+
+	// the money of a debit frozen account can't be swept
+	sweep := TransferTxParams{
+		FromAccountID: account.ID,
+		ToAccountID:   sweepAccount.ID,
+		Amount:        money.New(100, account.Currency),
+	}
+	_, err = store.CloseAccountTx(context.Background(), CloseAccountTxParams{AccountID: account.ID, Sweep: &sweep})
+	require.ErrorIs(t, err, ErrAccountFrozen)
+
+	_, err = store.CloseAccountTx(context.Background(), CloseAccountTxParams{AccountID: account.ID})
+	require.ErrorIs(t, err, ErrAccountFrozen)
+
+	// without money, it is closed
+	_, err = testQueries.UpdateAccount(context.Background(), UpdateAccountParams{ID: account.ID, Balance: 0})
+	require.NoError(t, err)
+
+	result, err := store.CloseAccountTx(context.Background(), CloseAccountTxParams{AccountID: account.ID})
+	require.NoError(t, err)
+	require.Equal(t, AccountClosed, result.Account.Status)
+
+	// a frozen account is never closed
+	frozen := createFundedAccount(t, 0)
+	_, err = store.UpdateAccountStatusTx(context.Background(), UpdateAccountStatusTxParams{
+		AccountID: frozen.ID,
+		Status:    AccountFrozen,
+	})
+	require.NoError(t, err)
+
+	_, err = store.CloseAccountTx(context.Background(), CloseAccountTxParams{AccountID: frozen.ID})
+	require.ErrorIs(t, err, ErrAccountFrozen)
+}
+
+func TestCloseAccountTxPendingTransfer(t *testing.T) {
+	store := NewStore(testDB)
+
+	account := createFundedAccount(t, 100)
+	other := createRandomAccountWithCurrency(t, account.Currency)
+
+	_, err := store.AuthorizeTransferTx(context.Background(), AuthorizeTransferTxParams{
+		TransferTxParams: TransferTxParams{
+			FromAccountID: account.ID,
+			ToAccountID:   other.ID,
+			Amount:        money.New(100, account.Currency),
+		},
+		ExpiresAt: time.Now().Add(time.Hour),
+	})
+	require.NoError(t, err)
+
+	// the held money must be captured or released first
+	sweep := TransferTxParams{
+		FromAccountID: account.ID,
+		ToAccountID:   other.ID,
+		Amount:        money.New(100, account.Currency),
+	}
+	_, err = store.CloseAccountTx(context.Background(), CloseAccountTxParams{AccountID: account.ID, Sweep: &sweep})
+	require.ErrorIs(t, err, ErrAccountNotSettled)
+}
+
+func TestUpdateAccountStatusTx(t *testing.T) {
+	store := NewStore(testDB)
+
+	account := createFundedAccount(t, 100)
+	other := createFundedAccount(t, 100)
+	transfer := func(from, to Account) error {
+		_, err := store.TransferTx(context.Background(), TransferTxParams{
+			FromAccountID: from.ID,
+			ToAccountID:   to.ID,
+			Amount:        money.New(10, from.Currency),
+		})
+		return err
+	}
+
+	// a debit frozen account can only receive money
+	updated, err := store.UpdateAccountStatusTx(context.Background(), UpdateAccountStatusTxParams{
+		AccountID: account.ID,
+		Status:    AccountDebitFrozen,
+		Reason:    "investigation",
+		Actor:     "banker",
+	})
+	require.NoError(t, err)
+	require.Equal(t, AccountDebitFrozen, updated.Status)
+	require.Equal(t, "investigation", updated.StatusReason)
+	require.Equal(t, "banker", updated.StatusChangedBy)
+	require.WithinDuration(t, time.Now(), updated.StatusChangedAt, time.Minute)
+
+	require.ErrorIs(t, transfer(account, other), ErrAccountFrozen)
+	require.NoError(t, transfer(other, account))
+
+	// a frozen account can neither send nor receive money
+	updated, err = store.UpdateAccountStatusTx(context.Background(), UpdateAccountStatusTxParams{
+		AccountID: account.ID,
+		Status:    AccountFrozen,
+	})
+	require.NoError(t, err)
+	require.Equal(t, AccountFrozen, updated.Status)
+
+	require.ErrorIs(t, transfer(account, other), ErrAccountFrozen)
+	require.ErrorIs(t, transfer(other, account), ErrAccountFrozen)
+	_, err = store.CloseAccountTx(context.Background(), CloseAccountTxParams{AccountID: account.ID})
+	require.ErrorIs(t, err, ErrAccountFrozen)
+
+	updated, err = store.UpdateAccountStatusTx(context.Background(), UpdateAccountStatusTxParams{
+		AccountID: account.ID,
+		Status:    AccountActive,
+	})
+	require.NoError(t, err)
+	require.Equal(t, AccountActive, updated.Status)
+	require.NoError(t, transfer(account, other))
+
+	// an account is closed by CloseAccountTx only, and can't be opened again
+	_, err = store.UpdateAccountStatusTx(context.Background(), UpdateAccountStatusTxParams{
+		AccountID: account.ID,
+		Status:    AccountClosed,
+	})
+	require.ErrorIs(t, err, ErrInvalidAccountStatus)
+}
+
+func TestCaptureTransferTxFrozenAccount(t *testing.T) {
+	store := NewStore(testDB)
+
+	account := createFundedAccount(t, 100)
+	other := createRandomAccountWithCurrency(t, account.Currency)
+
+	hold, err := store.AuthorizeTransferTx(context.Background(), AuthorizeTransferTxParams{
+		TransferTxParams: TransferTxParams{
+			FromAccountID: account.ID,
+			ToAccountID:   other.ID,
+			Amount:        money.New(50, account.Currency),
+		},
+		ExpiresAt: time.Now().Add(time.Hour),
+	})
+	require.NoError(t, err)
+
+	_, err = store.UpdateAccountStatusTx(context.Background(), UpdateAccountStatusTxParams{
+		AccountID: account.ID,
+		Status:    AccountDebitFrozen,
+	})
+	require.NoError(t, err)
+
+	// the held money can't be taken from a frozen account, but the hold can be released
+	_, err = store.CaptureTransferTx(context.Background(), hold.Transfer.ID)
+	require.ErrorIs(t, err, ErrAccountFrozen)
+
+	result, err := store.VoidTransferTx(context.Background(), hold.Transfer.ID)
+	require.NoError(t, err)
+	require.Zero(t, result.FromAccount.HeldBalance)
+}
+
+func TestCreateAccountTx(t *testing.T) {
+	store := NewStore(testDB)
+	user := createRandomUser(t)
+
+	createAccount := func(nickname string) (Account, error) {
+		return store.CreateAccountTx(context.Background(), CreateAccountTxParams{
+			CreateAccountParams: CreateAccountParams{
+				Owner:    user.Username,
+				Currency: util.EUR,
+				Type:     util.CheckingAccount,
+				Nickname: nickname,
+			},
+			MaxAccounts: 2,
+		})
+	}
+
+	// the accounts of other owners the user is a joint holder of don't count
+	for i := 0; i < 2; i++ {
+		joint := createRandomAccountWithCurrency(t, util.EUR)
+		_, err := testQueries.UpsertAccountHolder(context.Background(), UpsertAccountHolderParams{
+			AccountID:  joint.ID,
+			Username:   user.Username,
+			Permission: PermissionTransfer,
+		})
+		require.NoError(t, err)
+	}
+
+	// several accounts of a currency are told apart by their nickname
+	travel, err := createAccount("Travel fund")
+	require.NoError(t, err)
+	require.Equal(t, "Travel fund", travel.Nickname)
+
+	_, err = createAccount("Travel fund")
+	pqErr, ok := err.(*pq.Error)
+	require.True(t, ok)
+	require.Equal(t, "unique_violation", string(pqErr.Code.Name()))
+
+	bills, err := createAccount("Bills")
+	require.NoError(t, err)
+
+	_, err = createAccount("Savings")
+	require.ErrorIs(t, err, ErrAccountLimitReached)
+
+	// a closed account doesn't count, and its nickname can be given to a new account
+	_, err = store.CloseAccountTx(context.Background(), CloseAccountTxParams{AccountID: bills.ID})
+	require.NoError(t, err)
+
+	_, err = createAccount("Bills")
+	require.NoError(t, err)
+}
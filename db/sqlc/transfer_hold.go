@@ -0,0 +1,224 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"db.sqlc.dev/app/money"
+)
+
+// AuthorizeTransferTxParams contains the input parameters of the first phase of a two-phase transfer.
+// The idempotency key of the transfer parameters is not supported
+type AuthorizeTransferTxParams struct {
+	TransferTxParams
+	// ExpiresAt: the hold is released if the transfer isn't captured before this time
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// ExpireTransferTxParams contains the input parameters of the expiry of a pending transfer
+type ExpireTransferTxParams struct {
+	TransferID int64 `json:"transfer_id"`
+	// Now: time compared with the expiry time of the hold, the clock the expired holds were listed with
+	Now time.Time `json:"now"`
+}
+
+// TransferHoldTxResult is the result of the transactions placing or releasing the hold of a transfer
+type TransferHoldTxResult struct {
+	Transfer Transfer     `json:"transfer"`
+	Hold     TransferHold `json:"hold"`
+	// FromAccount: from account with its updated held balance
+	FromAccount Account `json:"from_account"`
+	// AvailableBalance: money that can still be taken from the from account
+	AvailableBalance int64 `json:"available_balance"`
+}
+
+// AuthorizeTransferTx creates a pending transfer and holds its amount and fee on the from account,
+// without posting entries: the held money can't be spent until the transfer is captured, voided or expired
+func (store *SQLStore) AuthorizeTransferTx(ctx context.Context, arg AuthorizeTransferTxParams) (TransferHoldTxResult, error) {
+	var result TransferHoldTxResult
+	transferArg := arg.TransferTxParams.withDefaults()
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		debit, err := lockTransferAccounts(ctx, q, transferArg)
+		if err != nil {
+			return err
+		}
+
+		result.Transfer, err = insertTransfer(ctx, q, transferArg, TransferPending)
+		if err != nil {
+			return err
+		}
+
+		holdArg := CreateTransferHoldParams{
+			TransferID: result.Transfer.ID,
+			AccountID:  transferArg.FromAccountID,
+			Amount:     debit.Minor,
+			ExpiresAt:  arg.ExpiresAt,
+		}
+		if !transferArg.Fee.IsZero() {
+			holdArg.FeeAccountID = sql.NullInt64{Int64: transferArg.FeeAccountID, Valid: true}
+		}
+		result.Hold, err = q.CreateTransferHold(ctx, holdArg)
+		if err != nil {
+			return err
+		}
+
+		result.FromAccount, err = q.AddAccountHeldBalance(ctx, AddAccountHeldBalanceParams{
+			ID:     transferArg.FromAccountID,
+			Amount: debit.Minor,
+		})
+		if err != nil {
+			return err
+		}
+		return setHoldAvailableBalance(&result)
+	})
+
+	return result, err
+}
+
+// CaptureTransferTx completes a pending transfer: it releases the hold and posts the entries of the transfer,
+// with the amounts and the fee of the authorization
+func (store *SQLStore) CaptureTransferTx(ctx context.Context, transferID int64) (TransferTxResult, error) {
+	var result TransferTxResult
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		// lock the transfer first, so that it can't be captured and released concurrently
+		transfer, hold, err := getPendingTransfer(ctx, q, transferID)
+		if err != nil {
+			return err
+		}
+		if !time.Now().Before(hold.ExpiresAt) {
+			return fmt.Errorf("%w: transfer [%d] expired at %s", ErrHoldExpired, transfer.ID, hold.ExpiresAt)
+		}
+
+		accountIDs := []int64{transfer.FromAccountID, transfer.ToAccountID}
+		if hold.FeeAccountID.Valid {
+			accountIDs = append(accountIDs, hold.FeeAccountID.Int64)
+		}
+		accounts, err := lockAccounts(ctx, q, accountIDs...)
+		if err != nil {
+			return err
+		}
+		// the accounts may have been frozen or closed since the authorization
+		if err := checkAccountsStatus(accounts[transfer.FromAccountID], accounts[transfer.ToAccountID]); err != nil {
+			return err
+		}
+		if hold.FeeAccountID.Valid {
+			if err := checkCreditStatus(accounts[hold.FeeAccountID.Int64]); err != nil {
+				return fmt.Errorf("fee %w", err)
+			}
+		}
+		fromCurrency := accounts[transfer.FromAccountID].Currency
+
+		if _, err := releaseHold(ctx, q, hold); err != nil {
+			return err
+		}
+
+		arg := TransferTxParams{
+			FromAccountID: transfer.FromAccountID,
+			ToAccountID:   transfer.ToAccountID,
+			Amount:        money.New(transfer.Amount, fromCurrency),
+			ToAmount:      money.New(transfer.ToAmount, accounts[transfer.ToAccountID].Currency),
+			ExchangeRate:  transfer.ExchangeRate,
+			Fee:           money.New(transfer.Fee, fromCurrency),
+			FeeAccountID:  hold.FeeAccountID.Int64,
+		}
+		if err := postTransfer(ctx, q, arg, money.New(hold.Amount, fromCurrency), &result); err != nil {
+			return err
+		}
+
+		result.Transfer, err = q.UpdateTransferStatus(ctx, UpdateTransferStatusParams{
+			ID:     transfer.ID,
+			Status: TransferCaptured,
+		})
+		return err
+	})
+
+	return result, err
+}
+
+// VoidTransferTx cancels a pending transfer: the held money is available again
+func (store *SQLStore) VoidTransferTx(ctx context.Context, transferID int64) (TransferHoldTxResult, error) {
+	return store.releaseTransferTx(ctx, transferID, TransferVoided, time.Now())
+}
+
+// ExpireTransferTx releases the hold of a pending transfer that hasn't been captured before its expiry time
+func (store *SQLStore) ExpireTransferTx(ctx context.Context, arg ExpireTransferTxParams) (TransferHoldTxResult, error) {
+	return store.releaseTransferTx(ctx, arg.TransferID, TransferExpired, arg.Now)
+}
+
+// releaseTransferTx releases the hold of a pending transfer and sets its final status: voided or expired.
+// An expired hold must expire at or before now
+func (store *SQLStore) releaseTransferTx(ctx context.Context, transferID int64, status string, now time.Time) (TransferHoldTxResult, error) {
+	var result TransferHoldTxResult
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		transfer, hold, err := getPendingTransfer(ctx, q, transferID)
+		if err != nil {
+			return err
+		}
+		if status == TransferExpired && now.Before(hold.ExpiresAt) {
+			return fmt.Errorf("hold of transfer [%d] expires at %s", transfer.ID, hold.ExpiresAt)
+		}
+
+		// releasing a hold doesn't move any money, so it is allowed whatever the status of the accounts
+		result.Hold = hold
+		result.FromAccount, err = releaseHold(ctx, q, hold)
+		if err != nil {
+			return err
+		}
+
+		result.Transfer, err = q.UpdateTransferStatus(ctx, UpdateTransferStatusParams{
+			ID:     transfer.ID,
+			Status: status,
+		})
+		if err != nil {
+			return err
+		}
+		return setHoldAvailableBalance(&result)
+	})
+
+	return result, err
+}
+
+// getPendingTransfer locks a pending transfer and returns it with its hold
+func getPendingTransfer(ctx context.Context, q *Queries, transferID int64) (Transfer, TransferHold, error) {
+	transfer, err := q.GetTransferForUpdate(ctx, transferID)
+	if err != nil {
+		return Transfer{}, TransferHold{}, err
+	}
+	if transfer.Status != TransferPending {
+		return Transfer{}, TransferHold{}, fmt.Errorf("%w: transfer [%d] is %s", ErrTransferNotPending, transfer.ID, transfer.Status)
+	}
+
+	hold, err := q.GetTransferHold(ctx, transfer.ID)
+	if err != nil {
+		return Transfer{}, TransferHold{}, err
+	}
+	return transfer, hold, nil
+}
+
+// releaseHold deletes the hold of a transfer and makes its money available on the account,
+// it returns the account with its updated held balance
+func releaseHold(ctx context.Context, q *Queries, hold TransferHold) (Account, error) {
+	if _, err := q.DeleteTransferHold(ctx, hold.TransferID); err != nil {
+		return Account{}, err
+	}
+
+	return q.AddAccountHeldBalance(ctx, AddAccountHeldBalanceParams{
+		ID:     hold.AccountID,
+		Amount: -hold.Amount,
+	})
+}
+
+// setHoldAvailableBalance sets the available balance of the from account of the result
+func setHoldAvailableBalance(result *TransferHoldTxResult) error {
+	available, err := result.FromAccount.AvailableBalance()
+	if err != nil {
+		return err
+	}
+	result.AvailableBalance = available.Minor
+	return nil
+}
@@ -0,0 +1,143 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.15.0
+// source: transfer_hold.sql
+
+package db
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+const createTransferHold = `-- name: CreateTransferHold :one
+INSERT INTO transfer_holds (
+  transfer_id,
+  account_id,
+  amount,
+  fee_account_id,
+  expires_at
+) VALUES (
+  $1, $2, $3, $4, $5
+) RETURNING transfer_id, account_id, amount, fee_account_id, expires_at, created_at
+`
+
+type CreateTransferHoldParams struct {
+	TransferID   int64         `json:"transfer_id"`
+	AccountID    int64         `json:"account_id"`
+	Amount       int64         `json:"amount"`
+	FeeAccountID sql.NullInt64 `json:"fee_account_id"`
+	ExpiresAt    time.Time     `json:"expires_at"`
+}
+
+func (q *Queries) CreateTransferHold(ctx context.Context, arg CreateTransferHoldParams) (TransferHold, error) {
+	row := q.db.QueryRowContext(ctx, createTransferHold,
+		arg.TransferID,
+		arg.AccountID,
+		arg.Amount,
+		arg.FeeAccountID,
+		arg.ExpiresAt,
+	)
+	var i TransferHold
+	err := row.Scan(
+		&i.TransferID,
+		&i.AccountID,
+		&i.Amount,
+		&i.FeeAccountID,
+		&i.ExpiresAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const deleteTransferHold = `-- name: DeleteTransferHold :one
+DELETE FROM transfer_holds
+WHERE transfer_id = $1
+RETURNING transfer_id, account_id, amount, fee_account_id, expires_at, created_at
+`
+
+func (q *Queries) DeleteTransferHold(ctx context.Context, transferID int64) (TransferHold, error) {
+	row := q.db.QueryRowContext(ctx, deleteTransferHold, transferID)
+	var i TransferHold
+	err := row.Scan(
+		&i.TransferID,
+		&i.AccountID,
+		&i.Amount,
+		&i.FeeAccountID,
+		&i.ExpiresAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const getTransferHold = `-- name: GetTransferHold :one
+SELECT transfer_id, account_id, amount, fee_account_id, expires_at, created_at FROM transfer_holds
+WHERE transfer_id = $1 LIMIT 1
+`
+
+func (q *Queries) GetTransferHold(ctx context.Context, transferID int64) (TransferHold, error) {
+	row := q.db.QueryRowContext(ctx, getTransferHold, transferID)
+	var i TransferHold
+	err := row.Scan(
+		&i.TransferID,
+		&i.AccountID,
+		&i.Amount,
+		&i.FeeAccountID,
+		&i.ExpiresAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const listExpiredTransferHolds = `-- name: ListExpiredTransferHolds :many
+SELECT transfer_id, account_id, amount, fee_account_id, expires_at, created_at FROM transfer_holds
+WHERE expires_at <= $1
+  AND (expires_at, transfer_id) > ($2::timestamptz, $3::bigint)
+ORDER BY expires_at, transfer_id
+LIMIT $4
+`
+
+type ListExpiredTransferHoldsParams struct {
+	Now             time.Time `json:"now"`
+	AfterExpiresAt  time.Time `json:"after_expires_at"`
+	AfterTransferID int64     `json:"after_transfer_id"`
+	Limit           int32     `json:"limit"`
+}
+
+// keyset page of the expired holds after the hold (after_expires_at, after_transfer_id),
+// so that the holds that can't be expired don't hide the next ones
+func (q *Queries) ListExpiredTransferHolds(ctx context.Context, arg ListExpiredTransferHoldsParams) ([]TransferHold, error) {
+	rows, err := q.db.QueryContext(ctx, listExpiredTransferHolds,
+		arg.Now,
+		arg.AfterExpiresAt,
+		arg.AfterTransferID,
+		arg.Limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []TransferHold{}
+	for rows.Next() {
+		var i TransferHold
+		if err := rows.Scan(
+			&i.TransferID,
+			&i.AccountID,
+			&i.Amount,
+			&i.FeeAccountID,
+			&i.ExpiresAt,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
@@ -2,6 +2,7 @@ package db
 
 import (
 	"context"
+	"database/sql"
 	"testing"
 	"time"
 
@@ -14,6 +15,10 @@ func createRandomTransfer(t *testing.T, account_from Account, account_to Account
 		FromAccountID: account_from.ID,
 		ToAccountID:   account_to.ID,
 		Amount:        util.RandomMoney(),
+		ToAmount:      util.RandomMoney(),
+		ExchangeRate:  "1.25",
+		Fee:           util.RandomInt(0, 100),
+		Status:        TransferCaptured,
 	}
 
 	transfer, err := testQueries.CreateTransfer(context.Background(), arg)
@@ -23,6 +28,11 @@ func createRandomTransfer(t *testing.T, account_from Account, account_to Account
 	require.Equal(t, transfer.FromAccountID, arg.FromAccountID)
 	require.Equal(t, transfer.ToAccountID, arg.ToAccountID)
 	require.Equal(t, transfer.Amount, arg.Amount)
+	require.Equal(t, transfer.ToAmount, arg.ToAmount)
+	require.Equal(t, "1.25", transfer.ExchangeRate)
+	require.Equal(t, arg.Fee, transfer.Fee)
+	require.Equal(t, TransferCaptured, transfer.Status)
+	require.False(t, transfer.ReversalOf.Valid)
 
 	require.NotZero(t, transfer.ID)
 	require.NotZero(t, transfer.CreatedAt)
@@ -75,3 +85,69 @@ func TestListTransfers(t *testing.T) {
 		require.True(t, transfer.FromAccountID == account_from.ID || transfer.ToAccountID == account_to.ID)
 	}
 }
+
+func TestListAccountTransfers(t *testing.T) {
+	account := createRandomAccount(t)
+	other := createRandomAccount(t)
+	for i := 0; i < 5; i++ {
+		createRandomTransfer(t, account, other)
+		createRandomTransfer(t, other, account)
+	}
+
+	arg := ListAccountTransfersParams{
+		AccountID: account.ID,
+		Direction: "incoming",
+		StartTime: sql.NullTime{Time: time.Now().Add(-time.Minute), Valid: true},
+		Limit:     10,
+	}
+	transfers, err := testQueries.ListAccountTransfers(context.Background(), arg)
+	require.NoError(t, err)
+	require.Len(t, transfers, 5)
+	for _, transfer := range transfers {
+		require.Equal(t, account.ID, transfer.ToAccountID)
+	}
+
+	// the incoming transfers are filtered on the amount received by the account
+	arg = ListAccountTransfersParams{
+		AccountID: account.ID,
+		MinAmount: sql.NullInt64{Int64: transfers[0].ToAmount, Valid: true},
+		MaxAmount: sql.NullInt64{Int64: transfers[0].ToAmount, Valid: true},
+		Direction: "incoming",
+		Limit:     10,
+	}
+	filtered, err := testQueries.ListAccountTransfers(context.Background(), arg)
+	require.NoError(t, err)
+	require.NotEmpty(t, filtered)
+	for _, transfer := range filtered {
+		require.Equal(t, transfers[0].ToAmount, transfer.ToAmount)
+	}
+
+	arg = ListAccountTransfersParams{
+		AccountID: account.ID,
+		EndTime:   sql.NullTime{Time: time.Now().Add(-time.Minute), Valid: true},
+		Limit:     10,
+	}
+	transfers, err = testQueries.ListAccountTransfers(context.Background(), arg)
+	require.NoError(t, err)
+	require.Empty(t, transfers)
+}
+
+func TestListAccountTransfersAfter(t *testing.T) {
+	account := createRandomAccount(t)
+	other := createRandomAccount(t)
+
+	var transfers []Transfer
+	for i := 0; i < 3; i++ {
+		transfers = append(transfers, createRandomTransfer(t, account, other), createRandomTransfer(t, other, account))
+	}
+
+	arg := ListAccountTransfersAfterParams{
+		AccountID: account.ID,
+		Direction: "outgoing",
+		AfterID:   transfers[0].ID,
+		Limit:     10,
+	}
+	page, err := testQueries.ListAccountTransfersAfter(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, []Transfer{transfers[2], transfers[4]}, page)
+}
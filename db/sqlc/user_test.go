@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"db.sqlc.dev/app/util"
+	"github.com/lib/pq"
 	"github.com/stretchr/testify/require"
 )
 
@@ -29,6 +30,8 @@ func createRandomUser(t *testing.T) User {
 	require.Equal(t, arg.HashedPassword, user.HashedPassword)
 	require.Equal(t, arg.FullName, user.FullName)
 	require.Equal(t, arg.Email, user.Email)
+	// new users are depositors by default
+	require.Equal(t, util.DepositorRole, user.Role)
 	require.NotZero(t, user.CreatedAt)
 	// required user.PasswordChangedAt is filled with a default value of a zero timestamp
 	require.True(t, user.PasswordChangedAt.IsZero())
@@ -53,3 +56,53 @@ func TestGetUser(t *testing.T) {
 	require.WithinDuration(t, user1.PasswordChangedAt, user2.PasswordChangedAt, time.Second)
 	require.WithinDuration(t, user1.CreatedAt, user2.CreatedAt, time.Second)
 }
+
+func TestUpdateUserTokensRevokedAt(t *testing.T) {
+	user1 := createRandomUser(t)
+	require.True(t, user1.TokensRevokedAt.IsZero())
+
+	revokedAt := time.Now()
+	user2, err := testQueries.UpdateUserTokensRevokedAt(context.Background(), UpdateUserTokensRevokedAtParams{
+		Username:        user1.Username,
+		TokensRevokedAt: revokedAt,
+	})
+	require.NoError(t, err)
+	require.NotEmpty(t, user2)
+
+	require.Equal(t, user1.Username, user2.Username)
+	require.WithinDuration(t, revokedAt, user2.TokensRevokedAt, time.Second)
+}
+
+func TestUpdateUserPassword(t *testing.T) {
+	user1 := createRandomUser(t)
+
+	hashedPassword, err := util.HashPassword(util.RandomString(6))
+	require.NoError(t, err)
+
+	passwordChangedAt := time.Now()
+	user2, err := testQueries.UpdateUserPassword(context.Background(), UpdateUserPasswordParams{
+		Username:          user1.Username,
+		HashedPassword:    hashedPassword,
+		PasswordChangedAt: passwordChangedAt,
+	})
+	require.NoError(t, err)
+	require.NotEmpty(t, user2)
+
+	require.Equal(t, user1.Username, user2.Username)
+	require.Equal(t, hashedPassword, user2.HashedPassword)
+	require.NotEqual(t, user1.HashedPassword, user2.HashedPassword)
+	require.WithinDuration(t, passwordChangedAt, user2.PasswordChangedAt, time.Second)
+	require.Equal(t, user1.Email, user2.Email)
+}
+
+func TestUserRoleCheckConstraint(t *testing.T) {
+	user := createRandomUser(t)
+
+	// a user is either a depositor or a banker
+	_, err := testDB.ExecContext(context.Background(), `UPDATE users SET role = 'admin' WHERE username = $1`, user.Username)
+	require.Error(t, err)
+
+	pqErr, ok := err.(*pq.Error)
+	require.True(t, ok)
+	require.Equal(t, "users_role_check", pqErr.Constraint)
+}
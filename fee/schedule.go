@@ -0,0 +1,214 @@
+// Package fee computes the fees charged on transfers
+package fee
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+
+	"db.sqlc.dev/app/money"
+)
+
+// Tier is a band of transfer amounts with its own fee: flat amount + rate * transfer amount
+type Tier struct {
+	// UpTo: the tier applies to transfer amounts up to UpTo minor units, 0 for the last tier without limit
+	UpTo int64 `json:"up_to"`
+	// Flat: fixed fee in minor units
+	Flat int64 `json:"flat"`
+	// Rate: decimal fraction of the transfer amount, e.g. "0.01" for 1%
+	Rate string `json:"rate"`
+}
+
+// Rule is the fee of the transfers from the accounts of a currency and an account type.
+// A rule has either the Flat and Rate of a single tier, or several Tiers.
+// The fee is then capped between Min and Max
+type Rule struct {
+	Currency string `json:"currency"`
+	// AccountType: type of the from account, empty for the accounts of all types without their own rule
+	AccountType string `json:"account_type"`
+	Flat        int64  `json:"flat"`
+	Rate        string `json:"rate"`
+	Tiers       []Tier `json:"tiers"`
+	// Min and Max bound the fee in minor units, Max is 0 if the fee isn't capped
+	Min int64 `json:"min"`
+	Max int64 `json:"max"`
+}
+
+// tier is a parsed Tier
+type tier struct {
+	upTo int64
+	flat int64
+	rate *big.Rat
+}
+
+// rule is a parsed Rule
+type rule struct {
+	tiers []tier
+	min   int64
+	max   int64
+}
+
+// Schedule holds the fee rules and the house accounts credited with the fees
+type Schedule struct {
+	rules       map[string]rule  // "CURRENCY/account type" -> rule
+	feeAccounts map[string]int64 // currency -> ID of the house fee account
+}
+
+// NewSchedule creates a schedule of the input rules.
+// feeAccounts gives the ID of the house account of each currency, which receives the fees charged in the currency
+func NewSchedule(rules []Rule, feeAccounts map[string]int64) (*Schedule, error) {
+	schedule := &Schedule{
+		rules:       make(map[string]rule, len(rules)),
+		feeAccounts: feeAccounts,
+	}
+
+	for currency, id := range feeAccounts {
+		if id <= 0 {
+			return nil, fmt.Errorf("invalid fee account %d for currency %s", id, currency)
+		}
+	}
+
+	for _, r := range rules {
+		if _, ok := feeAccounts[r.Currency]; !ok {
+			return nil, fmt.Errorf("no fee account for currency %s", r.Currency)
+		}
+
+		key := ruleKey(r.Currency, r.AccountType)
+		if _, ok := schedule.rules[key]; ok {
+			return nil, fmt.Errorf("duplicate fee rule for %s", key)
+		}
+
+		parsed, err := parseRule(r)
+		if err != nil {
+			return nil, fmt.Errorf("invalid fee rule for %s: %w", key, err)
+		}
+		schedule.rules[key] = parsed
+	}
+	return schedule, nil
+}
+
+// parseRule checks the amounts of a rule and parses its rates
+func parseRule(r Rule) (rule, error) {
+	tiers := r.Tiers
+	if len(tiers) == 0 {
+		tiers = []Tier{{Flat: r.Flat, Rate: r.Rate}}
+	} else if r.Flat != 0 || r.Rate != "" {
+		return rule{}, errors.New("a rule with tiers can't have its own flat fee or rate")
+	}
+
+	parsed := rule{tiers: make([]tier, len(tiers)), min: r.Min, max: r.Max}
+	for i, t := range tiers {
+		last := i == len(tiers)-1
+		if last && t.UpTo != 0 {
+			return rule{}, errors.New("the last tier must not have an up_to limit")
+		}
+		if !last && (t.UpTo <= 0 || (i > 0 && t.UpTo <= tiers[i-1].UpTo)) {
+			return rule{}, errors.New("the up_to limits of the tiers must be positive and increasing")
+		}
+		if t.Flat < 0 {
+			return rule{}, errors.New("flat fee must not be negative")
+		}
+
+		rate := new(big.Rat)
+		if t.Rate != "" {
+			if _, ok := rate.SetString(t.Rate); !ok || rate.Sign() < 0 {
+				return rule{}, fmt.Errorf("invalid rate %q", t.Rate)
+			}
+		}
+		parsed.tiers[i] = tier{upTo: t.UpTo, flat: t.Flat, rate: rate}
+	}
+
+	if r.Min < 0 || r.Max < 0 || (r.Max != 0 && r.Max < r.Min) {
+		return rule{}, errors.New("min and max must not be negative, and max must not be lower than min")
+	}
+	return parsed, nil
+}
+
+// Fee returns the fee of a transfer of the input amount from an account of the input type,
+// in the currency of the amount. The fee is zero if no rule applies
+func (schedule *Schedule) Fee(amount money.Amount, accountType string) (money.Amount, error) {
+	r, ok := schedule.rules[ruleKey(amount.Currency, accountType)]
+	if !ok {
+		r, ok = schedule.rules[ruleKey(amount.Currency, "")]
+	}
+	if !ok {
+		return money.New(0, amount.Currency), nil
+	}
+
+	t := r.tiers[len(r.tiers)-1]
+	for _, candidate := range r.tiers {
+		if candidate.upTo == 0 || amount.Minor <= candidate.upTo {
+			t = candidate
+			break
+		}
+	}
+
+	// fee = flat + rate * amount, rounded half up to a minor unit
+	fee := new(big.Rat).Mul(t.rate, new(big.Rat).SetInt64(amount.Minor))
+	fee.Add(fee, new(big.Rat).SetInt64(t.flat))
+	fee.Add(fee, big.NewRat(1, 2))
+	rounded := new(big.Int).Quo(fee.Num(), fee.Denom())
+	if !rounded.IsInt64() {
+		return money.Amount{}, fmt.Errorf("%w: fee of %s", money.ErrOverflow, amount)
+	}
+
+	minor := rounded.Int64()
+	if minor < r.min {
+		minor = r.min
+	}
+	if r.max != 0 && minor > r.max {
+		minor = r.max
+	}
+	return money.New(minor, amount.Currency), nil
+}
+
+// FeeAccountID returns the ID of the house account credited with the fees of the currency
+func (schedule *Schedule) FeeAccountID(currency string) (int64, bool) {
+	id, ok := schedule.feeAccounts[currency]
+	return id, ok
+}
+
+// FeeAccounts returns the ID of the house fee account of each currency
+func (schedule *Schedule) FeeAccounts() map[string]int64 {
+	feeAccounts := make(map[string]int64, len(schedule.feeAccounts))
+	for currency, id := range schedule.feeAccounts {
+		feeAccounts[currency] = id
+	}
+	return feeAccounts
+}
+
+// scheduleFile is the JSON format of the fee file, e.g.
+//
+//	{
+//	  "fee_accounts": {"USD": 1, "EUR": 2},
+//	  "rules": [
+//	    {"currency": "USD", "flat": 25},
+//	    {"currency": "USD", "account_type": "savings", "rate": "0.01", "min": 50, "max": 500},
+//	    {"currency": "EUR", "tiers": [{"up_to": 100000, "flat": 50}, {"rate": "0.0005"}]}
+//	  ]
+//	}
+type scheduleFile struct {
+	FeeAccounts map[string]int64 `json:"fee_accounts"`
+	Rules       []Rule           `json:"rules"`
+}
+
+// LoadSchedule creates a schedule with the rules and the fee accounts of a JSON file
+func LoadSchedule(path string) (*Schedule, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read fee file: %w", err)
+	}
+
+	var file scheduleFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("cannot parse fee file %s: %w", path, err)
+	}
+	return NewSchedule(file.Rules, file.FeeAccounts)
+}
+
+// ruleKey returns the key of the rule of a currency and an account type
+func ruleKey(currency string, accountType string) string {
+	return currency + "/" + accountType
+}
@@ -0,0 +1,115 @@
+package fee
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"db.sqlc.dev/app/money"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScheduleFee(t *testing.T) {
+	schedule, err := NewSchedule([]Rule{
+		{Currency: "USD", Flat: 25},
+		{Currency: "USD", AccountType: "savings", Rate: "0.01", Min: 50, Max: 500},
+		{Currency: "EUR", Tiers: []Tier{
+			{UpTo: 10000, Flat: 50},
+			{UpTo: 100000, Rate: "0.002"},
+			{Flat: 100, Rate: "0.001"},
+		}},
+	}, map[string]int64{"USD": 1, "EUR": 2})
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name        string
+		amount      money.Amount
+		accountType string
+		expected    int64
+	}{
+		{name: "Flat", amount: money.New(1000, "USD"), accountType: "checking", expected: 25},
+		{name: "Percentage", amount: money.New(12345, "USD"), accountType: "savings", expected: 123},
+		{name: "PercentageRounded", amount: money.New(12350, "USD"), accountType: "savings", expected: 124},
+		{name: "CappedMin", amount: money.New(1000, "USD"), accountType: "savings", expected: 50},
+		{name: "CappedMax", amount: money.New(1000000, "USD"), accountType: "savings", expected: 500},
+		{name: "FirstTier", amount: money.New(10000, "EUR"), accountType: "checking", expected: 50},
+		{name: "SecondTier", amount: money.New(10001, "EUR"), accountType: "checking", expected: 20},
+		{name: "LastTier", amount: money.New(200000, "EUR"), accountType: "checking", expected: 300},
+		{name: "NoRule", amount: money.New(1000, "CAD"), accountType: "checking", expected: 0},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			fee, err := schedule.Fee(tc.amount, tc.accountType)
+			require.NoError(t, err)
+			require.Equal(t, money.New(tc.expected, tc.amount.Currency), fee)
+		})
+	}
+
+	id, ok := schedule.FeeAccountID("EUR")
+	require.True(t, ok)
+	require.Equal(t, int64(2), id)
+
+	_, ok = schedule.FeeAccountID("CAD")
+	require.False(t, ok)
+}
+
+func TestScheduleFeeOverflow(t *testing.T) {
+	schedule, err := NewSchedule([]Rule{{Currency: "USD", Rate: "2"}}, map[string]int64{"USD": 1})
+	require.NoError(t, err)
+
+	_, err = schedule.Fee(money.New(math.MaxInt64, "USD"), "checking")
+	require.ErrorIs(t, err, money.ErrOverflow)
+}
+
+func TestNewScheduleInvalidRule(t *testing.T) {
+	feeAccounts := map[string]int64{"USD": 1}
+
+	testCases := []struct {
+		name string
+		rule Rule
+	}{
+		{name: "NoFeeAccount", rule: Rule{Currency: "EUR", Flat: 25}},
+		{name: "NegativeFlat", rule: Rule{Currency: "USD", Flat: -1}},
+		{name: "NegativeRate", rule: Rule{Currency: "USD", Rate: "-0.01"}},
+		{name: "InvalidRate", rule: Rule{Currency: "USD", Rate: "1%"}},
+		{name: "MaxLowerThanMin", rule: Rule{Currency: "USD", Rate: "0.01", Min: 50, Max: 10}},
+		{name: "TiersWithFlat", rule: Rule{Currency: "USD", Flat: 10, Tiers: []Tier{{Flat: 25}}}},
+		{name: "LastTierLimited", rule: Rule{Currency: "USD", Tiers: []Tier{{UpTo: 100, Flat: 25}}}},
+		{name: "TiersNotIncreasing", rule: Rule{Currency: "USD", Tiers: []Tier{{UpTo: 100}, {UpTo: 100}, {}}}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := NewSchedule([]Rule{tc.rule}, feeAccounts)
+			require.Error(t, err)
+		})
+	}
+
+	_, err := NewSchedule([]Rule{{Currency: "USD", Flat: 25}, {Currency: "USD", Flat: 50}}, feeAccounts)
+	require.Error(t, err)
+
+	// a fee account ID must be valid even without a rule in its currency
+	_, err = NewSchedule(nil, map[string]int64{"USD": 0})
+	require.Error(t, err)
+}
+
+func TestLoadSchedule(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "fees.json")
+	err := os.WriteFile(path, []byte(`{
+		"fee_accounts": {"USD": 1},
+		"rules": [{"currency": "USD", "rate": "0.01", "max": 500}]
+	}`), 0600)
+	require.NoError(t, err)
+
+	schedule, err := LoadSchedule(path)
+	require.NoError(t, err)
+
+	fee, err := schedule.Fee(money.New(10000, "USD"), "checking")
+	require.NoError(t, err)
+	require.Equal(t, money.New(100, "USD"), fee)
+
+	_, err = LoadSchedule(filepath.Join(t.TempDir(), "missing.json"))
+	require.Error(t, err)
+}
@@ -0,0 +1,83 @@
+// Package fx provides the exchange rates of cross-currency transfers
+package fx
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+var ErrRateNotFound = errors.New("exchange rate not found")
+
+// RateProvider interface: source of the exchange rates, e.g. a static file or the API of a rate vendor
+type RateProvider interface {
+	// Rate returns the current rate converting amounts from the currency from into the currency to
+	Rate(ctx context.Context, from string, to string) (Rate, error)
+}
+
+// StaticRateProvider: struct of a rate provider with fixed rates, which implements the fx.RateProvider interface
+// The inverse of a rate is used if only the opposite pair is defined
+type StaticRateProvider struct {
+	rates map[string]Rate // "FROM/TO" -> rate
+}
+
+// NewStaticRateProvider creates a rate provider with the input rates
+func NewStaticRateProvider(rates ...Rate) *StaticRateProvider {
+	provider := &StaticRateProvider{rates: make(map[string]Rate, len(rates))}
+	for _, rate := range rates {
+		provider.rates[pair(rate.From, rate.To)] = rate
+	}
+	return provider
+}
+
+func (provider *StaticRateProvider) Rate(ctx context.Context, from string, to string) (Rate, error) {
+	if from == to {
+		return identityRate(from), nil
+	}
+
+	if rate, ok := provider.rates[pair(from, to)]; ok {
+		return rate, nil
+	}
+	if rate, ok := provider.rates[pair(to, from)]; ok {
+		return rate.Inverse()
+	}
+	return Rate{}, fmt.Errorf("%w: %s", ErrRateNotFound, pair(from, to))
+}
+
+// LoadRateFile creates a static rate provider with the rates of a JSON file, e.g.
+//
+//	{"USD/EUR": "0.92", "USD/CAD": "1.36"}
+func LoadRateFile(path string) (*StaticRateProvider, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read rate file: %w", err)
+	}
+
+	var file map[string]string
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("cannot parse rate file %s: %w", path, err)
+	}
+
+	rates := make([]Rate, 0, len(file))
+	for currencies, value := range file {
+		from, to, ok := strings.Cut(currencies, "/")
+		if !ok {
+			return nil, fmt.Errorf("invalid currency pair %q in rate file %s", currencies, path)
+		}
+
+		rate, err := ParseRate(from, to, value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid rate of %s: %w", currencies, err)
+		}
+		rates = append(rates, rate)
+	}
+	return NewStaticRateProvider(rates...), nil
+}
+
+// pair returns the key of a currency pair
+func pair(from string, to string) string {
+	return from + "/" + to
+}
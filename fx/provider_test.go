@@ -0,0 +1,66 @@
+package fx
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestStaticRateProvider(t *testing.T) {
+	rate, err := ParseRate("USD", "EUR", "0.8")
+	require.NoError(t, err)
+	provider := NewStaticRateProvider(rate)
+
+	got, err := provider.Rate(context.Background(), "USD", "EUR")
+	require.NoError(t, err)
+	require.Equal(t, "0.8", got.String())
+
+	// the inverse rate is used for the opposite pair
+	got, err = provider.Rate(context.Background(), "EUR", "USD")
+	require.NoError(t, err)
+	require.Equal(t, "EUR", got.From)
+	require.Equal(t, "USD", got.To)
+	require.Equal(t, "1.25", got.String())
+
+	got, err = provider.Rate(context.Background(), "CAD", "CAD")
+	require.NoError(t, err)
+	require.Equal(t, "1", got.String())
+
+	_, err = provider.Rate(context.Background(), "USD", "CAD")
+	require.ErrorIs(t, err, ErrRateNotFound)
+}
+
+func TestLoadRateFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rates.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{"USD/EUR": "0.92", "USD/CAD": "1.36"}`), 0600))
+
+	provider, err := LoadRateFile(path)
+	require.NoError(t, err)
+
+	rate, err := provider.Rate(context.Background(), "USD", "CAD")
+	require.NoError(t, err)
+	require.Equal(t, "1.36", rate.String())
+
+	testCases := map[string]string{
+		"InvalidPair": `{"USDEUR": "0.92"}`,
+		"InvalidRate": `{"USD/EUR": "-0.92"}`,
+		"InvalidJSON": `{"USD/EUR": 0.92}`,
+	}
+	for name, data := range testCases {
+		require.NoError(t, os.WriteFile(path, []byte(data), 0600))
+		_, err := LoadRateFile(path)
+		require.Error(t, err, name)
+	}
+
+	_, err = LoadRateFile(filepath.Join(t.TempDir(), "missing.json"))
+	require.Error(t, err)
+}
+
+// the rate file of the repository must be valid
+func TestDefaultRateFile(t *testing.T) {
+	_, err := LoadRateFile("../fx_rates.json")
+	require.NoError(t, err)
+}
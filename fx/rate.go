@@ -0,0 +1,119 @@
+package fx
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"db.sqlc.dev/app/money"
+)
+
+// RateScale is the number of decimal places kept in the exchange rates,
+// the recorded rate of a transfer is exactly the rate used to convert its amount
+const RateScale = 10
+
+var (
+	ErrInvalidRate = errors.New("exchange rate must be a positive decimal number")
+	ErrOverflow    = errors.New("converted amount overflows")
+)
+
+// Rate converts amounts from the currency From into the currency To:
+// 1 unit of From is worth Value units of To
+type Rate struct {
+	From  string
+	To    string
+	value *big.Rat
+}
+
+// NewRate creates the rate of the currency pair, value is rounded to RateScale decimal places
+func NewRate(from string, to string, value *big.Rat) (Rate, error) {
+	value = roundRat(value, RateScale)
+	if value.Sign() <= 0 {
+		return Rate{}, ErrInvalidRate
+	}
+	return Rate{From: from, To: to, value: value}, nil
+}
+
+// ParseRate creates the rate of the currency pair from a decimal string, e.g. "0.92"
+func ParseRate(from string, to string, value string) (Rate, error) {
+	r, ok := new(big.Rat).SetString(value)
+	if !ok {
+		return Rate{}, ErrInvalidRate
+	}
+	return NewRate(from, to, r)
+}
+
+// identityRate is the rate between a currency and itself
+func identityRate(currency string) Rate {
+	return Rate{From: currency, To: currency, value: big.NewRat(1, 1)}
+}
+
+// Inverse returns the rate converting amounts from To into From
+func (rate Rate) Inverse() (Rate, error) {
+	return NewRate(rate.To, rate.From, new(big.Rat).Inv(rate.value))
+}
+
+// String returns the rate as a decimal string without trailing zeros, e.g. "0.92"
+func (rate Rate) String() string {
+	s := rate.value.FloatString(RateScale)
+	s = strings.TrimRight(s, "0")
+	return strings.TrimSuffix(s, ".")
+}
+
+// Convert returns the amount of To worth the input amount of From, rounded half away from zero,
+// for currencies whose minor units have the same exponent
+func (rate Rate) Convert(amount int64) (int64, error) {
+	return rate.ConvertMinorUnits(amount, 0, 0)
+}
+
+// ConvertMinorUnits returns the amount of To in minor units worth the input amount of From in minor units,
+// rounded half away from zero. The rate applies to the major units, so the amount is rescaled
+// from the exponent of From to the exponent of To, e.g. 2 for USD cents and 0 for JPY
+func (rate Rate) ConvertMinorUnits(amount int64, fromExponent int, toExponent int) (int64, error) {
+	converted := new(big.Rat).Mul(new(big.Rat).SetInt64(amount), rate.value)
+	exponent := big.NewInt(int64(toExponent - fromExponent))
+	if exponent.Sign() < 0 {
+		exponent.Neg(exponent)
+	}
+	scale := new(big.Rat).SetInt(new(big.Int).Exp(big.NewInt(10), exponent, nil))
+	if toExponent >= fromExponent {
+		converted.Mul(converted, scale)
+	} else {
+		converted.Quo(converted, scale)
+	}
+
+	converted = roundRat(converted, 0)
+	if !converted.Num().IsInt64() {
+		return 0, fmt.Errorf("%w: %d %s at rate %s", ErrOverflow, amount, rate.From, rate)
+	}
+	return converted.Num().Int64(), nil
+}
+
+// ConvertAmount converts an amount of From into To like ConvertMinorUnits,
+// it returns money.ErrCurrencyMismatch if the amount isn't in the currency From
+func (rate Rate) ConvertAmount(amount money.Amount, fromExponent int, toExponent int) (money.Amount, error) {
+	if amount.Currency != rate.From {
+		return money.Amount{}, fmt.Errorf("%w: amount in %s converted at rate of %s", money.ErrCurrencyMismatch, amount.Currency, rate.From)
+	}
+
+	converted, err := rate.ConvertMinorUnits(amount.Minor, fromExponent, toExponent)
+	if err != nil {
+		return money.Amount{}, err
+	}
+	return money.New(converted, rate.To), nil
+}
+
+// roundRat rounds x half away from zero to the input number of decimal places
+func roundRat(x *big.Rat, places int) *big.Rat {
+	scale := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(places)), nil)
+
+	// scaled = |x| * 10^places, rounded by adding 1/2 before truncating
+	scaled := new(big.Rat).Mul(new(big.Rat).Abs(x), new(big.Rat).SetInt(scale))
+	scaled.Add(scaled, big.NewRat(1, 2))
+	n := new(big.Int).Quo(scaled.Num(), scaled.Denom())
+	if x.Sign() < 0 {
+		n.Neg(n)
+	}
+	return new(big.Rat).SetFrac(n, scale)
+}
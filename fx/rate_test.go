@@ -0,0 +1,120 @@
+package fx
+
+import (
+	"math"
+	"testing"
+
+	"db.sqlc.dev/app/money"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseRate(t *testing.T) {
+	rate, err := ParseRate("USD", "EUR", "0.92")
+	require.NoError(t, err)
+	require.Equal(t, "USD", rate.From)
+	require.Equal(t, "EUR", rate.To)
+	require.Equal(t, "0.92", rate.String())
+
+	// rates are rounded to RateScale decimal places
+	rate, err = ParseRate("USD", "EUR", "0.123456789012")
+	require.NoError(t, err)
+	require.Equal(t, "0.123456789", rate.String())
+
+	rate, err = ParseRate("USD", "JPY", "150")
+	require.NoError(t, err)
+	require.Equal(t, "150", rate.String())
+
+	for _, value := range []string{"", "abc", "0", "-1", "0.00000000001"} {
+		_, err = ParseRate("USD", "EUR", value)
+		require.ErrorIs(t, err, ErrInvalidRate, value)
+	}
+}
+
+func TestRateInverse(t *testing.T) {
+	rate, err := ParseRate("USD", "EUR", "0.8")
+	require.NoError(t, err)
+
+	inverse, err := rate.Inverse()
+	require.NoError(t, err)
+	require.Equal(t, "EUR", inverse.From)
+	require.Equal(t, "USD", inverse.To)
+	require.Equal(t, "1.25", inverse.String())
+
+	rate, err = ParseRate("USD", "EUR", "0.9")
+	require.NoError(t, err)
+	inverse, err = rate.Inverse()
+	require.NoError(t, err)
+	require.Equal(t, "1.1111111111", inverse.String())
+}
+
+func TestRateConvert(t *testing.T) {
+	testCases := []struct {
+		rate     string
+		amount   int64
+		expected int64
+	}{
+		{rate: "0.92", amount: 100, expected: 92},
+		{rate: "0.9", amount: 15, expected: 14}, // 13.5 is rounded half away from zero
+		{rate: "0.9", amount: 14, expected: 13}, // 12.6
+		{rate: "0.4", amount: 1, expected: 0},
+		{rate: "1.36", amount: 1000, expected: 1360},
+		{rate: "1", amount: math.MaxInt64, expected: math.MaxInt64},
+	}
+
+	for _, tc := range testCases {
+		rate, err := ParseRate("USD", "EUR", tc.rate)
+		require.NoError(t, err)
+
+		converted, err := rate.Convert(tc.amount)
+		require.NoError(t, err)
+		require.Equal(t, tc.expected, converted, "%d at %s", tc.amount, tc.rate)
+	}
+
+	rate, err := ParseRate("USD", "JPY", "150")
+	require.NoError(t, err)
+	_, err = rate.Convert(math.MaxInt64)
+	require.ErrorIs(t, err, ErrOverflow)
+}
+
+func TestRateConvertMinorUnits(t *testing.T) {
+	testCases := []struct {
+		to         string
+		rate       string
+		amount     int64
+		toExponent int
+		expected   int64
+	}{
+		{to: "EUR", rate: "0.92", amount: 100, toExponent: 2, expected: 92},
+		{to: "JPY", rate: "149.5", amount: 100, toExponent: 0, expected: 150}, // 149.5 yen
+		{to: "JPY", rate: "149.5", amount: 1, toExponent: 0, expected: 1},     // 1.495 yen
+		{to: "BHD", rate: "0.376", amount: 1000, toExponent: 3, expected: 3760},
+	}
+
+	for _, tc := range testCases {
+		rate, err := ParseRate("USD", tc.to, tc.rate)
+		require.NoError(t, err)
+
+		converted, err := rate.ConvertMinorUnits(tc.amount, 2, tc.toExponent)
+		require.NoError(t, err)
+		require.Equal(t, tc.expected, converted, "%d USD cents into %s", tc.amount, tc.to)
+	}
+
+	// 150 yen -> 1.00334... USD
+	rate, err := ParseRate("JPY", "USD", "0.0066889632")
+	require.NoError(t, err)
+	converted, err := rate.ConvertMinorUnits(150, 0, 2)
+	require.NoError(t, err)
+	require.Equal(t, int64(100), converted)
+}
+
+func TestRateConvertAmount(t *testing.T) {
+	rate, err := ParseRate("USD", "JPY", "150")
+	require.NoError(t, err)
+
+	converted, err := rate.ConvertAmount(money.New(1234, "USD"), 2, 0)
+	require.NoError(t, err)
+	require.Equal(t, money.New(1851, "JPY"), converted)
+
+	_, err = rate.ConvertAmount(money.New(1234, "EUR"), 2, 0)
+	require.ErrorIs(t, err, money.ErrCurrencyMismatch)
+}
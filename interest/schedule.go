@@ -0,0 +1,156 @@
+// Package interest computes the interest paid on the balance of the accounts.
+//
+// The interest of a day is accrued on the balance of the account when the interest job runs,
+// there is no history of the balances: the days the job missed are backfilled on the balance of the day of the backfill,
+// not on the balance the account had on those days
+package interest
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"sort"
+)
+
+// DaysInYear is the day count of the annual rates: the interest of a day is 1/365 of the annual interest,
+// leap years included (Actual/365 Fixed)
+const DaysInYear = 365
+
+// Rate is the annual interest rate of the accounts of a currency and an account type
+type Rate struct {
+	Currency    string `json:"currency"`
+	AccountType string `json:"account_type"`
+	// AnnualRate: decimal fraction of the balance paid over a year, e.g. "0.02" for 2%
+	AnnualRate string `json:"annual_rate"`
+}
+
+// Schedule holds the interest rates and the house accounts paying the interest
+type Schedule struct {
+	rates           []Rate
+	expenseAccounts map[string]int64 // currency -> ID of the house interest expense account
+}
+
+// NewSchedule creates a schedule of the input rates.
+// expenseAccounts gives the ID of the house account of each currency, which pays the interest in the currency
+// out of its overdraft, so each expense account needs an overdraft limit
+func NewSchedule(rates []Rate, expenseAccounts map[string]int64) (*Schedule, error) {
+	schedule := &Schedule{
+		rates:           make([]Rate, 0, len(rates)),
+		expenseAccounts: expenseAccounts,
+	}
+
+	keys := make(map[string]bool, len(rates))
+	for _, r := range rates {
+		if _, ok := expenseAccounts[r.Currency]; !ok {
+			return nil, fmt.Errorf("no interest expense account for currency %s", r.Currency)
+		}
+		if r.AccountType == "" {
+			return nil, fmt.Errorf("no account type for the interest rate of %s", r.Currency)
+		}
+
+		key := r.Currency + "/" + r.AccountType
+		if keys[key] {
+			return nil, fmt.Errorf("duplicate interest rate for %s", key)
+		}
+		keys[key] = true
+
+		if _, err := parseRate(r.AnnualRate); err != nil {
+			return nil, fmt.Errorf("invalid interest rate for %s: %w", key, err)
+		}
+		schedule.rates = append(schedule.rates, r)
+	}
+
+	// the accounts are accrued in the same order at each run
+	sort.Slice(schedule.rates, func(i, j int) bool {
+		if schedule.rates[i].Currency != schedule.rates[j].Currency {
+			return schedule.rates[i].Currency < schedule.rates[j].Currency
+		}
+		return schedule.rates[i].AccountType < schedule.rates[j].AccountType
+	})
+	return schedule, nil
+}
+
+// Rates returns the interest rates of the schedule
+func (schedule *Schedule) Rates() []Rate {
+	return append([]Rate(nil), schedule.rates...)
+}
+
+// ExpenseAccountID returns the ID of the house account paying the interest of the currency
+func (schedule *Schedule) ExpenseAccountID(currency string) (int64, bool) {
+	id, ok := schedule.expenseAccounts[currency]
+	return id, ok
+}
+
+// Day is the balance of an account at the end of a day, with the annual rate of the account on that day
+type Day struct {
+	Balance    int64
+	AnnualRate string
+}
+
+// Accrued returns the interest of the days in minor units.
+// The exact interest of a day is balance * annual rate / DaysInYear, a negative balance earns nothing,
+// and the sum of the exact interests is rounded half up to a minor unit.
+// Accruing the interest of a period as Accrued(days so far) - interest already accrued
+// keeps the rounding error of the whole period under half a minor unit
+func Accrued(days []Day) (int64, error) {
+	sum := new(big.Rat)
+	for _, day := range days {
+		if day.Balance <= 0 {
+			continue
+		}
+		rate, err := parseRate(day.AnnualRate)
+		if err != nil {
+			return 0, err
+		}
+		interest := new(big.Rat).Mul(rate, new(big.Rat).SetInt64(day.Balance))
+		sum.Add(sum, interest)
+	}
+	sum.Quo(sum, new(big.Rat).SetInt64(DaysInYear))
+
+	// rounded half up, as the fees
+	sum.Add(sum, big.NewRat(1, 2))
+	rounded := new(big.Int).Quo(sum.Num(), sum.Denom())
+	if !rounded.IsInt64() {
+		return 0, errors.New("interest overflows int64")
+	}
+	return rounded.Int64(), nil
+}
+
+// parseRate parses a decimal annual rate, which must not be negative
+func parseRate(s string) (*big.Rat, error) {
+	rate, ok := new(big.Rat).SetString(s)
+	if !ok || rate.Sign() < 0 {
+		return nil, fmt.Errorf("invalid rate %q", s)
+	}
+	return rate, nil
+}
+
+// scheduleFile is the JSON format of the interest file, e.g.
+//
+//	{
+//	  "expense_accounts": {"USD": 3, "EUR": 4},
+//	  "rates": [
+//	    {"currency": "USD", "account_type": "savings", "annual_rate": "0.02"},
+//	    {"currency": "EUR", "account_type": "savings", "annual_rate": "0.015"}
+//	  ]
+//	}
+type scheduleFile struct {
+	ExpenseAccounts map[string]int64 `json:"expense_accounts"`
+	Rates           []Rate           `json:"rates"`
+}
+
+// LoadSchedule creates a schedule with the rates and the expense accounts of a JSON file
+func LoadSchedule(path string) (*Schedule, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read interest file: %w", err)
+	}
+
+	var file scheduleFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("cannot parse interest file %s: %w", path, err)
+	}
+	return NewSchedule(file.Rates, file.ExpenseAccounts)
+}
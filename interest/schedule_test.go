@@ -0,0 +1,121 @@
+package interest
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAccrued(t *testing.T) {
+	testCases := []struct {
+		name     string
+		days     []Day
+		expected int64
+	}{
+		{name: "NoDays", days: nil, expected: 0},
+		{name: "Exact", days: []Day{{Balance: 10000, AnnualRate: "0.0365"}}, expected: 1},
+		{name: "RoundedUp", days: []Day{{Balance: 10000, AnnualRate: "0.02"}}, expected: 1},
+		{name: "RoundedDown", days: []Day{{Balance: 5000, AnnualRate: "0.02"}}, expected: 0},
+		{name: "HalfUp", days: []Day{{Balance: 18250, AnnualRate: "0.01"}}, expected: 1},
+		{
+			// 3 * 0.548 = 1.644, instead of 3 * 1 if each day was rounded
+			name: "Period",
+			days: []Day{
+				{Balance: 10000, AnnualRate: "0.02"},
+				{Balance: 10000, AnnualRate: "0.02"},
+				{Balance: 10000, AnnualRate: "0.02"},
+			},
+			expected: 2,
+		},
+		{
+			name: "RateChange",
+			days: []Day{
+				{Balance: 100000, AnnualRate: "0.0365"},
+				{Balance: 100000, AnnualRate: "0.073"},
+			},
+			expected: 30,
+		},
+		{
+			name: "NegativeBalance",
+			days: []Day{
+				{Balance: -100000, AnnualRate: "0.0365"},
+				{Balance: 100000, AnnualRate: "0.0365"},
+			},
+			expected: 10,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			interest, err := Accrued(tc.days)
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, interest)
+		})
+	}
+
+	_, err := Accrued([]Day{{Balance: 100, AnnualRate: "2%"}})
+	require.Error(t, err)
+}
+
+func TestSchedule(t *testing.T) {
+	schedule, err := NewSchedule([]Rate{
+		{Currency: "USD", AccountType: "savings", AnnualRate: "0.02"},
+		{Currency: "EUR", AccountType: "savings", AnnualRate: "0.015"},
+	}, map[string]int64{"USD": 3, "EUR": 4})
+	require.NoError(t, err)
+
+	rates := schedule.Rates()
+	require.Len(t, rates, 2)
+	require.Equal(t, "EUR", rates[0].Currency)
+	require.Equal(t, "USD", rates[1].Currency)
+
+	id, ok := schedule.ExpenseAccountID("USD")
+	require.True(t, ok)
+	require.Equal(t, int64(3), id)
+
+	_, ok = schedule.ExpenseAccountID("CAD")
+	require.False(t, ok)
+}
+
+func TestNewScheduleInvalidRate(t *testing.T) {
+	expenseAccounts := map[string]int64{"USD": 3}
+
+	testCases := []struct {
+		name string
+		rate Rate
+	}{
+		{name: "NoExpenseAccount", rate: Rate{Currency: "EUR", AccountType: "savings", AnnualRate: "0.02"}},
+		{name: "NoAccountType", rate: Rate{Currency: "USD", AnnualRate: "0.02"}},
+		{name: "NegativeRate", rate: Rate{Currency: "USD", AccountType: "savings", AnnualRate: "-0.02"}},
+		{name: "InvalidRate", rate: Rate{Currency: "USD", AccountType: "savings", AnnualRate: "2%"}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := NewSchedule([]Rate{tc.rate}, expenseAccounts)
+			require.Error(t, err)
+		})
+	}
+
+	rate := Rate{Currency: "USD", AccountType: "savings", AnnualRate: "0.02"}
+	_, err := NewSchedule([]Rate{rate, rate}, expenseAccounts)
+	require.Error(t, err)
+}
+
+func TestLoadSchedule(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "interest.json")
+	err := os.WriteFile(path, []byte(`{
+		"expense_accounts": {"USD": 3},
+		"rates": [{"currency": "USD", "account_type": "savings", "annual_rate": "0.02"}]
+	}`), 0600)
+	require.NoError(t, err)
+
+	schedule, err := LoadSchedule(path)
+	require.NoError(t, err)
+	require.Equal(t, []Rate{{Currency: "USD", AccountType: "savings", AnnualRate: "0.02"}}, schedule.Rates())
+
+	_, err = LoadSchedule(filepath.Join(t.TempDir(), "missing.json"))
+	require.Error(t, err)
+}
@@ -1,11 +1,14 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 	"log"
 
 	"db.sqlc.dev/app/api"
 	db "db.sqlc.dev/app/db/sqlc"
+	"db.sqlc.dev/app/interest"
+	"db.sqlc.dev/app/scheduler"
 	"db.sqlc.dev/app/util"
 	_ "github.com/lib/pq"
 )
@@ -30,6 +33,28 @@ func main() {
 		log.Fatal("cannot create server:", err)
 	}
 
+	// run the due scheduled transfers in the background, through the server to price them like the API transfers
+	transferScheduler := scheduler.New(store, server, scheduler.Config{
+		Interval:     config.SchedulerInterval,
+		MaxAttempts:  config.SchedulerMaxAttempts,
+		RetryBackoff: config.SchedulerRetryBackoff,
+	})
+	go transferScheduler.Start(context.Background())
+
+	// release the holds of the authorized transfers that haven't been captured in time
+	holdExpirer := scheduler.NewHoldExpirer(store, config.HoldExpiryInterval)
+	go holdExpirer.Start(context.Background())
+
+	// accrue the daily interest of the savings accounts and post it monthly, without interest file no interest is paid
+	if config.InterestPath != "" {
+		interestSchedule, err := interest.LoadSchedule(config.InterestPath)
+		if err != nil {
+			log.Fatal("cannot load interest schedule:", err)
+		}
+		interestJob := scheduler.NewInterestJob(store, interestSchedule, config.InterestInterval)
+		go interestJob.Start(context.Background())
+	}
+
 	err = server.Start(config.ServerAddress)
 	if err != nil {
 		log.Fatal("cannot start server:", err)
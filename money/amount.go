@@ -0,0 +1,114 @@
+// Package money provides amounts of money in the minor unit of their currency,
+// with arithmetic that fails instead of mixing currencies or overflowing
+package money
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+)
+
+var (
+	ErrCurrencyMismatch = errors.New("currency mismatch")
+	ErrOverflow         = errors.New("amount overflows")
+)
+
+// Amount is an amount of money in the minor unit of its currency, e.g. {Minor: 1234, Currency: "USD"} is 12.34 USD
+type Amount struct {
+	Minor    int64  `json:"minor"`
+	Currency string `json:"currency"`
+}
+
+// New creates an amount of minor units of the currency
+func New(minor int64, currency string) Amount {
+	return Amount{Minor: minor, Currency: currency}
+}
+
+// Add returns a + b, both amounts must have the same currency
+func (a Amount) Add(b Amount) (Amount, error) {
+	if err := a.checkCurrency(b); err != nil {
+		return Amount{}, err
+	}
+	if (b.Minor > 0 && a.Minor > math.MaxInt64-b.Minor) || (b.Minor < 0 && a.Minor < math.MinInt64-b.Minor) {
+		return Amount{}, fmt.Errorf("%w: %s + %s", ErrOverflow, a, b)
+	}
+	return New(a.Minor+b.Minor, a.Currency), nil
+}
+
+// Sub returns a - b, both amounts must have the same currency
+func (a Amount) Sub(b Amount) (Amount, error) {
+	if err := a.checkCurrency(b); err != nil {
+		return Amount{}, err
+	}
+	if (b.Minor < 0 && a.Minor > math.MaxInt64+b.Minor) || (b.Minor > 0 && a.Minor < math.MinInt64+b.Minor) {
+		return Amount{}, fmt.Errorf("%w: %s - %s", ErrOverflow, a, b)
+	}
+	return New(a.Minor-b.Minor, a.Currency), nil
+}
+
+// Neg returns -a
+func (a Amount) Neg() (Amount, error) {
+	if a.Minor == math.MinInt64 {
+		return Amount{}, fmt.Errorf("%w: -(%s)", ErrOverflow, a)
+	}
+	return New(-a.Minor, a.Currency), nil
+}
+
+// Cmp compares a and b, which must have the same currency, and returns -1 if a < b, 0 if a == b and +1 if a > b
+func (a Amount) Cmp(b Amount) (int, error) {
+	if err := a.checkCurrency(b); err != nil {
+		return 0, err
+	}
+	switch {
+	case a.Minor < b.Minor:
+		return -1, nil
+	case a.Minor > b.Minor:
+		return 1, nil
+	}
+	return 0, nil
+}
+
+// IsPositive returns true if the amount is greater than 0
+func (a Amount) IsPositive() bool {
+	return a.Minor > 0
+}
+
+// IsNegative returns true if the amount is lower than 0
+func (a Amount) IsNegative() bool {
+	return a.Minor < 0
+}
+
+// IsZero returns true if the amount is 0, whatever its currency
+func (a Amount) IsZero() bool {
+	return a.Minor == 0
+}
+
+// String returns the amount in minor units with its currency, e.g. "1234 USD"
+func (a Amount) String() string {
+	return fmt.Sprintf("%d %s", a.Minor, a.Currency)
+}
+
+// UnmarshalJSON decodes an amount encoded as {"minor": 1234, "currency": "USD"}, the currency is required
+func (a *Amount) UnmarshalJSON(data []byte) error {
+	// amount has the fields of Amount without its methods, to avoid calling UnmarshalJSON recursively
+	type amount Amount
+	var v amount
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	if v.Currency == "" {
+		return errors.New("amount has no currency")
+	}
+
+	*a = Amount(v)
+	return nil
+}
+
+// checkCurrency returns ErrCurrencyMismatch if b doesn't have the currency of a
+func (a Amount) checkCurrency(b Amount) error {
+	if a.Currency != b.Currency {
+		return fmt.Errorf("%w: %s vs %s", ErrCurrencyMismatch, a.Currency, b.Currency)
+	}
+	return nil
+}
@@ -0,0 +1,97 @@
+package money
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAdd(t *testing.T) {
+	sum, err := New(1000, "USD").Add(New(-234, "USD"))
+	require.NoError(t, err)
+	require.Equal(t, New(766, "USD"), sum)
+
+	_, err = New(1000, "USD").Add(New(234, "EUR"))
+	require.ErrorIs(t, err, ErrCurrencyMismatch)
+
+	_, err = New(math.MaxInt64, "USD").Add(New(1, "USD"))
+	require.ErrorIs(t, err, ErrOverflow)
+
+	_, err = New(math.MinInt64, "USD").Add(New(-1, "USD"))
+	require.ErrorIs(t, err, ErrOverflow)
+
+	sum, err = New(math.MaxInt64, "USD").Add(New(math.MinInt64, "USD"))
+	require.NoError(t, err)
+	require.Equal(t, New(-1, "USD"), sum)
+}
+
+func TestSub(t *testing.T) {
+	diff, err := New(1000, "USD").Sub(New(1234, "USD"))
+	require.NoError(t, err)
+	require.Equal(t, New(-234, "USD"), diff)
+
+	_, err = New(1000, "USD").Sub(New(234, "EUR"))
+	require.ErrorIs(t, err, ErrCurrencyMismatch)
+
+	_, err = New(math.MinInt64, "USD").Sub(New(1, "USD"))
+	require.ErrorIs(t, err, ErrOverflow)
+
+	_, err = New(0, "USD").Sub(New(math.MinInt64, "USD"))
+	require.ErrorIs(t, err, ErrOverflow)
+
+	diff, err = New(-1, "USD").Sub(New(math.MinInt64, "USD"))
+	require.NoError(t, err)
+	require.Equal(t, New(math.MaxInt64, "USD"), diff)
+}
+
+func TestNeg(t *testing.T) {
+	neg, err := New(1234, "JPY").Neg()
+	require.NoError(t, err)
+	require.Equal(t, New(-1234, "JPY"), neg)
+	require.True(t, neg.IsNegative())
+
+	_, err = New(math.MinInt64, "JPY").Neg()
+	require.ErrorIs(t, err, ErrOverflow)
+}
+
+func TestCmp(t *testing.T) {
+	testCases := []struct {
+		a        Amount
+		b        Amount
+		expected int
+	}{
+		{a: New(1, "USD"), b: New(2, "USD"), expected: -1},
+		{a: New(2, "USD"), b: New(2, "USD"), expected: 0},
+		{a: New(3, "USD"), b: New(2, "USD"), expected: 1},
+	}
+
+	for _, tc := range testCases {
+		cmp, err := tc.a.Cmp(tc.b)
+		require.NoError(t, err)
+		require.Equal(t, tc.expected, cmp, "%s vs %s", tc.a, tc.b)
+	}
+
+	_, err := New(1, "USD").Cmp(New(1, "EUR"))
+	require.ErrorIs(t, err, ErrCurrencyMismatch)
+}
+
+func TestJSON(t *testing.T) {
+	amount := New(-1234, "BHD")
+
+	data, err := json.Marshal(amount)
+	require.NoError(t, err)
+	require.JSONEq(t, `{"minor": -1234, "currency": "BHD"}`, string(data))
+
+	var decoded Amount
+	err = json.Unmarshal(data, &decoded)
+	require.NoError(t, err)
+	require.Equal(t, amount, decoded)
+
+	err = json.Unmarshal([]byte(`{"minor": 1234}`), &decoded)
+	require.Error(t, err)
+
+	err = json.Unmarshal([]byte(`{"minor": "1234", "currency": "USD"}`), &decoded)
+	require.Error(t, err)
+}
@@ -0,0 +1,93 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	db "db.sqlc.dev/app/db/sqlc"
+)
+
+// HoldExpirer releases the holds of the pending transfers after their expiry time
+type HoldExpirer struct {
+	store db.Store
+	// interval: how often the expired holds are searched
+	interval  time.Duration
+	batchSize int32
+}
+
+// NewHoldExpirer creates a job expiring the stale holds of the store every interval
+func NewHoldExpirer(store db.Store, interval time.Duration) *HoldExpirer {
+	if interval <= 0 {
+		interval = defaultInterval
+	}
+
+	return &HoldExpirer{
+		store:     store,
+		interval:  interval,
+		batchSize: defaultBatchSize,
+	}
+}
+
+// Start expires the stale holds every interval until the context is canceled
+func (expirer *HoldExpirer) Start(ctx context.Context) {
+	ticker := time.NewTicker(expirer.interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := expirer.ExpireHolds(ctx); err != nil {
+			log.Println("cannot expire transfer holds:", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// ExpireHolds expires the pending transfers whose hold has expired, it returns the number of expired transfers.
+// A transfer that can't be expired is logged and retried at the next run, the other transfers are still expired:
+// the holds are read in pages after the last listed hold until all the expired holds have been listed
+func (expirer *HoldExpirer) ExpireHolds(ctx context.Context) (int, error) {
+	// the holds are listed and released with the same clock
+	now := time.Now()
+	arg := db.ListExpiredTransferHoldsParams{
+		Now:   now,
+		Limit: expirer.batchSize,
+	}
+
+	expired := 0
+	for {
+		holds, err := expirer.store.ListExpiredTransferHolds(ctx, arg)
+		if err != nil {
+			return expired, fmt.Errorf("cannot list expired holds: %w", err)
+		}
+
+		for _, hold := range holds {
+			_, err := expirer.store.ExpireTransferTx(ctx, db.ExpireTransferTxParams{
+				TransferID: hold.TransferID,
+				Now:        now,
+			})
+			if err != nil {
+				// the transfer has been captured or voided since the holds were listed
+				if errors.Is(err, db.ErrTransferNotPending) {
+					continue
+				}
+				log.Printf("cannot expire transfer [%d]: %v", hold.TransferID, err)
+				continue
+			}
+			expired++
+		}
+
+		if len(holds) < int(arg.Limit) {
+			return expired, nil
+		}
+		last := holds[len(holds)-1]
+		arg.AfterExpiresAt = last.ExpiresAt
+		arg.AfterTransferID = last.TransferID
+	}
+}
@@ -0,0 +1,127 @@
+package scheduler
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"testing"
+	"time"
+
+	mockdb "db.sqlc.dev/app/db/mock"
+	db "db.sqlc.dev/app/db/sqlc"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExpireHolds(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	holds := []db.TransferHold{{TransferID: 1}, {TransferID: 2}, {TransferID: 3}}
+
+	store := mockdb.NewMockStore(ctrl)
+	var now time.Time
+	store.EXPECT().
+		ListExpiredTransferHolds(gomock.Any(), gomock.Any()).
+		Times(1).
+		DoAndReturn(func(_ context.Context, arg db.ListExpiredTransferHoldsParams) ([]db.TransferHold, error) {
+			require.Equal(t, int32(defaultBatchSize), arg.Limit)
+			now = arg.Now
+			return holds, nil
+		})
+	store.EXPECT().
+		ExpireTransferTx(gomock.Any(), gomock.Any()).
+		Times(3).
+		DoAndReturn(func(_ context.Context, arg db.ExpireTransferTxParams) (db.TransferHoldTxResult, error) {
+			// the holds are released with the clock they were listed with
+			require.True(t, now.Equal(arg.Now))
+			// captured since the holds were listed
+			if arg.TransferID == 2 {
+				return db.TransferHoldTxResult{}, fmt.Errorf("%w: transfer [2] is captured", db.ErrTransferNotPending)
+			}
+			return db.TransferHoldTxResult{}, nil
+		})
+
+	n, err := NewHoldExpirer(store, 0).ExpireHolds(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, 2, n)
+}
+
+func TestExpireHoldsSkipsFailedTransfer(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().
+		ListExpiredTransferHolds(gomock.Any(), gomock.Any()).
+		Times(1).
+		Return([]db.TransferHold{{TransferID: 1}, {TransferID: 2}}, nil)
+	store.EXPECT().
+		ExpireTransferTx(gomock.Any(), gomock.Any()).
+		Times(1).
+		Return(db.TransferHoldTxResult{}, sql.ErrConnDone)
+	// the failed transfer doesn't stop the batch
+	store.EXPECT().ExpireTransferTx(gomock.Any(), gomock.Any()).Times(1)
+
+	n, err := NewHoldExpirer(store, 0).ExpireHolds(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, 1, n)
+}
+
+func TestExpireHoldsPages(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	expiresAt := time.Now().Add(-time.Minute)
+	failing := db.TransferHold{TransferID: 1, ExpiresAt: expiresAt}
+	hold := db.TransferHold{TransferID: 2, ExpiresAt: expiresAt}
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().
+		ListExpiredTransferHolds(gomock.Any(), gomock.Any()).
+		Times(3).
+		DoAndReturn(func(_ context.Context, arg db.ListExpiredTransferHoldsParams) ([]db.TransferHold, error) {
+			require.Equal(t, int32(1), arg.Limit)
+			// each page starts after the last hold of the previous page
+			switch arg.AfterTransferID {
+			case 0:
+				return []db.TransferHold{failing}, nil
+			case failing.TransferID:
+				require.True(t, expiresAt.Equal(arg.AfterExpiresAt))
+				return []db.TransferHold{hold}, nil
+			}
+			return nil, nil
+		})
+	// the hold that always fails to expire doesn't block the next holds
+	store.EXPECT().
+		ExpireTransferTx(gomock.Any(), gomock.Any()).
+		Times(2).
+		DoAndReturn(func(_ context.Context, arg db.ExpireTransferTxParams) (db.TransferHoldTxResult, error) {
+			if arg.TransferID == failing.TransferID {
+				return db.TransferHoldTxResult{}, sql.ErrConnDone
+			}
+			return db.TransferHoldTxResult{}, nil
+		})
+
+	expirer := NewHoldExpirer(store, 0)
+	expirer.batchSize = 1
+	n, err := expirer.ExpireHolds(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, 1, n)
+}
+
+func TestExpireHoldsListError(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().
+		ListExpiredTransferHolds(gomock.Any(), gomock.Any()).
+		Times(1).
+		Return(nil, sql.ErrConnDone)
+	store.EXPECT().ExpireTransferTx(gomock.Any(), gomock.Any()).Times(0)
+
+	n, err := NewHoldExpirer(store, 0).ExpireHolds(context.Background())
+	require.ErrorIs(t, err, sql.ErrConnDone)
+	require.Zero(t, n)
+}
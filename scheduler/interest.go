@@ -0,0 +1,191 @@
+package scheduler
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	db "db.sqlc.dev/app/db/sqlc"
+	"db.sqlc.dev/app/interest"
+)
+
+// InterestJob accrues the daily interest of the accounts with an interest rate,
+// and posts the interest accrued during a month at the beginning of the next month
+type InterestJob struct {
+	store    db.Store
+	schedule *interest.Schedule
+	// interval: how often the job runs, each run accrues the days since the last accrued day once
+	interval  time.Duration
+	batchSize int32
+	// now returns the current time, replaced in tests
+	now func() time.Time
+}
+
+// NewInterestJob creates a job accruing and posting the interest of the schedule every interval
+func NewInterestJob(store db.Store, schedule *interest.Schedule, interval time.Duration) *InterestJob {
+	if interval <= 0 {
+		interval = defaultInterval
+	}
+
+	return &InterestJob{
+		store:     store,
+		schedule:  schedule,
+		interval:  interval,
+		batchSize: defaultBatchSize,
+		now:       time.Now,
+	}
+}
+
+// Start runs the job every interval until the context is canceled
+func (job *InterestJob) Start(ctx context.Context) {
+	ticker := time.NewTicker(job.interval)
+	defer ticker.Stop()
+
+	for {
+		if err := job.Run(ctx); err != nil {
+			log.Println("cannot run interest job:", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Run accrues the interest of every day (UTC) from the last accrued day up to the previous day,
+// then posts the interest accrued before the current month. Running it again the same day does nothing.
+// The days the job didn't run are accrued on the balance of the accounts when the job runs again,
+// not on their balance of those days
+func (job *InterestJob) Run(ctx context.Context) error {
+	now := job.now().UTC()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	yesterday := today.AddDate(0, 0, -1)
+
+	// the last accrued day is accrued again, in case the run accruing it stopped before the last account
+	day := yesterday
+	last, err := job.store.GetLastInterestAccrualDate(ctx)
+	if err != nil && err != sql.ErrNoRows {
+		return fmt.Errorf("cannot get last interest accrual date: %w", err)
+	}
+	if err == nil {
+		last = time.Date(last.Year(), last.Month(), last.Day(), 0, 0, 0, 0, time.UTC)
+		if last.Before(day) {
+			day = last
+		}
+	}
+
+	for ; !day.After(yesterday); day = day.AddDate(0, 0, 1) {
+		if _, err := job.AccrueInterest(ctx, day); err != nil {
+			return err
+		}
+	}
+
+	firstOfMonth := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	_, err = job.PostInterest(ctx, firstOfMonth)
+	return err
+}
+
+// AccrueInterest accrues the interest of the day on the accounts with an interest rate,
+// it returns the number of accruals. The accounts already accrued for the day are skipped
+func (job *InterestJob) AccrueInterest(ctx context.Context, day time.Time) (int, error) {
+	accrued := 0
+	for _, rate := range job.schedule.Rates() {
+		var afterID int64
+		for {
+			accounts, err := job.store.ListInterestAccounts(ctx, db.ListInterestAccountsParams{
+				Currency:    rate.Currency,
+				Type:        rate.AccountType,
+				AfterID:     afterID,
+				AccrualDate: day,
+				Limit:       job.batchSize,
+			})
+			if err != nil {
+				return accrued, fmt.Errorf("cannot list %s %s accounts: %w", rate.Currency, rate.AccountType, err)
+			}
+
+			for _, account := range accounts {
+				_, err := job.store.AccrueInterestTx(ctx, db.AccrueInterestTxParams{
+					AccountID:  account.ID,
+					Date:       day,
+					AnnualRate: rate.AnnualRate,
+				})
+				if err != nil {
+					// the account has been accrued by another job, or closed, since the accounts were listed
+					if errors.Is(err, db.ErrInterestAccrued) || errors.Is(err, db.ErrAccountClosed) {
+						continue
+					}
+					return accrued, fmt.Errorf("cannot accrue interest of account [%d]: %w", account.ID, err)
+				}
+				accrued++
+			}
+
+			if len(accounts) < int(job.batchSize) {
+				break
+			}
+			afterID = accounts[len(accounts)-1].ID
+		}
+	}
+	return accrued, nil
+}
+
+// PostInterest pays the interest accrued before the input date, it returns the number of accounts posted.
+// The interest of a frozen account stays accrued until the account can receive money again.
+// An account that can't be posted is logged and skipped, e.g. if the expense account has no overdraft limit,
+// its interest is posted by a later run
+func (job *InterestJob) PostInterest(ctx context.Context, before time.Time) (int, error) {
+	posted := 0
+	var afterID int64
+	for {
+		accounts, err := job.store.ListUnpostedInterestAccounts(ctx, db.ListUnpostedInterestAccountsParams{
+			AfterID: afterID,
+			Before:  before,
+			Limit:   job.batchSize,
+		})
+		if err != nil {
+			return posted, fmt.Errorf("cannot list accounts with unposted interest: %w", err)
+		}
+
+		for _, account := range accounts {
+			expenseAccountID, ok := job.schedule.ExpenseAccountID(account.Currency)
+			if !ok {
+				log.Printf("cannot post interest of account [%d]: no interest expense account for currency %s", account.ID, account.Currency)
+				continue
+			}
+
+			_, err := job.store.PostInterestTx(ctx, db.PostInterestTxParams{
+				AccountID:        account.ID,
+				ExpenseAccountID: expenseAccountID,
+				Before:           before,
+			})
+			if err != nil {
+				// the interest of every account of the currency stays accrued until the expense account can pay
+				if errors.Is(err, db.ErrExpenseAccountUnavailable) {
+					log.Printf("cannot post interest of account [%d]: the expense account [%d] can't pay: %v", account.ID, expenseAccountID, err)
+					continue
+				}
+				// the account has been frozen or closed since the accounts were listed
+				if errors.Is(err, db.ErrAccountFrozen) || errors.Is(err, db.ErrAccountClosed) {
+					continue
+				}
+				// the expense account pays the interest out of its overdraft limit
+				if errors.Is(err, db.ErrInsufficientFunds) {
+					log.Printf("cannot post interest of account [%d]: the expense account [%d] needs an overdraft limit: %v", account.ID, expenseAccountID, err)
+					continue
+				}
+				log.Printf("cannot post interest of account [%d]: %v", account.ID, err)
+				continue
+			}
+			posted++
+		}
+
+		if len(accounts) < int(job.batchSize) {
+			return posted, nil
+		}
+		afterID = accounts[len(accounts)-1].ID
+	}
+}
@@ -0,0 +1,194 @@
+package scheduler
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"testing"
+	"time"
+
+	mockdb "db.sqlc.dev/app/db/mock"
+	db "db.sqlc.dev/app/db/sqlc"
+	"db.sqlc.dev/app/interest"
+	"db.sqlc.dev/app/util"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestInterestJob(t *testing.T, store db.Store, now time.Time) *InterestJob {
+	schedule, err := interest.NewSchedule([]interest.Rate{
+		{Currency: util.USD, AccountType: util.SavingsAccount, AnnualRate: "0.02"},
+	}, map[string]int64{util.USD: 3})
+	require.NoError(t, err)
+
+	job := NewInterestJob(store, schedule, 0)
+	job.now = func() time.Time { return now }
+	return job
+}
+
+func TestInterestJobRun(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	now := time.Date(2023, time.February, 1, 10, 0, 0, 0, time.UTC)
+	day := time.Date(2023, time.January, 31, 0, 0, 0, 0, time.UTC)
+	firstOfMonth := time.Date(2023, time.February, 1, 0, 0, 0, 0, time.UTC)
+	accounts := []db.Account{{ID: 10, Currency: util.USD}, {ID: 11, Currency: util.USD}}
+
+	store := mockdb.NewMockStore(ctrl)
+	// first run of the job
+	store.EXPECT().
+		GetLastInterestAccrualDate(gomock.Any()).
+		Times(1).
+		Return(time.Time{}, sql.ErrNoRows)
+	store.EXPECT().
+		ListInterestAccounts(gomock.Any(), gomock.Eq(db.ListInterestAccountsParams{
+			Currency:    util.USD,
+			Type:        util.SavingsAccount,
+			AfterID:     0,
+			AccrualDate: day,
+			Limit:       defaultBatchSize,
+		})).
+		Times(1).
+		Return(accounts, nil)
+	store.EXPECT().
+		AccrueInterestTx(gomock.Any(), gomock.Eq(db.AccrueInterestTxParams{AccountID: 10, Date: day, AnnualRate: "0.02"})).
+		Times(1)
+	// accrued by another job since the accounts were listed
+	store.EXPECT().
+		AccrueInterestTx(gomock.Any(), gomock.Eq(db.AccrueInterestTxParams{AccountID: 11, Date: day, AnnualRate: "0.02"})).
+		Times(1).
+		Return(db.InterestAccrual{}, fmt.Errorf("%w: account [11]", db.ErrInterestAccrued))
+
+	store.EXPECT().
+		ListUnpostedInterestAccounts(gomock.Any(), gomock.Eq(db.ListUnpostedInterestAccountsParams{
+			AfterID: 0,
+			Before:  firstOfMonth,
+			Limit:   defaultBatchSize,
+		})).
+		Times(1).
+		Return(accounts, nil)
+	store.EXPECT().
+		PostInterestTx(gomock.Any(), gomock.Eq(db.PostInterestTxParams{AccountID: 10, ExpenseAccountID: 3, Before: firstOfMonth})).
+		Times(1)
+	// frozen since the accounts were listed
+	store.EXPECT().
+		PostInterestTx(gomock.Any(), gomock.Eq(db.PostInterestTxParams{AccountID: 11, ExpenseAccountID: 3, Before: firstOfMonth})).
+		Times(1).
+		Return(db.PostInterestTxResult{}, fmt.Errorf("%w: account [11] is frozen", db.ErrAccountFrozen))
+
+	err := newTestInterestJob(t, store, now).Run(context.Background())
+	require.NoError(t, err)
+}
+
+func TestInterestJobRunMissedDays(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// the job didn't run since it accrued January 28
+	now := time.Date(2023, time.February, 1, 10, 0, 0, 0, time.UTC)
+	last := time.Date(2023, time.January, 28, 0, 0, 0, 0, time.UTC)
+	yesterday := time.Date(2023, time.January, 31, 0, 0, 0, 0, time.UTC)
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().
+		GetLastInterestAccrualDate(gomock.Any()).
+		Times(1).
+		Return(last, nil)
+	// the last accrued day is listed again, then each missed day up to January 31
+	for day := last; !day.After(yesterday); day = day.AddDate(0, 0, 1) {
+		store.EXPECT().
+			ListInterestAccounts(gomock.Any(), gomock.Eq(db.ListInterestAccountsParams{
+				Currency:    util.USD,
+				Type:        util.SavingsAccount,
+				AfterID:     0,
+				AccrualDate: day,
+				Limit:       defaultBatchSize,
+			})).
+			Times(1).
+			Return([]db.Account{{ID: 10, Currency: util.USD}}, nil)
+		store.EXPECT().
+			AccrueInterestTx(gomock.Any(), gomock.Eq(db.AccrueInterestTxParams{AccountID: 10, Date: day, AnnualRate: "0.02"})).
+			Times(1)
+	}
+	store.EXPECT().
+		ListUnpostedInterestAccounts(gomock.Any(), gomock.Any()).
+		Times(1).
+		Return([]db.Account{}, nil)
+
+	err := newTestInterestJob(t, store, now).Run(context.Background())
+	require.NoError(t, err)
+}
+
+func TestInterestJobAccrueInterestError(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	day := time.Date(2023, time.January, 31, 0, 0, 0, 0, time.UTC)
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().
+		ListInterestAccounts(gomock.Any(), gomock.Any()).
+		Times(1).
+		Return([]db.Account{{ID: 10}, {ID: 11}}, nil)
+	store.EXPECT().
+		AccrueInterestTx(gomock.Any(), gomock.Any()).
+		Times(1).
+		Return(db.InterestAccrual{}, sql.ErrConnDone)
+
+	n, err := newTestInterestJob(t, store, time.Now()).AccrueInterest(context.Background(), day)
+	require.ErrorIs(t, err, sql.ErrConnDone)
+	require.Zero(t, n)
+}
+
+func TestInterestJobPostInterestNoExpenseAccount(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().
+		ListUnpostedInterestAccounts(gomock.Any(), gomock.Any()).
+		Times(1).
+		Return([]db.Account{{ID: 10, Currency: util.EUR}}, nil)
+	store.EXPECT().
+		PostInterestTx(gomock.Any(), gomock.Any()).
+		Times(0)
+
+	n, err := newTestInterestJob(t, store, time.Now()).PostInterest(context.Background(), time.Now())
+	require.NoError(t, err)
+	require.Zero(t, n)
+}
+
+func TestInterestJobPostInterestSkipsFailedAccount(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	before := time.Date(2023, time.February, 1, 0, 0, 0, 0, time.UTC)
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().
+		ListUnpostedInterestAccounts(gomock.Any(), gomock.Any()).
+		Times(1).
+		Return([]db.Account{{ID: 10, Currency: util.USD}, {ID: 11, Currency: util.USD}, {ID: 12, Currency: util.USD}, {ID: 13, Currency: util.USD}}, nil)
+	// the expense account has no overdraft limit
+	store.EXPECT().
+		PostInterestTx(gomock.Any(), gomock.Eq(db.PostInterestTxParams{AccountID: 10, ExpenseAccountID: 3, Before: before})).
+		Times(1).
+		Return(db.PostInterestTxResult{}, fmt.Errorf("%w: account [3]", db.ErrInsufficientFunds))
+	store.EXPECT().
+		PostInterestTx(gomock.Any(), gomock.Eq(db.PostInterestTxParams{AccountID: 11, ExpenseAccountID: 3, Before: before})).
+		Times(1).
+		Return(db.PostInterestTxResult{}, sql.ErrConnDone)
+	// the expense account is frozen
+	store.EXPECT().
+		PostInterestTx(gomock.Any(), gomock.Eq(db.PostInterestTxParams{AccountID: 13, ExpenseAccountID: 3, Before: before})).
+		Times(1).
+		Return(db.PostInterestTxResult{}, fmt.Errorf("%w: account [3] is frozen", db.ErrExpenseAccountUnavailable))
+	store.EXPECT().
+		PostInterestTx(gomock.Any(), gomock.Eq(db.PostInterestTxParams{AccountID: 12, ExpenseAccountID: 3, Before: before})).
+		Times(1)
+
+	n, err := newTestInterestJob(t, store, time.Now()).PostInterest(context.Background(), before)
+	require.NoError(t, err)
+	require.Equal(t, 1, n)
+}
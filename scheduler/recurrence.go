@@ -0,0 +1,229 @@
+package scheduler
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// minInterval limits how often an "@every" recurrence can run
+const minInterval = time.Minute
+
+// maxSearch bounds the search of the next occurrence of a cron expression,
+// expressions without occurrence in this period are rejected, e.g. "0 0 30 2 *"
+const maxSearch = 5 * 366 * 24 * time.Hour
+
+// Recurrence gives the occurrences of a recurring transfer
+type Recurrence interface {
+	// Next returns the first occurrence strictly after t, or the zero time if there is none
+	Next(t time.Time) time.Time
+}
+
+// descriptors are the shortcuts of common cron expressions
+var descriptors = map[string]string{
+	"@hourly":  "0 * * * *",
+	"@daily":   "0 0 * * *",
+	"@weekly":  "0 0 * * 0",
+	"@monthly": "0 0 1 * *",
+	"@yearly":  "0 0 1 1 *",
+}
+
+// ParseRecurrence parses a recurrence, either:
+//   - a cron expression with 5 fields: minute hour day-of-month month day-of-week, e.g. "0 9 1 * *"
+//     for 9:00 on the first day of every month. A field is "*", a value, a range "a-b", a step "*/n" or "a-b/n",
+//     or a comma-separated list of those. Day of week goes from 0 (Sunday) to 7 (Sunday again);
+//   - a descriptor: "@hourly", "@daily", "@weekly", "@monthly" or "@yearly";
+//   - an interval: "@every <duration>", e.g. "@every 168h", of at least a minute.
+func ParseRecurrence(spec string) (Recurrence, error) {
+	spec = strings.TrimSpace(spec)
+	if spec == "" {
+		return nil, errors.New("empty recurrence")
+	}
+
+	if strings.HasPrefix(spec, "@every ") {
+		interval, err := time.ParseDuration(strings.TrimSpace(strings.TrimPrefix(spec, "@every ")))
+		if err != nil {
+			return nil, fmt.Errorf("invalid interval: %w", err)
+		}
+		if interval < minInterval {
+			return nil, fmt.Errorf("interval must be at least %s", minInterval)
+		}
+		return every(interval), nil
+	}
+
+	if expr, ok := descriptors[spec]; ok {
+		spec = expr
+	} else if strings.HasPrefix(spec, "@") {
+		return nil, fmt.Errorf("unknown descriptor %q", spec)
+	}
+
+	schedule, err := parseCron(spec)
+	if err != nil {
+		return nil, err
+	}
+	start := time.Unix(0, 0).UTC()
+	if schedule.Next(start).IsZero() {
+		return nil, fmt.Errorf("cron expression %q never runs", spec)
+	}
+	return schedule, nil
+}
+
+// every is a recurrence at a fixed interval
+type every time.Duration
+
+func (interval every) Next(t time.Time) time.Time {
+	return t.Add(time.Duration(interval))
+}
+
+// cronSchedule is a parsed cron expression, each field is a bit set of the accepted values
+type cronSchedule struct {
+	minute, hour, dom, month, dow uint64
+	// domAny and dowAny are true if the day fields are "*":
+	// if both day fields are restricted, a day matches if it matches either of them
+	domAny, dowAny bool
+}
+
+// cronField is the range of the values of a field
+type cronField struct {
+	name     string
+	min, max int
+}
+
+var cronFields = []cronField{
+	{name: "minute", min: 0, max: 59},
+	{name: "hour", min: 0, max: 23},
+	{name: "day of month", min: 1, max: 31},
+	{name: "month", min: 1, max: 12},
+	{name: "day of week", min: 0, max: 7},
+}
+
+func parseCron(spec string) (*cronSchedule, error) {
+	fields := strings.Fields(spec)
+	if len(fields) != len(cronFields) {
+		return nil, fmt.Errorf("cron expression %q must have %d fields", spec, len(cronFields))
+	}
+
+	sets := make([]uint64, len(fields))
+	for i, field := range fields {
+		set, err := parseCronField(field, cronFields[i])
+		if err != nil {
+			return nil, err
+		}
+		sets[i] = set
+	}
+
+	schedule := &cronSchedule{
+		minute: sets[0],
+		hour:   sets[1],
+		dom:    sets[2],
+		month:  sets[3],
+		dow:    sets[4],
+		domAny: fields[2] == "*",
+		dowAny: fields[4] == "*",
+	}
+	// 7 is another name of Sunday
+	if schedule.dow&(1<<7) != 0 {
+		schedule.dow = schedule.dow&^(1<<7) | 1
+	}
+	return schedule, nil
+}
+
+// parseCronField returns the bit set of the values of a field, e.g. "1-5,10" -> bits 1 to 5 and 10
+func parseCronField(field string, bounds cronField) (uint64, error) {
+	var set uint64
+	for _, part := range strings.Split(field, ",") {
+		rangePart, stepPart, hasStep := strings.Cut(part, "/")
+
+		step := 1
+		if hasStep {
+			n, err := strconv.Atoi(stepPart)
+			if err != nil || n <= 0 {
+				return 0, fmt.Errorf("invalid step %q in %s field", stepPart, bounds.name)
+			}
+			step = n
+		}
+
+		low, high := bounds.min, bounds.max
+		if rangePart != "*" {
+			lowPart, highPart, isRange := strings.Cut(rangePart, "-")
+			var err error
+			if low, err = parseCronValue(lowPart, bounds); err != nil {
+				return 0, err
+			}
+			high = low
+			if isRange {
+				if high, err = parseCronValue(highPart, bounds); err != nil {
+					return 0, err
+				}
+				if high < low {
+					return 0, fmt.Errorf("invalid range %q in %s field", rangePart, bounds.name)
+				}
+			} else if hasStep {
+				// "a/n" means from a to the maximum every n
+				high = bounds.max
+			}
+		}
+
+		for value := low; value <= high; value += step {
+			set |= 1 << value
+		}
+	}
+	return set, nil
+}
+
+func parseCronValue(value string, bounds cronField) (int, error) {
+	n, err := strconv.Atoi(value)
+	if err != nil || n < bounds.min || n > bounds.max {
+		return 0, fmt.Errorf("invalid value %q in %s field, must be between %d and %d", value, bounds.name, bounds.min, bounds.max)
+	}
+	return n, nil
+}
+
+// Next returns the first minute after t that matches the expression, in the location of t
+func (schedule *cronSchedule) Next(t time.Time) time.Time {
+	limit := t.Add(maxSearch)
+	loc := t.Location()
+	t = t.Truncate(time.Minute).Add(time.Minute)
+
+	for t.Before(limit) {
+		if !has(schedule.month, int(t.Month())) {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, loc)
+			continue
+		}
+		if !schedule.matchDay(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, loc)
+			continue
+		}
+		if !has(schedule.hour, t.Hour()) {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, loc)
+			continue
+		}
+		if !has(schedule.minute, t.Minute()) {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
+
+func (schedule *cronSchedule) matchDay(t time.Time) bool {
+	domMatch := has(schedule.dom, t.Day())
+	dowMatch := has(schedule.dow, int(t.Weekday()))
+	switch {
+	case schedule.domAny && schedule.dowAny:
+		return true
+	case schedule.domAny:
+		return dowMatch
+	case schedule.dowAny:
+		return domMatch
+	default:
+		return domMatch || dowMatch
+	}
+}
+
+func has(set uint64, value int) bool {
+	return set&(1<<value) != 0
+}
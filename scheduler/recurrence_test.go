@@ -0,0 +1,89 @@
+package scheduler
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecurrenceNext(t *testing.T) {
+	// Wednesday
+	start := time.Date(2023, time.March, 15, 10, 30, 20, 0, time.UTC)
+
+	testCases := []struct {
+		name     string
+		spec     string
+		expected time.Time
+	}{
+		{name: "EveryMinute", spec: "* * * * *", expected: time.Date(2023, time.March, 15, 10, 31, 0, 0, time.UTC)},
+		{name: "DailyAt9", spec: "0 9 * * *", expected: time.Date(2023, time.March, 16, 9, 0, 0, 0, time.UTC)},
+		{name: "LaterToday", spec: "45 10 * * *", expected: time.Date(2023, time.March, 15, 10, 45, 0, 0, time.UTC)},
+		{name: "FirstOfMonth", spec: "0 9 1 * *", expected: time.Date(2023, time.April, 1, 9, 0, 0, 0, time.UTC)},
+		{name: "Step", spec: "*/20 * * * *", expected: time.Date(2023, time.March, 15, 10, 40, 0, 0, time.UTC)},
+		{name: "Weekdays", spec: "0 8 * * 1-5", expected: time.Date(2023, time.March, 16, 8, 0, 0, 0, time.UTC)},
+		{name: "Sunday7", spec: "0 8 * * 7", expected: time.Date(2023, time.March, 19, 8, 0, 0, 0, time.UTC)},
+		{name: "List", spec: "0 0 1,20 * *", expected: time.Date(2023, time.March, 20, 0, 0, 0, 0, time.UTC)},
+		{name: "DayOfMonthOrWeek", spec: "0 0 31 * 5", expected: time.Date(2023, time.March, 17, 0, 0, 0, 0, time.UTC)},
+		{name: "LeapDay", spec: "0 0 29 2 *", expected: time.Date(2024, time.February, 29, 0, 0, 0, 0, time.UTC)},
+		{name: "Monthly", spec: "@monthly", expected: time.Date(2023, time.April, 1, 0, 0, 0, 0, time.UTC)},
+		{name: "Weekly", spec: "@weekly", expected: time.Date(2023, time.March, 19, 0, 0, 0, 0, time.UTC)},
+		{name: "Every", spec: "@every 168h", expected: start.Add(168 * time.Hour)},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			recurrence, err := ParseRecurrence(tc.spec)
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, recurrence.Next(start))
+		})
+	}
+}
+
+func TestParseRecurrenceInvalid(t *testing.T) {
+	specs := []string{
+		"",
+		"* * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"5-1 * * * *",
+		"*/0 * * * *",
+		"a * * * *",
+		"0 0 30 2 *",
+		"@every 30s",
+		"@every tomorrow",
+		"@never",
+	}
+
+	for _, spec := range specs {
+		_, err := ParseRecurrence(spec)
+		require.Error(t, err, spec)
+	}
+}
+
+func TestFirstRun(t *testing.T) {
+	start := time.Date(2023, time.March, 15, 9, 0, 0, 0, time.UTC)
+
+	first, err := FirstRun("", start)
+	require.NoError(t, err)
+	require.Equal(t, start, first)
+
+	first, err = FirstRun("@every 24h", start)
+	require.NoError(t, err)
+	require.Equal(t, start, first)
+
+	// a start time matching the cron expression is the first run
+	first, err = FirstRun("0 9 * * *", start)
+	require.NoError(t, err)
+	require.Equal(t, start, first)
+
+	first, err = FirstRun("0 9 * * *", start.Add(time.Second))
+	require.NoError(t, err)
+	require.Equal(t, start.Add(24*time.Hour), first)
+
+	_, err = FirstRun("@every 1s", start)
+	require.Error(t, err)
+}
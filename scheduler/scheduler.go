@@ -0,0 +1,259 @@
+// Package scheduler runs the background jobs of the bank: it executes the scheduled transfers when they are due,
+// records the outcome of each run and retries the failed runs with an exponential backoff,
+// it releases the holds of the pending transfers that haven't been captured in time,
+// and it accrues and posts the interest of the savings accounts
+package scheduler
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	db "db.sqlc.dev/app/db/sqlc"
+)
+
+// Statuses of a scheduled transfer
+const (
+	// StatusActive: the transfer runs at its next occurrences
+	StatusActive = "active"
+	// StatusPaused: the transfer doesn't run until it is active again
+	StatusPaused = "paused"
+	// StatusCompleted: the transfer has no more occurrences
+	StatusCompleted = "completed"
+	// StatusFailed: all the attempts of a one-off transfer have failed
+	StatusFailed = "failed"
+)
+
+// Statuses of a run of a scheduled transfer
+const (
+	RunSucceeded = db.ScheduledRunSucceeded
+	RunFailed    = "failed"
+)
+
+// Default values of the Config fields
+const (
+	defaultInterval     = time.Minute
+	defaultBatchSize    = 100
+	defaultLease        = 5 * time.Minute
+	defaultMaxAttempts  = 5
+	defaultRetryBackoff = time.Minute
+	// maxRetryBackoff caps the exponential backoff
+	maxRetryBackoff = 24 * time.Hour
+)
+
+// Executor executes a scheduled transfer, e.g. with Store.TransferTx.
+// The executor records the succeeded run of the occurrence next_run_at in the transaction of its transfer
+// (see db.TransferTxParams.ScheduledRun): a run is repeated if the scheduler stops before scheduling the next occurrence,
+// and executing an occurrence that already has a succeeded run must return db.ErrOccurrenceExecuted without moving the money
+type Executor interface {
+	ExecuteScheduledTransfer(ctx context.Context, scheduled db.ScheduledTransfer) (db.TransferTxResult, error)
+}
+
+// Config of a scheduler, the zero fields get default values
+type Config struct {
+	// Interval: how often the due transfers are searched
+	Interval time.Duration
+	// BatchSize: maximum number of transfers claimed at once
+	BatchSize int32
+	// Lease: how long the claimed transfers are reserved, so that other schedulers don't run them.
+	// It must be longer than the execution of a batch
+	Lease time.Duration
+	// MaxAttempts: attempts of an occurrence before giving up on it
+	MaxAttempts int32
+	// RetryBackoff: delay before the first retry of a failed run, doubled at each retry
+	RetryBackoff time.Duration
+}
+
+// Scheduler runs the due scheduled transfers of the store
+type Scheduler struct {
+	store    db.Store
+	executor Executor
+	config   Config
+	// now returns the current time, replaced in tests
+	now func() time.Time
+}
+
+// New creates a scheduler executing the due transfers of the store with the executor
+func New(store db.Store, executor Executor, config Config) *Scheduler {
+	if config.Interval <= 0 {
+		config.Interval = defaultInterval
+	}
+	if config.BatchSize <= 0 {
+		config.BatchSize = defaultBatchSize
+	}
+	if config.Lease <= 0 {
+		config.Lease = defaultLease
+	}
+	if config.MaxAttempts <= 0 {
+		config.MaxAttempts = defaultMaxAttempts
+	}
+	if config.RetryBackoff <= 0 {
+		config.RetryBackoff = defaultRetryBackoff
+	}
+
+	return &Scheduler{
+		store:    store,
+		executor: executor,
+		config:   config,
+		now:      time.Now,
+	}
+}
+
+// Start runs the due transfers every config.Interval until the context is canceled
+func (scheduler *Scheduler) Start(ctx context.Context) {
+	ticker := time.NewTicker(scheduler.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := scheduler.RunDue(ctx); err != nil {
+			log.Println("cannot run scheduled transfers:", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunDue claims the due transfers and runs them, it returns the number of runs.
+// The runs of a batch are independent: a failed run is recorded and retried later,
+// and a run whose outcome can't be stored is logged and repeated when its lease expires
+func (scheduler *Scheduler) RunDue(ctx context.Context) (int, error) {
+	claimed, err := scheduler.store.ClaimDueScheduledTransfers(ctx, db.ClaimDueScheduledTransfersParams{
+		LeaseUntil: scheduler.now().Add(scheduler.config.Lease),
+		MaxCount:   scheduler.config.BatchSize,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("cannot claim due transfers: %w", err)
+	}
+
+	runs := 0
+	for _, scheduled := range claimed {
+		if err := scheduler.run(ctx, scheduled); err != nil {
+			log.Println(err)
+			continue
+		}
+		runs++
+	}
+	return runs, nil
+}
+
+// run executes the occurrence next_run_at of a scheduled transfer, records its outcome and schedules the next attempt
+func (scheduler *Scheduler) run(ctx context.Context, scheduled db.ScheduledTransfer) error {
+	_, execErr := scheduler.executor.ExecuteScheduledTransfer(ctx, scheduled)
+	// the succeeded run is recorded by the executor, also when the occurrence has been executed by an earlier run
+	succeeded := execErr == nil || errors.Is(execErr, db.ErrOccurrenceExecuted)
+
+	if !succeeded {
+		arg := db.CreateScheduledTransferRunParams{
+			ScheduledTransferID: scheduled.ID,
+			RunAt:               scheduled.NextRunAt,
+			Attempt:             scheduled.Attempts + 1,
+			Status:              RunFailed,
+			Error:               execErr.Error(),
+		}
+		if _, err := scheduler.store.CreateScheduledTransferRun(ctx, arg); err != nil {
+			return fmt.Errorf("cannot record run of scheduled transfer [%d]: %w", scheduled.ID, err)
+		}
+	}
+
+	next := scheduler.nextSchedule(scheduled, succeeded)
+	// the schedule is only updated if the transfer is still claimed by this run
+	next.ClaimedUntil = scheduled.NextAttemptAt
+	if _, err := scheduler.store.UpdateScheduledTransferSchedule(ctx, next); err != nil {
+		if err == sql.ErrNoRows {
+			// the transfer has been paused, updated or deleted during the run, its new schedule is kept
+			log.Printf("scheduled transfer [%d] changed concurrently during its run", scheduled.ID)
+			return nil
+		}
+		return fmt.Errorf("cannot schedule transfer [%d]: %w", scheduled.ID, err)
+	}
+	return nil
+}
+
+// nextSchedule returns the schedule of a transfer after a run: the next occurrence after a success
+// or after the last failed attempt, or a retry of the occurrence after a failure
+func (scheduler *Scheduler) nextSchedule(scheduled db.ScheduledTransfer, succeeded bool) db.UpdateScheduledTransferScheduleParams {
+	now := scheduler.now()
+	attempts := scheduled.Attempts + 1
+
+	if !succeeded && attempts < scheduler.config.MaxAttempts {
+		return db.UpdateScheduledTransferScheduleParams{
+			ID:            scheduled.ID,
+			Status:        StatusActive,
+			NextRunAt:     scheduled.NextRunAt,
+			NextAttemptAt: now.Add(scheduler.backoff(attempts)),
+			Attempts:      attempts,
+		}
+	}
+
+	next := db.UpdateScheduledTransferScheduleParams{
+		ID:            scheduled.ID,
+		Status:        StatusCompleted,
+		NextRunAt:     scheduled.NextRunAt,
+		NextAttemptAt: scheduled.NextRunAt,
+	}
+	if scheduled.Recurrence == "" {
+		if !succeeded {
+			next.Status = StatusFailed
+		}
+		return next
+	}
+
+	recurrence, err := ParseRecurrence(scheduled.Recurrence)
+	if err != nil {
+		// the recurrence is checked when the transfer is created, so this is not expected
+		log.Printf("invalid recurrence of scheduled transfer [%d]: %v", scheduled.ID, err)
+		next.Status = StatusFailed
+		return next
+	}
+
+	// the occurrences missed while the transfer was retried or not running are skipped
+	nextRunAt := recurrence.Next(scheduled.NextRunAt)
+	if !nextRunAt.IsZero() && !nextRunAt.After(now) {
+		nextRunAt = recurrence.Next(now)
+	}
+	if nextRunAt.IsZero() || (scheduled.EndAt.Valid && nextRunAt.After(scheduled.EndAt.Time)) {
+		return next
+	}
+
+	next.Status = StatusActive
+	next.NextRunAt = nextRunAt
+	next.NextAttemptAt = nextRunAt
+	return next
+}
+
+// backoff returns the delay before the next attempt after the input number of failed attempts
+func (scheduler *Scheduler) backoff(attempts int32) time.Duration {
+	delay := scheduler.config.RetryBackoff
+	for i := int32(1); i < attempts && delay < maxRetryBackoff; i++ {
+		delay *= 2
+	}
+	if delay > maxRetryBackoff {
+		delay = maxRetryBackoff
+	}
+	return delay
+}
+
+// FirstRun returns the first occurrence of a transfer with the input recurrence starting at start:
+// start itself for a one-off transfer or an interval, the first matching minute from start for a cron expression
+func FirstRun(recurrence string, start time.Time) (time.Time, error) {
+	if recurrence == "" {
+		return start, nil
+	}
+
+	parsed, err := ParseRecurrence(recurrence)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if _, ok := parsed.(every); ok {
+		return start, nil
+	}
+	// Next returns an occurrence strictly after its input
+	return parsed.Next(start.Add(-time.Nanosecond)), nil
+}
@@ -0,0 +1,229 @@
+package scheduler
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	mockdb "db.sqlc.dev/app/db/mock"
+	db "db.sqlc.dev/app/db/sqlc"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+// executorFunc adapts a function to the Executor interface
+type executorFunc func(ctx context.Context, scheduled db.ScheduledTransfer) (db.TransferTxResult, error)
+
+func (fn executorFunc) ExecuteScheduledTransfer(ctx context.Context, scheduled db.ScheduledTransfer) (db.TransferTxResult, error) {
+	return fn(ctx, scheduled)
+}
+
+func succeed(transferID int64) Executor {
+	return executorFunc(func(ctx context.Context, scheduled db.ScheduledTransfer) (db.TransferTxResult, error) {
+		return db.TransferTxResult{Transfer: db.Transfer{ID: transferID}}, nil
+	})
+}
+
+func fail(err error) Executor {
+	return executorFunc(func(ctx context.Context, scheduled db.ScheduledTransfer) (db.TransferTxResult, error) {
+		return db.TransferTxResult{}, err
+	})
+}
+
+func TestRunDue(t *testing.T) {
+	now := time.Date(2023, time.March, 15, 9, 0, 30, 0, time.UTC)
+	runAt := time.Date(2023, time.March, 15, 9, 0, 0, 0, time.UTC)
+	errTransfer := errors.New("insufficient funds")
+
+	testCases := []struct {
+		name      string
+		scheduled db.ScheduledTransfer
+		executor  Executor
+		// run: failed run recorded by the scheduler, the succeeded runs are recorded by the executor
+		run  *db.CreateScheduledTransferRunParams
+		next db.UpdateScheduledTransferScheduleParams
+	}{
+		{
+			name:      "RecurringSucceeded",
+			scheduled: db.ScheduledTransfer{ID: 1, Recurrence: "0 9 * * *", NextRunAt: runAt},
+			executor:  succeed(10),
+			next: db.UpdateScheduledTransferScheduleParams{
+				ID: 1, Status: StatusActive, NextRunAt: runAt.Add(24 * time.Hour), NextAttemptAt: runAt.Add(24 * time.Hour),
+			},
+		},
+		{
+			name:      "OneOffSucceeded",
+			scheduled: db.ScheduledTransfer{ID: 2, NextRunAt: runAt},
+			executor:  succeed(11),
+			next: db.UpdateScheduledTransferScheduleParams{
+				ID: 2, Status: StatusCompleted, NextRunAt: runAt, NextAttemptAt: runAt,
+			},
+		},
+		{
+			name: "LastOccurrence",
+			scheduled: db.ScheduledTransfer{
+				ID: 3, Recurrence: "@every 24h", NextRunAt: runAt,
+				EndAt: sql.NullTime{Time: runAt.Add(time.Hour), Valid: true},
+			},
+			executor: succeed(12),
+			next: db.UpdateScheduledTransferScheduleParams{
+				ID: 3, Status: StatusCompleted, NextRunAt: runAt, NextAttemptAt: runAt,
+			},
+		},
+		{
+			name:      "MissedOccurrencesSkipped",
+			scheduled: db.ScheduledTransfer{ID: 4, Recurrence: "@hourly", NextRunAt: runAt.Add(-5 * time.Hour)},
+			executor:  succeed(13),
+			next: db.UpdateScheduledTransferScheduleParams{
+				ID: 4, Status: StatusActive, NextRunAt: runAt.Add(time.Hour), NextAttemptAt: runAt.Add(time.Hour),
+			},
+		},
+		{
+			name:      "OccurrenceExecuted",
+			scheduled: db.ScheduledTransfer{ID: 8, Recurrence: "0 9 * * *", NextRunAt: runAt, Attempts: 1},
+			// a run repeated after the transfer of the occurrence has been made
+			executor: fail(fmt.Errorf("%w: scheduled transfer [8]", db.ErrOccurrenceExecuted)),
+			next: db.UpdateScheduledTransferScheduleParams{
+				ID: 8, Status: StatusActive, NextRunAt: runAt.Add(24 * time.Hour), NextAttemptAt: runAt.Add(24 * time.Hour),
+			},
+		},
+		{
+			name:      "FailedRetried",
+			scheduled: db.ScheduledTransfer{ID: 5, Recurrence: "0 9 * * *", NextRunAt: runAt, Attempts: 2},
+			executor:  fail(errTransfer),
+			run: &db.CreateScheduledTransferRunParams{
+				ScheduledTransferID: 5, RunAt: runAt, Attempt: 3, Status: RunFailed, Error: errTransfer.Error(),
+			},
+			next: db.UpdateScheduledTransferScheduleParams{
+				// backoff of the third failed attempt: 4 * 1m
+				ID: 5, Status: StatusActive, NextRunAt: runAt, NextAttemptAt: now.Add(4 * time.Minute), Attempts: 3,
+			},
+		},
+		{
+			name:      "RecurringAttemptsExhausted",
+			scheduled: db.ScheduledTransfer{ID: 6, Recurrence: "0 9 * * *", NextRunAt: runAt, Attempts: 4},
+			executor:  fail(errTransfer),
+			run: &db.CreateScheduledTransferRunParams{
+				ScheduledTransferID: 6, RunAt: runAt, Attempt: 5, Status: RunFailed, Error: errTransfer.Error(),
+			},
+			next: db.UpdateScheduledTransferScheduleParams{
+				ID: 6, Status: StatusActive, NextRunAt: runAt.Add(24 * time.Hour), NextAttemptAt: runAt.Add(24 * time.Hour),
+			},
+		},
+		{
+			name:      "OneOffAttemptsExhausted",
+			scheduled: db.ScheduledTransfer{ID: 7, NextRunAt: runAt, Attempts: 4},
+			executor:  fail(errTransfer),
+			run: &db.CreateScheduledTransferRunParams{
+				ScheduledTransferID: 7, RunAt: runAt, Attempt: 5, Status: RunFailed, Error: errTransfer.Error(),
+			},
+			next: db.UpdateScheduledTransferScheduleParams{
+				ID: 7, Status: StatusFailed, NextRunAt: runAt, NextAttemptAt: runAt, Attempts: 0,
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			// the schedule is updated if the transfer is still claimed until the end of the lease
+			leaseUntil := now.Add(defaultLease)
+			tc.scheduled.NextAttemptAt = leaseUntil
+			tc.next.ClaimedUntil = leaseUntil
+
+			store := mockdb.NewMockStore(ctrl)
+			store.EXPECT().
+				ClaimDueScheduledTransfers(gomock.Any(), gomock.Eq(db.ClaimDueScheduledTransfersParams{
+					LeaseUntil: leaseUntil,
+					MaxCount:   defaultBatchSize,
+				})).
+				Times(1).
+				Return([]db.ScheduledTransfer{tc.scheduled}, nil)
+			if tc.run != nil {
+				store.EXPECT().
+					CreateScheduledTransferRun(gomock.Any(), gomock.Eq(*tc.run)).
+					Times(1)
+			} else {
+				store.EXPECT().CreateScheduledTransferRun(gomock.Any(), gomock.Any()).Times(0)
+			}
+			store.EXPECT().
+				UpdateScheduledTransferSchedule(gomock.Any(), gomock.Eq(tc.next)).
+				Times(1)
+
+			scheduler := New(store, tc.executor, Config{})
+			scheduler.now = func() time.Time { return now }
+
+			n, err := scheduler.RunDue(context.Background())
+			require.NoError(t, err)
+			require.Equal(t, 1, n)
+		})
+	}
+}
+
+func TestRunDueClaimError(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().
+		ClaimDueScheduledTransfers(gomock.Any(), gomock.Any()).
+		Times(1).
+		Return(nil, sql.ErrConnDone)
+	store.EXPECT().CreateScheduledTransferRun(gomock.Any(), gomock.Any()).Times(0)
+
+	scheduler := New(store, succeed(1), Config{})
+	n, err := scheduler.RunDue(context.Background())
+	require.ErrorIs(t, err, sql.ErrConnDone)
+	require.Zero(t, n)
+}
+
+func TestRunDueStoreError(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	now := time.Now()
+	leaseUntil := now.Add(defaultLease)
+	claimed := []db.ScheduledTransfer{
+		{ID: 1, NextRunAt: now, NextAttemptAt: leaseUntil},
+		{ID: 2, NextRunAt: now, NextAttemptAt: leaseUntil},
+		{ID: 3, NextRunAt: now, NextAttemptAt: leaseUntil},
+	}
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().ClaimDueScheduledTransfers(gomock.Any(), gomock.Any()).Times(1).Return(claimed, nil)
+	store.EXPECT().
+		UpdateScheduledTransferSchedule(gomock.Any(), gomock.Any()).
+		Times(3).
+		DoAndReturn(func(ctx context.Context, arg db.UpdateScheduledTransferScheduleParams) (db.ScheduledTransfer, error) {
+			switch arg.ID {
+			case 1:
+				// the outcome of the run can't be stored, the transfer stays leased and runs again after the lease
+				return db.ScheduledTransfer{}, sql.ErrConnDone
+			case 2:
+				// the transfer has been paused during its run
+				return db.ScheduledTransfer{}, sql.ErrNoRows
+			}
+			return db.ScheduledTransfer{ID: arg.ID, Status: arg.Status}, nil
+		})
+
+	// the other transfers of the batch still run
+	scheduler := New(store, succeed(10), Config{})
+	scheduler.now = func() time.Time { return now }
+	n, err := scheduler.RunDue(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, 2, n)
+}
+
+func TestBackoff(t *testing.T) {
+	scheduler := New(nil, nil, Config{RetryBackoff: time.Minute})
+
+	require.Equal(t, time.Minute, scheduler.backoff(1))
+	require.Equal(t, 2*time.Minute, scheduler.backoff(2))
+	require.Equal(t, 8*time.Minute, scheduler.backoff(4))
+	require.Equal(t, maxRetryBackoff, scheduler.backoff(100))
+}
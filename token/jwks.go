@@ -0,0 +1,95 @@
+package token
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"sort"
+	"time"
+)
+
+// JSONWebKey is the JSON web key (RFC 7517) of an Ed25519 public key (RFC 8037)
+type JSONWebKey struct {
+	KeyType string `json:"kty"`
+	Curve   string `json:"crv"`
+	// X: base64url encoding of the public key
+	X     string `json:"x"`
+	KeyID string `json:"kid,omitempty"`
+	// Algorithm is only set for the keys of JSON web tokens, Paseto tokens have no JOSE algorithm
+	Algorithm string `json:"alg,omitempty"`
+	Use       string `json:"use"`
+}
+
+// JSONWebKeySet is the document published on /.well-known/jwks.json
+type JSONWebKeySet struct {
+	Keys []JSONWebKey `json:"keys"`
+}
+
+// PublicKeyMaker is implemented by the makers of tokens signed with asymmetric keys,
+// whose public keys can be published to let other services verify the tokens
+type PublicKeyMaker interface {
+	Maker
+	// PublicKeys returns the keys that currently verify the tokens of the maker
+	PublicKeys() []JSONWebKey
+}
+
+// newEd25519JSONWebKey creates the JSON web key of an Ed25519 public key
+func newEd25519JSONWebKey(publicKey ed25519.PublicKey, keyID string, algorithm string) JSONWebKey {
+	return JSONWebKey{
+		KeyType:   "OKP",
+		Curve:     "Ed25519",
+		X:         base64.RawURLEncoding.EncodeToString(publicKey),
+		KeyID:     keyID,
+		Algorithm: algorithm,
+		Use:       "sig",
+	}
+}
+
+// Ed25519PublicKey decodes the public key of a JSON web key
+func (key JSONWebKey) Ed25519PublicKey() (ed25519.PublicKey, error) {
+	if key.KeyType != "OKP" || key.Curve != "Ed25519" {
+		return nil, ErrInvalidKey
+	}
+
+	publicKey, err := base64.RawURLEncoding.DecodeString(key.X)
+	if err != nil || len(publicKey) != ed25519.PublicKeySize {
+		return nil, ErrInvalidKey
+	}
+	return publicKey, nil
+}
+
+func (maker *PasetoPublicMaker) PublicKeys() []JSONWebKey {
+	return []JSONWebKey{newEd25519JSONWebKey(maker.publicKey, maker.keyID, "")}
+}
+
+func (maker *JWTEdDSAMaker) PublicKeys() []JSONWebKey {
+	return []JSONWebKey{newEd25519JSONWebKey(maker.publicKey, maker.keyID, signingMethodEdDSA.Alg())}
+}
+
+// PublicKeys returns the public keys of the keyring that are not retired, starting with the active key.
+// Keyrings of symmetric keys have no public keys
+func (keyring *Keyring) PublicKeys() []JSONWebKey {
+	ids := make([]string, 0, len(keyring.keys))
+	for id := range keyring.keys {
+		if id != keyring.activeKeyID {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	ids = append([]string{keyring.activeKeyID}, ids...)
+
+	now := time.Now()
+	keys := []JSONWebKey{}
+	for _, id := range ids {
+		key := keyring.keys[id]
+		if !key.retiredAt.IsZero() && !now.Before(key.retiredAt) {
+			continue
+		}
+
+		maker, ok := key.maker.(PublicKeyMaker)
+		if !ok {
+			continue
+		}
+		keys = append(keys, maker.PublicKeys()...)
+	}
+	return keys
+}
@@ -0,0 +1,79 @@
+package token
+
+import (
+	"testing"
+	"time"
+
+	"db.sqlc.dev/app/util"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKeyringPublicKeys(t *testing.T) {
+	activeKey := newTestKeyringKey(t, TypePasetoPublic, "active")
+	oldKey := newTestKeyringKey(t, TypePasetoPublic, "old")
+	oldKey.PrivateKey = nil
+	oldKey.RetiredAt = time.Now().Add(time.Hour)
+	retiredKey := newTestKeyringKey(t, TypePasetoPublic, "retired")
+	retiredKey.RetiredAt = time.Now()
+
+	keyring, err := NewKeyring(TypePasetoPublic, []KeyringKey{oldKey, retiredKey, activeKey}, "active")
+	require.NoError(t, err)
+
+	// the retired key is not published, the active key comes first
+	keys := keyring.PublicKeys()
+	require.Len(t, keys, 2)
+	require.Equal(t, "active", keys[0].KeyID)
+	require.Equal(t, "old", keys[1].KeyID)
+
+	for i, key := range []KeyringKey{activeKey, oldKey} {
+		require.Equal(t, "OKP", keys[i].KeyType)
+		require.Equal(t, "Ed25519", keys[i].Curve)
+		require.Equal(t, "sig", keys[i].Use)
+		require.Empty(t, keys[i].Algorithm)
+
+		publicKey, err := keys[i].Ed25519PublicKey()
+		require.NoError(t, err)
+		require.True(t, key.PublicKey.Equal(publicKey))
+	}
+
+	// symmetric keys are never published
+	keyring, err = NewKeyring(TypeJWT, []KeyringKey{newTestKeyringKey(t, TypeJWT, "key")}, "key")
+	require.NoError(t, err)
+	require.Empty(t, keyring.PublicKeys())
+}
+
+func TestJWTEdDSAMakerPublicKeys(t *testing.T) {
+	privateKey, publicKey := newEd25519KeyPair(t)
+	maker, err := NewJWTEdDSAMaker(privateKey, publicKey)
+	require.NoError(t, err)
+
+	keys := maker.(PublicKeyMaker).PublicKeys()
+	require.Len(t, keys, 1)
+	require.Empty(t, keys[0].KeyID)
+	require.Equal(t, "EdDSA", keys[0].Algorithm)
+
+	// tokens of the maker are verified with the published key
+	decoded, err := keys[0].Ed25519PublicKey()
+	require.NoError(t, err)
+	verifier, err := NewJWTEdDSAMaker(nil, decoded)
+	require.NoError(t, err)
+
+	token, _, err := maker.CreateToken(util.RandomOwner(), util.DepositorRole, TokenTypeAccess, time.Minute)
+	require.NoError(t, err)
+	_, err = verifier.VerifyToken(token)
+	require.NoError(t, err)
+}
+
+func TestInvalidJSONWebKey(t *testing.T) {
+	_, publicKey := newEd25519KeyPair(t)
+	key := newEd25519JSONWebKey(publicKey, "key", "")
+
+	key.KeyType = "RSA"
+	_, err := key.Ed25519PublicKey()
+	require.EqualError(t, err, ErrInvalidKey.Error())
+
+	key = newEd25519JSONWebKey(publicKey, "key", "")
+	key.X = key.X[:10]
+	_, err = key.Ed25519PublicKey()
+	require.EqualError(t, err, ErrInvalidKey.Error())
+}
@@ -0,0 +1,74 @@
+package token
+
+import (
+	"crypto/ed25519"
+	"errors"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+// JWTEdDSAMaker: struct of JSON web token maker, which implements the token.Maker interface(./token/maker.go)
+// Use asymmetric EdDSA algorithm: tokens are signed with the private key and verified with the public key
+type JWTEdDSAMaker struct {
+	privateKey ed25519.PrivateKey // nil if the maker can only verify tokens
+	publicKey  ed25519.PublicKey
+	keyID      string // ID of the key stored in the "kid" header, empty outside of a Keyring
+}
+
+func (maker *JWTEdDSAMaker) CreateToken(username string, role string, tokenType TokenType, duration time.Duration) (string, *Payload, error) {
+	if maker.privateKey == nil {
+		return "", nil, ErrVerifyOnlyMaker
+	}
+
+	payload, err := NewPayload(username, role, tokenType, duration)
+	if err != nil {
+		return "", payload, err
+	}
+
+	jwtToken := jwt.NewWithClaims(signingMethodEdDSA, payload)
+	setJWTKeyID(jwtToken, maker.keyID)
+	token, err := jwtToken.SignedString(maker.privateKey)
+	return token, payload, err
+}
+
+func (maker *JWTEdDSAMaker) VerifyToken(token string) (*Payload, error) {
+	keyFunc := func(token *jwt.Token) (interface{}, error) {
+		// only accept tokens signed with EdDSA, to prevent algorithm substitution attacks
+		_, ok := token.Method.(*SigningMethodEdDSA)
+		if !ok {
+			return nil, ErrInvalidToken
+		}
+		return maker.publicKey, nil
+	}
+
+	jwtToken, err := jwt.ParseWithClaims(token, &Payload{}, keyFunc)
+	if err != nil {
+		verr, ok := err.(*jwt.ValidationError)
+		if ok && errors.Is(verr.Inner, ErrExpiredToken) {
+			return nil, ErrExpiredToken
+		}
+		return nil, ErrInvalidToken
+	}
+
+	payload, ok := jwtToken.Claims.(*Payload)
+	if !ok {
+		return nil, ErrInvalidToken
+	}
+
+	return payload, nil
+}
+
+// NewJWTEdDSAMaker creates a JWT maker with an Ed25519 key pair.
+// The private key is optional: without it, the maker can only verify tokens
+func NewJWTEdDSAMaker(privateKey ed25519.PrivateKey, publicKey ed25519.PublicKey) (Maker, error) {
+	return newJWTEdDSAMaker(privateKey, publicKey, "")
+}
+
+func newJWTEdDSAMaker(privateKey ed25519.PrivateKey, publicKey ed25519.PublicKey, keyID string) (*JWTEdDSAMaker, error) {
+	if err := checkEd25519KeyPair(privateKey, publicKey); err != nil {
+		return nil, err
+	}
+
+	return &JWTEdDSAMaker{privateKey: privateKey, publicKey: publicKey, keyID: keyID}, nil
+}
@@ -0,0 +1,96 @@
+package token
+
+import (
+	"testing"
+	"time"
+
+	"db.sqlc.dev/app/util"
+	"github.com/dgrijalva/jwt-go"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJWTEdDSAMaker(t *testing.T) {
+	privateKey, publicKey := newEd25519KeyPair(t)
+	maker, err := NewJWTEdDSAMaker(privateKey, publicKey)
+	require.NoError(t, err)
+
+	username := util.RandomOwner()
+	role := util.BankerRole
+	duration := time.Minute
+
+	issuedAt := time.Now()
+	expiredAt := issuedAt.Add(duration)
+
+	token, payload, err := maker.CreateToken(username, role, TokenTypeAccess, duration)
+	require.NoError(t, err)
+	require.NotEmpty(t, token)
+	require.NotEmpty(t, payload)
+
+	// the token can be verified with the public key only
+	verifier, err := NewJWTEdDSAMaker(nil, publicKey)
+	require.NoError(t, err)
+
+	payload, err = verifier.VerifyToken(token)
+	require.NoError(t, err)
+
+	require.NotZero(t, payload.ID)
+	require.Equal(t, username, payload.Username)
+	require.Equal(t, role, payload.Role)
+	require.Equal(t, TokenTypeAccess, payload.Type)
+	require.WithinDuration(t, issuedAt, payload.IssuedAt, time.Second)
+	require.WithinDuration(t, expiredAt, payload.ExpiredAt, time.Second)
+
+	_, _, err = verifier.CreateToken(username, role, TokenTypeAccess, duration)
+	require.EqualError(t, err, ErrVerifyOnlyMaker.Error())
+}
+
+func TestExpiredJWTEdDSAToken(t *testing.T) {
+	privateKey, publicKey := newEd25519KeyPair(t)
+	maker, err := NewJWTEdDSAMaker(privateKey, publicKey)
+	require.NoError(t, err)
+
+	token, payload, err := maker.CreateToken(util.RandomOwner(), util.DepositorRole, TokenTypeAccess, -time.Minute)
+	require.NoError(t, err)
+	require.NotEmpty(t, token)
+	require.NotEmpty(t, payload)
+
+	payload, err = maker.VerifyToken(token)
+	require.Error(t, err)
+	require.EqualError(t, err, ErrExpiredToken.Error())
+	require.Nil(t, payload)
+}
+
+func TestInvalidJWTEdDSATokenWrongKey(t *testing.T) {
+	privateKey, publicKey := newEd25519KeyPair(t)
+	maker, err := NewJWTEdDSAMaker(privateKey, publicKey)
+	require.NoError(t, err)
+
+	token, _, err := maker.CreateToken(util.RandomOwner(), util.DepositorRole, TokenTypeAccess, time.Minute)
+	require.NoError(t, err)
+
+	_, otherPublicKey := newEd25519KeyPair(t)
+	otherMaker, err := NewJWTEdDSAMaker(nil, otherPublicKey)
+	require.NoError(t, err)
+
+	payload, err := otherMaker.VerifyToken(token)
+	require.EqualError(t, err, ErrInvalidToken.Error())
+	require.Nil(t, payload)
+}
+
+// a token signed with HS256 must be rejected, even if the public key is used as the secret
+func TestInvalidJWTEdDSATokenAlgHS256(t *testing.T) {
+	privateKey, publicKey := newEd25519KeyPair(t)
+	maker, err := NewJWTEdDSAMaker(privateKey, publicKey)
+	require.NoError(t, err)
+
+	payload, err := NewPayload(util.RandomOwner(), util.DepositorRole, TokenTypeAccess, time.Minute)
+	require.NoError(t, err)
+
+	jwtToken := jwt.NewWithClaims(jwt.SigningMethodHS256, payload)
+	token, err := jwtToken.SignedString([]byte(publicKey))
+	require.NoError(t, err)
+
+	payload, err = maker.VerifyToken(token)
+	require.EqualError(t, err, ErrInvalidToken.Error())
+	require.Nil(t, payload)
+}
@@ -12,24 +12,27 @@ import (
 // Use symmetric key algorithm to sign the tokens
 type JWTMaker struct {
 	secretKey string
+	keyID     string // ID of the key stored in the "kid" header, empty outside of a Keyring
 }
 
 // Define CreateToken & VerifyToken method for *JWTMaker to implement Maker interface
-func (maker *JWTMaker) CreateToken(username string, duration time.Duration) (string, error) {
+func (maker *JWTMaker) CreateToken(username string, role string, tokenType TokenType, duration time.Duration) (string, *Payload, error) {
 	// create a new token payload:
-	payload, err := NewPayload(username, duration)
+	payload, err := NewPayload(username, role, tokenType, duration)
 	if err != nil {
-		return "", err
+		return "", payload, err
 	}
 
 	// create a new jwtToken by calling the jwt.NewWithClaims() function of the jwt-go package
 	// jwt.SigningMethodHS256: the signing method(algorithm)
 	// payload: the claims which implement jwt.Claims interface
 	jwtToken := jwt.NewWithClaims(jwt.SigningMethodHS256, payload)
+	setJWTKeyID(jwtToken, maker.keyID)
 
 	// generate a token string bycall jwtToken.SignedString()
 	// pass in the secretKey after converting it to []byte
-	return jwtToken.SignedString([]byte(maker.secretKey))
+	token, err := jwtToken.SignedString([]byte(maker.secretKey))
+	return token, payload, err
 }
 
 // VerifyToken: verify the tocken and return the payload data
@@ -73,9 +76,13 @@ func (maker *JWTMaker) VerifyToken(token string) (*Payload, error) {
 const minSecretKeySize = 32
 
 func NewJWTMaker(secretKey string) (Maker, error) {
+	return newJWTMaker(secretKey, "")
+}
+
+func newJWTMaker(secretKey string, keyID string) (*JWTMaker, error) {
 	if len(secretKey) < minSecretKeySize {
 		return nil, fmt.Errorf("invalid key size: must be at least %d characters", minSecretKeySize)
 	}
 
-	return &JWTMaker{secretKey}, nil
+	return &JWTMaker{secretKey: secretKey, keyID: keyID}, nil
 }
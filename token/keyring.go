@@ -0,0 +1,244 @@
+package token
+
+import (
+	"crypto/ed25519"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/o1egl/paseto"
+)
+
+// tokenFooter is the JSON footer of the Paseto tokens issued by a Keyring
+type tokenFooter struct {
+	KeyID string `json:"kid"`
+}
+
+// encodeTokenFooter returns the Paseto footer storing the key ID, or nil if the key has no ID
+func encodeTokenFooter(keyID string) []byte {
+	if keyID == "" {
+		return nil
+	}
+
+	// marshaling a struct of a single string cannot fail
+	footer, _ := json.Marshal(tokenFooter{KeyID: keyID})
+	return footer
+}
+
+// setJWTKeyID stores the key ID in the "kid" header of the JSON web token if the key has an ID
+func setJWTKeyID(jwtToken *jwt.Token, keyID string) {
+	if keyID != "" {
+		jwtToken.Header["kid"] = keyID
+	}
+}
+
+// KeyID returns the ID of the key that signed the token, without verifying the token.
+// Tokens issued without a key ID return an empty ID
+func KeyID(token string) (string, error) {
+	// Paseto tokens start with their version and purpose, e.g. "v2.local."
+	if strings.HasPrefix(token, "v2.local.") || strings.HasPrefix(token, pasetoV4PublicHeader) {
+		var footer []byte
+		if err := paseto.ParseFooter(token, &footer); err != nil {
+			return "", ErrInvalidToken
+		}
+		if len(footer) == 0 {
+			return "", nil
+		}
+
+		var f tokenFooter
+		if err := json.Unmarshal(footer, &f); err != nil {
+			return "", ErrInvalidToken
+		}
+		return f.KeyID, nil
+	}
+
+	jwtToken, _, err := new(jwt.Parser).ParseUnverified(token, &Payload{})
+	if err != nil {
+		return "", ErrInvalidToken
+	}
+
+	keyID, ok := jwtToken.Header["kid"]
+	if !ok {
+		return "", nil
+	}
+	id, ok := keyID.(string)
+	if !ok {
+		return "", ErrInvalidToken
+	}
+	return id, nil
+}
+
+// KeyringKey is a signing key of a Keyring.
+// Symmetric token types use SymmetricKey, asymmetric token types use the Ed25519 key pair
+type KeyringKey struct {
+	// ID is stored in every token signed with the key.
+	// A key with an empty ID verifies the tokens issued before key IDs were introduced and can't be active
+	ID           string
+	SymmetricKey string
+	// PrivateKey is only required for the active key
+	PrivateKey ed25519.PrivateKey
+	PublicKey  ed25519.PublicKey
+	// RetiredAt: time from which the tokens signed with the key are rejected, zero if the key never retires
+	RetiredAt time.Time
+}
+
+// keyringEntry is a key of the keyring with the maker that verifies its tokens
+type keyringEntry struct {
+	maker     Maker
+	retiredAt time.Time
+}
+
+// Keyring: token maker with several keys, which implements the token.Maker interface(./token/maker.go)
+// New tokens are signed with the active key and carry its ID (Paseto footer / JWT "kid" header),
+// so that the tokens signed with the older keys remain valid until their retirement time
+type Keyring struct {
+	activeKeyID string
+	keys        map[string]keyringEntry
+}
+
+func (keyring *Keyring) CreateToken(username string, role string, tokenType TokenType, duration time.Duration) (string, *Payload, error) {
+	return keyring.keys[keyring.activeKeyID].maker.CreateToken(username, role, tokenType, duration)
+}
+
+func (keyring *Keyring) VerifyToken(token string) (*Payload, error) {
+	keyID, err := KeyID(token)
+	if err != nil {
+		return nil, err
+	}
+
+	key, ok := keyring.keys[keyID]
+	if !ok {
+		return nil, ErrInvalidToken
+	}
+	// tokens signed with a retired key are rejected, even if they haven't expired yet
+	if !key.retiredAt.IsZero() && !time.Now().Before(key.retiredAt) {
+		return nil, ErrInvalidToken
+	}
+
+	return key.maker.VerifyToken(token)
+}
+
+// ActiveKeyID returns the ID of the key that signs the new tokens
+func (keyring *Keyring) ActiveKeyID() string {
+	return keyring.activeKeyID
+}
+
+// NewKeyring creates a keyring of the input token type (see TypePaseto, TypeJWT, ...),
+// which signs the new tokens with the key of ID activeKeyID
+func NewKeyring(tokenType string, keys []KeyringKey, activeKeyID string) (*Keyring, error) {
+	if activeKeyID == "" {
+		return nil, errors.New("the active key must have an ID")
+	}
+
+	keyring := &Keyring{
+		activeKeyID: activeKeyID,
+		keys:        make(map[string]keyringEntry, len(keys)),
+	}
+
+	for _, key := range keys {
+		if _, ok := keyring.keys[key.ID]; ok {
+			return nil, fmt.Errorf("duplicate key ID %q", key.ID)
+		}
+		if key.ID == activeKeyID && isAsymmetricType(tokenType) && key.PrivateKey == nil {
+			return nil, fmt.Errorf("active key %q has no private key", activeKeyID)
+		}
+
+		maker, err := newKeyringMaker(tokenType, key)
+		if err != nil {
+			return nil, fmt.Errorf("invalid key %q: %w", key.ID, err)
+		}
+		keyring.keys[key.ID] = keyringEntry{maker: maker, retiredAt: key.RetiredAt}
+	}
+
+	active, ok := keyring.keys[activeKeyID]
+	if !ok {
+		return nil, fmt.Errorf("active key %q not found", activeKeyID)
+	}
+	if !active.retiredAt.IsZero() {
+		return nil, fmt.Errorf("active key %q can't have a retirement time", activeKeyID)
+	}
+	return keyring, nil
+}
+
+// newKeyringMaker creates the maker of a single key of the keyring
+func newKeyringMaker(tokenType string, key KeyringKey) (Maker, error) {
+	switch tokenType {
+	case TypePaseto, "":
+		return newPasetoMaker(key.SymmetricKey, key.ID)
+	case TypeJWT:
+		return newJWTMaker(key.SymmetricKey, key.ID)
+	case TypePasetoPublic:
+		return newPasetoPublicMaker(key.PrivateKey, key.PublicKey, key.ID)
+	case TypeJWTEdDSA:
+		return newJWTEdDSAMaker(key.PrivateKey, key.PublicKey, key.ID)
+	}
+	return nil, fmt.Errorf("unsupported token type %s", tokenType)
+}
+
+// isAsymmetricType reports whether tokens of the type are signed with an Ed25519 key pair
+func isAsymmetricType(tokenType string) bool {
+	return tokenType == TypePasetoPublic || tokenType == TypeJWTEdDSA
+}
+
+// keyringFile is the JSON format of the keyring file, e.g.
+//
+//	{
+//	  "active_key_id": "2023-02",
+//	  "keys": [
+//	    {"id": "2023-02", "symmetric_key": "..."},
+//	    {"id": "2022-11", "symmetric_key": "...", "retired_at": "2023-03-01T00:00:00Z"}
+//	  ]
+//	}
+//
+// Asymmetric keys set private_key_path and public_key_path instead of symmetric_key
+type keyringFile struct {
+	ActiveKeyID string `json:"active_key_id"`
+	Keys        []struct {
+		ID             string    `json:"id"`
+		SymmetricKey   string    `json:"symmetric_key"`
+		PrivateKeyPath string    `json:"private_key_path"`
+		PublicKeyPath  string    `json:"public_key_path"`
+		RetiredAt      time.Time `json:"retired_at"`
+	} `json:"keys"`
+}
+
+// LoadKeyring reads the keys of a keyring of the input token type from a JSON file.
+// The Ed25519 keys are read from the PEM files referenced in the keyring file
+func LoadKeyring(tokenType string, path string) (*Keyring, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read keyring file: %w", err)
+	}
+
+	var file keyringFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("cannot parse keyring file %s: %w", path, err)
+	}
+
+	keys := make([]KeyringKey, len(file.Keys))
+	for i, k := range file.Keys {
+		keys[i] = KeyringKey{
+			ID:           k.ID,
+			SymmetricKey: k.SymmetricKey,
+			RetiredAt:    k.RetiredAt,
+		}
+
+		if !isAsymmetricType(tokenType) {
+			continue
+		}
+		if k.PrivateKeyPath != "" {
+			if keys[i].PrivateKey, err = LoadEd25519PrivateKey(k.PrivateKeyPath); err != nil {
+				return nil, err
+			}
+		}
+		if keys[i].PublicKey, err = LoadEd25519PublicKey(k.PublicKeyPath); err != nil {
+			return nil, err
+		}
+	}
+
+	return NewKeyring(tokenType, keys, file.ActiveKeyID)
+}
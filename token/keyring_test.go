@@ -0,0 +1,203 @@
+package token
+
+import (
+	"crypto/x509"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"db.sqlc.dev/app/util"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestKeyringKey creates a random key of the token type
+func newTestKeyringKey(t *testing.T, tokenType string, id string) KeyringKey {
+	key := KeyringKey{ID: id}
+	if isAsymmetricType(tokenType) {
+		key.PrivateKey, key.PublicKey = newEd25519KeyPair(t)
+	} else {
+		key.SymmetricKey = util.RandomString(32)
+	}
+	return key
+}
+
+func TestKeyringRotation(t *testing.T) {
+	for _, tokenType := range []string{TypePaseto, TypeJWT, TypePasetoPublic, TypeJWTEdDSA} {
+		tokenType := tokenType
+
+		t.Run(tokenType, func(t *testing.T) {
+			oldKey := newTestKeyringKey(t, tokenType, "old")
+			newKey := newTestKeyringKey(t, tokenType, "new")
+
+			oldKeyring, err := NewKeyring(tokenType, []KeyringKey{oldKey}, "old")
+			require.NoError(t, err)
+
+			username := util.RandomOwner()
+			oldToken, _, err := oldKeyring.CreateToken(username, util.DepositorRole, TokenTypeAccess, time.Minute)
+			require.NoError(t, err)
+
+			id, err := KeyID(oldToken)
+			require.NoError(t, err)
+			require.Equal(t, "old", id)
+
+			// rotate: the new key signs the tokens, the old key still verifies its tokens
+			oldKey.RetiredAt = time.Now().Add(time.Hour)
+			keyring, err := NewKeyring(tokenType, []KeyringKey{newKey, oldKey}, "new")
+			require.NoError(t, err)
+			require.Equal(t, "new", keyring.ActiveKeyID())
+
+			payload, err := keyring.VerifyToken(oldToken)
+			require.NoError(t, err)
+			require.Equal(t, username, payload.Username)
+
+			newToken, _, err := keyring.CreateToken(username, util.DepositorRole, TokenTypeAccess, time.Minute)
+			require.NoError(t, err)
+
+			id, err = KeyID(newToken)
+			require.NoError(t, err)
+			require.Equal(t, "new", id)
+
+			payload, err = keyring.VerifyToken(newToken)
+			require.NoError(t, err)
+			require.Equal(t, username, payload.Username)
+
+			// the old keyring doesn't know the new key
+			payload, err = oldKeyring.VerifyToken(newToken)
+			require.EqualError(t, err, ErrInvalidToken.Error())
+			require.Nil(t, payload)
+
+			// once the old key is retired, its tokens are rejected
+			oldKey.RetiredAt = time.Now()
+			keyring, err = NewKeyring(tokenType, []KeyringKey{newKey, oldKey}, "new")
+			require.NoError(t, err)
+
+			payload, err = keyring.VerifyToken(oldToken)
+			require.EqualError(t, err, ErrInvalidToken.Error())
+			require.Nil(t, payload)
+		})
+	}
+}
+
+func TestKeyringLegacyKey(t *testing.T) {
+	symmetricKey := util.RandomString(32)
+	maker, err := NewPasetoMaker(symmetricKey)
+	require.NoError(t, err)
+
+	token, _, err := maker.CreateToken(util.RandomOwner(), util.DepositorRole, TokenTypeAccess, time.Minute)
+	require.NoError(t, err)
+
+	// tokens issued without key ID are verified by the key with an empty ID
+	keyring, err := NewKeyring(TypePaseto, []KeyringKey{
+		newTestKeyringKey(t, TypePaseto, "new"),
+		{SymmetricKey: symmetricKey},
+	}, "new")
+	require.NoError(t, err)
+
+	_, err = keyring.VerifyToken(token)
+	require.NoError(t, err)
+
+	keyring, err = NewKeyring(TypePaseto, []KeyringKey{newTestKeyringKey(t, TypePaseto, "new")}, "new")
+	require.NoError(t, err)
+
+	_, err = keyring.VerifyToken(token)
+	require.EqualError(t, err, ErrInvalidToken.Error())
+}
+
+func TestKeyringExpiredToken(t *testing.T) {
+	keyring, err := NewKeyring(TypeJWT, []KeyringKey{newTestKeyringKey(t, TypeJWT, "key")}, "key")
+	require.NoError(t, err)
+
+	token, _, err := keyring.CreateToken(util.RandomOwner(), util.DepositorRole, TokenTypeAccess, -time.Minute)
+	require.NoError(t, err)
+
+	payload, err := keyring.VerifyToken(token)
+	require.EqualError(t, err, ErrExpiredToken.Error())
+	require.Nil(t, payload)
+}
+
+func TestNewKeyringInvalid(t *testing.T) {
+	key := newTestKeyringKey(t, TypePaseto, "key")
+	retiredKey := newTestKeyringKey(t, TypePaseto, "retired")
+	retiredKey.RetiredAt = time.Now().Add(time.Hour)
+	publicKey := newTestKeyringKey(t, TypePasetoPublic, "public")
+	publicKey.PrivateKey = nil
+
+	testCases := []struct {
+		name        string
+		tokenType   string
+		keys        []KeyringKey
+		activeKeyID string
+	}{
+		{name: "NoActiveKeyID", tokenType: TypePaseto, keys: []KeyringKey{key}},
+		{name: "ActiveKeyNotFound", tokenType: TypePaseto, keys: []KeyringKey{key}, activeKeyID: "missing"},
+		{name: "DuplicateKeyID", tokenType: TypePaseto, keys: []KeyringKey{key, key}, activeKeyID: "key"},
+		{name: "RetiredActiveKey", tokenType: TypePaseto, keys: []KeyringKey{retiredKey}, activeKeyID: "retired"},
+		{name: "ActiveKeyWithoutPrivateKey", tokenType: TypePasetoPublic, keys: []KeyringKey{publicKey}, activeKeyID: "public"},
+		{name: "InvalidKey", tokenType: TypePasetoPublic, keys: []KeyringKey{key}, activeKeyID: "key"},
+		{name: "UnsupportedTokenType", tokenType: "unknown", keys: []KeyringKey{key}, activeKeyID: "key"},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			keyring, err := NewKeyring(tc.tokenType, tc.keys, tc.activeKeyID)
+			require.Error(t, err)
+			require.Nil(t, keyring)
+		})
+	}
+}
+
+func TestLoadKeyring(t *testing.T) {
+	dir := t.TempDir()
+	writeKey := func(name string, der []byte, blockType string) string {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.Rename(writePEMFile(t, blockType, der), path))
+		return path
+	}
+
+	activeKey := newTestKeyringKey(t, TypeJWTEdDSA, "active")
+	oldKey := newTestKeyringKey(t, TypeJWTEdDSA, "old")
+
+	der, err := x509.MarshalPKCS8PrivateKey(activeKey.PrivateKey)
+	require.NoError(t, err)
+	privatePath := writeKey("active_private.pem", der, "PRIVATE KEY")
+	der, err = x509.MarshalPKIXPublicKey(activeKey.PublicKey)
+	require.NoError(t, err)
+	publicPath := writeKey("active_public.pem", der, "PUBLIC KEY")
+	der, err = x509.MarshalPKIXPublicKey(oldKey.PublicKey)
+	require.NoError(t, err)
+	oldPublicPath := writeKey("old_public.pem", der, "PUBLIC KEY")
+
+	keyringPath := filepath.Join(dir, "keyring.json")
+	data := fmt.Sprintf(`{
+		"active_key_id": "active",
+		"keys": [
+			{"id": "active", "private_key_path": %q, "public_key_path": %q},
+			{"id": "old", "public_key_path": %q, "retired_at": %q}
+		]
+	}`, privatePath, publicPath, oldPublicPath, time.Now().Add(time.Hour).Format(time.RFC3339))
+	require.NoError(t, os.WriteFile(keyringPath, []byte(data), 0600))
+
+	keyring, err := LoadKeyring(TypeJWTEdDSA, keyringPath)
+	require.NoError(t, err)
+
+	// a token of the old key, signed before the rotation
+	oldMaker, err := NewKeyring(TypeJWTEdDSA, []KeyringKey{oldKey}, "old")
+	require.NoError(t, err)
+	oldToken, _, err := oldMaker.CreateToken(util.RandomOwner(), util.DepositorRole, TokenTypeAccess, time.Minute)
+	require.NoError(t, err)
+
+	_, err = keyring.VerifyToken(oldToken)
+	require.NoError(t, err)
+
+	token, _, err := keyring.CreateToken(util.RandomOwner(), util.BankerRole, TokenTypeAccess, time.Minute)
+	require.NoError(t, err)
+	_, err = keyring.VerifyToken(token)
+	require.NoError(t, err)
+
+	_, err = LoadKeyring(TypeJWTEdDSA, filepath.Join(dir, "missing.json"))
+	require.Error(t, err)
+}
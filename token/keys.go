@@ -0,0 +1,88 @@
+package token
+
+import (
+	"crypto/ed25519"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+)
+
+var (
+	ErrInvalidKey      = errors.New("key is not a valid Ed25519 key")
+	ErrVerifyOnlyMaker = errors.New("token maker has no private key and can only verify tokens")
+)
+
+// LoadEd25519PrivateKey reads an Ed25519 private key from a PEM file in PKCS #8 format,
+// which can be generated with: openssl genpkey -algorithm ed25519 -out private.pem
+func LoadEd25519PrivateKey(path string) (ed25519.PrivateKey, error) {
+	block, err := readPEMBlock(path)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse private key %s: %w", path, err)
+	}
+
+	privateKey, ok := key.(ed25519.PrivateKey)
+	if !ok {
+		return nil, ErrInvalidKey
+	}
+	return privateKey, nil
+}
+
+// LoadEd25519PublicKey reads an Ed25519 public key from a PEM file in PKIX format,
+// which can be generated with: openssl pkey -in private.pem -pubout -out public.pem
+func LoadEd25519PublicKey(path string) (ed25519.PublicKey, error) {
+	block, err := readPEMBlock(path)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse public key %s: %w", path, err)
+	}
+
+	publicKey, ok := key.(ed25519.PublicKey)
+	if !ok {
+		return nil, ErrInvalidKey
+	}
+	return publicKey, nil
+}
+
+// readPEMBlock reads the first PEM block of a file
+func readPEMBlock(path string) (*pem.Block, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read key file: %w", err)
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM data found in key file %s", path)
+	}
+	return block, nil
+}
+
+// checkEd25519KeyPair checks the keys of an asymmetric token maker,
+// the private key is optional and must match the public key if it is provided
+func checkEd25519KeyPair(privateKey ed25519.PrivateKey, publicKey ed25519.PublicKey) error {
+	if len(publicKey) != ed25519.PublicKeySize {
+		return fmt.Errorf("invalid public key size: must be exactly %d bytes", ed25519.PublicKeySize)
+	}
+
+	if privateKey == nil {
+		return nil
+	}
+	if len(privateKey) != ed25519.PrivateKeySize {
+		return fmt.Errorf("invalid private key size: must be exactly %d bytes", ed25519.PrivateKeySize)
+	}
+	if !publicKey.Equal(privateKey.Public()) {
+		return errors.New("private key does not match the public key")
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+package token
+
+import (
+	"crypto/ed25519"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// newEd25519KeyPair generates a random Ed25519 key pair for tests
+func newEd25519KeyPair(t *testing.T) (ed25519.PrivateKey, ed25519.PublicKey) {
+	publicKey, privateKey, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+	return privateKey, publicKey
+}
+
+// writePEMFile writes a PEM block of the input type and bytes into a temporary file
+func writePEMFile(t *testing.T, blockType string, der []byte) string {
+	path := filepath.Join(t.TempDir(), "key.pem")
+	data := pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der})
+	require.NoError(t, os.WriteFile(path, data, 0600))
+	return path
+}
+
+func TestLoadEd25519Keys(t *testing.T) {
+	privateKey, publicKey := newEd25519KeyPair(t)
+
+	der, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	require.NoError(t, err)
+	loadedPrivateKey, err := LoadEd25519PrivateKey(writePEMFile(t, "PRIVATE KEY", der))
+	require.NoError(t, err)
+	require.True(t, privateKey.Equal(loadedPrivateKey))
+
+	der, err = x509.MarshalPKIXPublicKey(publicKey)
+	require.NoError(t, err)
+	loadedPublicKey, err := LoadEd25519PublicKey(writePEMFile(t, "PUBLIC KEY", der))
+	require.NoError(t, err)
+	require.True(t, publicKey.Equal(loadedPublicKey))
+}
+
+func TestLoadEd25519KeysInvalid(t *testing.T) {
+	// a public key is not a private key
+	_, publicKey := newEd25519KeyPair(t)
+	der, err := x509.MarshalPKIXPublicKey(publicKey)
+	require.NoError(t, err)
+	_, err = LoadEd25519PrivateKey(writePEMFile(t, "PUBLIC KEY", der))
+	require.Error(t, err)
+
+	// file without PEM data
+	path := filepath.Join(t.TempDir(), "key.pem")
+	require.NoError(t, os.WriteFile(path, []byte("not a key"), 0600))
+	_, err = LoadEd25519PublicKey(path)
+	require.Error(t, err)
+
+	// missing file
+	_, err = LoadEd25519PublicKey(filepath.Join(t.TempDir(), "missing.pem"))
+	require.Error(t, err)
+}
+
+func TestCheckEd25519KeyPair(t *testing.T) {
+	privateKey, publicKey := newEd25519KeyPair(t)
+	otherPrivateKey, _ := newEd25519KeyPair(t)
+
+	require.NoError(t, checkEd25519KeyPair(privateKey, publicKey))
+	require.NoError(t, checkEd25519KeyPair(nil, publicKey))
+	require.Error(t, checkEd25519KeyPair(otherPrivateKey, publicKey))
+	require.Error(t, checkEd25519KeyPair(privateKey, nil))
+}
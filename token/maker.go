@@ -4,10 +4,22 @@ import (
 	"time"
 )
 
+// Types of token makers, used to select the maker in the config
+const (
+	// TypePaseto: Paseto v2.local tokens, encrypted with a symmetric key
+	TypePaseto = "paseto"
+	// TypeJWT: JSON web tokens signed with HS256 and a symmetric key
+	TypeJWT = "jwt"
+	// TypePasetoPublic: Paseto v4.public tokens, signed with an Ed25519 private key
+	TypePasetoPublic = "paseto_public"
+	// TypeJWTEdDSA: JSON web tokens signed with EdDSA and an Ed25519 private key
+	TypeJWTEdDSA = "jwt_eddsa"
+)
+
 // Maker interface: manage the creation and verification of the tokens
 type Maker interface {
-	// CreateToken: returns a signed token string or an error
-	CreateToken(username string, duration time.Duration) (string, error)
+	// CreateToken: returns a signed token string and its payload, or an error
+	CreateToken(username string, role string, tokenType TokenType, duration time.Duration) (string, *Payload, error)
 	// VerifyToken: checks if input token is valid and return the payload data stored inside the body of the token
 	VerifyToken(token string) (*Payload, error)
 }
@@ -13,18 +13,20 @@ import (
 type PasetoMaker struct {
 	paseto       *paseto.V2
 	symmetricKey []byte // array of byte
+	keyID        string // ID of the key stored in the token footer, empty outside of a Keyring
 }
 
 // Define CreateToken & VerifyToken method for *PasetoMaker to implement Maker interface
-func (maker *PasetoMaker) CreateToken(username string, duration time.Duration) (string, error) {
+func (maker *PasetoMaker) CreateToken(username string, role string, tokenType TokenType, duration time.Duration) (string, *Payload, error) {
 	// create a new token payload:
-	payload, err := NewPayload(username, duration)
+	payload, err := NewPayload(username, role, tokenType, duration)
 	if err != nil {
-		return "", err
+		return "", payload, err
 	}
 
 	// generate encrypted token using paseto.Encrypt()
-	return maker.paseto.Encrypt(maker.symmetricKey, payload, nil)
+	token, err := maker.paseto.Encrypt(maker.symmetricKey, payload, encodeTokenFooter(maker.keyID))
+	return token, payload, err
 }
 
 func (maker *PasetoMaker) VerifyToken(token string) (*Payload, error) {
@@ -46,6 +48,10 @@ func (maker *PasetoMaker) VerifyToken(token string) (*Payload, error) {
 }
 
 func NewPasetoMaker(symmetricKey string) (Maker, error) {
+	return newPasetoMaker(symmetricKey, "")
+}
+
+func newPasetoMaker(symmetricKey string, keyID string) (*PasetoMaker, error) {
 	// Paseto version 2 uses Chacha20 Poly1305 algorithm to encrypt the payload
 	if len(symmetricKey) != chacha20poly1305.KeySize {
 		return nil, fmt.Errorf("invalid key size: must be exactly %d characters", chacha20poly1305.KeySize)
@@ -54,6 +60,7 @@ func NewPasetoMaker(symmetricKey string) (Maker, error) {
 	maker := &PasetoMaker{
 		paseto:       paseto.NewV2(),
 		symmetricKey: []byte(symmetricKey),
+		keyID:        keyID,
 	}
 
 	return maker, nil
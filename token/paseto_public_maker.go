@@ -0,0 +1,146 @@
+package token
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"strings"
+	"time"
+)
+
+// header of PASETO version 4 public tokens, which are signed with Ed25519
+const pasetoV4PublicHeader = "v4.public."
+
+// PasetoPublicMaker: struct of Paseto maker, which implements the token.Maker interface(./token/maker.go)
+// Use Paseto version 4 public purpose: tokens are signed with the private key and verified with the public key
+type PasetoPublicMaker struct {
+	privateKey ed25519.PrivateKey // nil if the maker can only verify tokens
+	publicKey  ed25519.PublicKey
+	keyID      string // ID of the key stored in the token footer, empty outside of a Keyring
+}
+
+func (maker *PasetoPublicMaker) CreateToken(username string, role string, tokenType TokenType, duration time.Duration) (string, *Payload, error) {
+	if maker.privateKey == nil {
+		return "", nil, ErrVerifyOnlyMaker
+	}
+
+	payload, err := NewPayload(username, role, tokenType, duration)
+	if err != nil {
+		return "", payload, err
+	}
+
+	message, err := json.Marshal(payload)
+	if err != nil {
+		return "", payload, err
+	}
+
+	return signPasetoV4Public(maker.privateKey, message, encodeTokenFooter(maker.keyID)), payload, nil
+}
+
+func (maker *PasetoPublicMaker) VerifyToken(token string) (*Payload, error) {
+	message, _, err := verifyPasetoV4Public(maker.publicKey, token)
+	if err != nil {
+		return nil, err
+	}
+
+	payload := &Payload{}
+	if err := json.Unmarshal(message, payload); err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	// check if payload is valid
+	err = payload.Valid()
+	if err != nil {
+		return nil, err
+	}
+
+	return payload, nil
+}
+
+// NewPasetoPublicMaker creates a Paseto v4.public maker with an Ed25519 key pair.
+// The private key is optional: without it, the maker can only verify tokens
+func NewPasetoPublicMaker(privateKey ed25519.PrivateKey, publicKey ed25519.PublicKey) (Maker, error) {
+	return newPasetoPublicMaker(privateKey, publicKey, "")
+}
+
+func newPasetoPublicMaker(privateKey ed25519.PrivateKey, publicKey ed25519.PublicKey, keyID string) (*PasetoPublicMaker, error) {
+	if err := checkEd25519KeyPair(privateKey, publicKey); err != nil {
+		return nil, err
+	}
+
+	return &PasetoPublicMaker{privateKey: privateKey, publicKey: publicKey, keyID: keyID}, nil
+}
+
+// signPasetoV4Public signs the message and the optional footer as specified by PASETO v4.public:
+// header || base64url(message || signature) [|| "." || base64url(footer)]
+func signPasetoV4Public(privateKey ed25519.PrivateKey, message []byte, footer []byte) string {
+	signature := ed25519.Sign(privateKey, preAuthEncode([]byte(pasetoV4PublicHeader), message, footer, nil))
+
+	token := pasetoV4PublicHeader + base64.RawURLEncoding.EncodeToString(append(message, signature...))
+	if len(footer) > 0 {
+		token += "." + base64.RawURLEncoding.EncodeToString(footer)
+	}
+	return token
+}
+
+// verifyPasetoV4Public checks the signature of a PASETO v4.public token and returns its message and footer
+func verifyPasetoV4Public(publicKey ed25519.PublicKey, token string) (message []byte, footer []byte, err error) {
+	if !strings.HasPrefix(token, pasetoV4PublicHeader) {
+		return nil, nil, ErrInvalidToken
+	}
+
+	message, footer, err = decodePasetoV4Public(token)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	signature := message[len(message)-ed25519.SignatureSize:]
+	message = message[:len(message)-ed25519.SignatureSize]
+	if !ed25519.Verify(publicKey, preAuthEncode([]byte(pasetoV4PublicHeader), message, footer, nil), signature) {
+		return nil, nil, ErrInvalidToken
+	}
+	return message, footer, nil
+}
+
+// decodePasetoV4Public decodes the signed message (still followed by its signature) and the footer of a token
+// without verifying it
+func decodePasetoV4Public(token string) (signedMessage []byte, footer []byte, err error) {
+	parts := strings.Split(strings.TrimPrefix(token, pasetoV4PublicHeader), ".")
+	if len(parts) > 2 {
+		return nil, nil, ErrInvalidToken
+	}
+
+	signedMessage, err = base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil || len(signedMessage) < ed25519.SignatureSize {
+		return nil, nil, ErrInvalidToken
+	}
+
+	if len(parts) == 2 {
+		footer, err = base64.RawURLEncoding.DecodeString(parts[1])
+		if err != nil {
+			return nil, nil, ErrInvalidToken
+		}
+	}
+	return signedMessage, footer, nil
+}
+
+// preAuthEncode implements the pre-authentication encoding (PAE) of PASETO,
+// which encodes the number of pieces and the length of each piece as 64-bit little endian integers
+func preAuthEncode(pieces ...[]byte) []byte {
+	var buf bytes.Buffer
+	writeLength := func(n int) {
+		var b [8]byte
+		// the most significant bit must be cleared for interoperability with languages without unsigned integers
+		binary.LittleEndian.PutUint64(b[:], uint64(n)&(1<<63-1))
+		buf.Write(b[:])
+	}
+
+	writeLength(len(pieces))
+	for _, piece := range pieces {
+		writeLength(len(piece))
+		buf.Write(piece)
+	}
+	return buf.Bytes()
+}
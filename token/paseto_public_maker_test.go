@@ -0,0 +1,137 @@
+package token
+
+import (
+	"crypto/ed25519"
+	"encoding/hex"
+	"testing"
+	"time"
+
+	"db.sqlc.dev/app/util"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPasetoPublicMaker(t *testing.T) {
+	privateKey, publicKey := newEd25519KeyPair(t)
+	maker, err := NewPasetoPublicMaker(privateKey, publicKey)
+	require.NoError(t, err)
+
+	username := util.RandomOwner()
+	role := util.DepositorRole
+	duration := time.Minute
+
+	issuedAt := time.Now()
+	expiredAt := issuedAt.Add(duration)
+
+	token, payload, err := maker.CreateToken(username, role, TokenTypeAccess, duration)
+	require.NoError(t, err)
+	require.NotEmpty(t, token)
+	require.NotEmpty(t, payload)
+
+	// the token can be verified with the public key only
+	verifier, err := NewPasetoPublicMaker(nil, publicKey)
+	require.NoError(t, err)
+
+	payload, err = verifier.VerifyToken(token)
+	require.NoError(t, err)
+
+	require.NotZero(t, payload.ID)
+	require.Equal(t, username, payload.Username)
+	require.Equal(t, role, payload.Role)
+	require.Equal(t, TokenTypeAccess, payload.Type)
+	require.WithinDuration(t, issuedAt, payload.IssuedAt, time.Second)
+	require.WithinDuration(t, expiredAt, payload.ExpiredAt, time.Second)
+
+	_, _, err = verifier.CreateToken(username, role, TokenTypeAccess, duration)
+	require.EqualError(t, err, ErrVerifyOnlyMaker.Error())
+}
+
+func TestExpiredPasetoPublicToken(t *testing.T) {
+	privateKey, publicKey := newEd25519KeyPair(t)
+	maker, err := NewPasetoPublicMaker(privateKey, publicKey)
+	require.NoError(t, err)
+
+	token, payload, err := maker.CreateToken(util.RandomOwner(), util.DepositorRole, TokenTypeAccess, -time.Minute)
+	require.NoError(t, err)
+	require.NotEmpty(t, token)
+	require.NotEmpty(t, payload)
+
+	payload, err = maker.VerifyToken(token)
+	require.Error(t, err)
+	require.EqualError(t, err, ErrExpiredToken.Error())
+	require.Nil(t, payload)
+}
+
+func TestInvalidPasetoPublicTokenWrongKey(t *testing.T) {
+	privateKey, publicKey := newEd25519KeyPair(t)
+	maker, err := NewPasetoPublicMaker(privateKey, publicKey)
+	require.NoError(t, err)
+
+	token, _, err := maker.CreateToken(util.RandomOwner(), util.DepositorRole, TokenTypeAccess, time.Minute)
+	require.NoError(t, err)
+
+	_, otherPublicKey := newEd25519KeyPair(t)
+	otherMaker, err := NewPasetoPublicMaker(nil, otherPublicKey)
+	require.NoError(t, err)
+
+	payload, err := otherMaker.VerifyToken(token)
+	require.EqualError(t, err, ErrInvalidToken.Error())
+	require.Nil(t, payload)
+
+	// tampered message: the last characters of the token may only hold padding bits, change the first one instead
+	tampered := []byte(token)
+	first := len(pasetoV4PublicHeader)
+	if tampered[first] == 'A' {
+		tampered[first] = 'B'
+	} else {
+		tampered[first] = 'A'
+	}
+	payload, err = maker.VerifyToken(string(tampered))
+	require.EqualError(t, err, ErrInvalidToken.Error())
+	require.Nil(t, payload)
+
+	// local paseto token
+	localMaker, err := NewPasetoMaker(util.RandomString(32))
+	require.NoError(t, err)
+	token, _, err = localMaker.CreateToken(util.RandomOwner(), util.DepositorRole, TokenTypeAccess, time.Minute)
+	require.NoError(t, err)
+	payload, err = maker.VerifyToken(token)
+	require.EqualError(t, err, ErrInvalidToken.Error())
+	require.Nil(t, payload)
+}
+
+// Test vectors 4-S-1 and 4-S-2 of the PASETO specification
+func TestPasetoV4PublicVectors(t *testing.T) {
+	seed, err := hex.DecodeString("b4cbfb43df4ce210727d953e4a713307fa19bb7d9f85041438d9e11b942a3774")
+	require.NoError(t, err)
+	privateKey := ed25519.NewKeyFromSeed(seed)
+	message := []byte(`{"data":"this is a signed message","exp":"2022-01-01T00:00:00+00:00"}`)
+
+	testCases := []struct {
+		name   string
+		footer []byte
+		token  string
+	}{
+		{
+			name:  "4-S-1",
+			token: "v4.public.eyJkYXRhIjoidGhpcyBpcyBhIHNpZ25lZCBtZXNzYWdlIiwiZXhwIjoiMjAyMi0wMS0wMVQwMDowMDowMCswMDowMCJ9bg_XBBzds8lTZShVlwwKSgeKpLT3yukTw6JUz3W4h_ExsQV-P0V54zemZDcAxFaSeef1QlXEFtkqxT1ciiQEDA",
+		},
+		{
+			name:   "4-S-2",
+			footer: []byte(`{"kid":"zVhMiPBP9fRf2snEcT7gFTioeA9COcNy9DfgL1W60haN"}`),
+			token:  "v4.public.eyJkYXRhIjoidGhpcyBpcyBhIHNpZ25lZCBtZXNzYWdlIiwiZXhwIjoiMjAyMi0wMS0wMVQwMDowMDowMCswMDowMCJ9v3Jt8mx_TdM2ceTGoqwrh4yDFn0XsHvvV_D0DtwQxVrJEBMl0F2caAdgnpKlt4p7xBnx1HcO-SPo8FPp214HDw.eyJraWQiOiJ6VmhNaVBCUDlmUmYyc25FY1Q3Z0ZUaW9lQTlDT2NOeTlEZmdMMVc2MGhhTiJ9",
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.token, signPasetoV4Public(privateKey, message, tc.footer))
+
+			verifiedMessage, footer, err := verifyPasetoV4Public(privateKey.Public().(ed25519.PublicKey), tc.token)
+			require.NoError(t, err)
+			require.Equal(t, message, verifiedMessage)
+			require.Equal(t, string(tc.footer), string(footer))
+		})
+	}
+}
@@ -0,0 +1,188 @@
+package token
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var ErrRevokedToken = errors.New("token has been revoked")
+
+// RevocationStore interface: manage the revocation of tokens before they expire
+type RevocationStore interface {
+	// RevokeToken: revokes a single token identified by its payload ID
+	RevokeToken(ctx context.Context, payload *Payload) error
+	// RevokeAllTokens: revokes every token of the user issued until now
+	RevokeAllTokens(ctx context.Context, username string) error
+	// IsRevoked: checks if the token of the input payload has been revoked
+	IsRevoked(ctx context.Context, payload *Payload) (bool, error)
+}
+
+// RevocationBackend interface: persistent storage of revocations, shared by all server instances
+type RevocationBackend interface {
+	// RevokeToken: stores the ID of the revoked token until the token expires
+	RevokeToken(ctx context.Context, payload *Payload) error
+	// IsTokenRevoked: checks if the token with the input ID has been revoked
+	IsTokenRevoked(ctx context.Context, tokenID uuid.UUID) (bool, error)
+	// RevokeAllTokens: stores the time before which all tokens of the user are revoked
+	RevokeAllTokens(ctx context.Context, username string, revokedAt time.Time) error
+	// TokensRevokedAt: returns the time before which all tokens of the user are revoked
+	TokensRevokedAt(ctx context.Context, username string) (time.Time, error)
+}
+
+// userRevocation caches the revocation time of all tokens of a user
+type userRevocation struct {
+	revokedAt time.Time
+	checkedAt time.Time
+}
+
+// CachedRevocationStore: struct of the revocation store, which implements the token.RevocationStore interface
+// It keeps revocations in memory and consults the backend on cache misses
+type CachedRevocationStore struct {
+	backend RevocationBackend
+	// duration: how long a "not revoked" answer of the backend is trusted before asking it again
+	duration time.Duration
+
+	mu      sync.Mutex
+	revoked map[uuid.UUID]time.Time // token ID -> expiration time of the revoked token
+	checked map[uuid.UUID]time.Time // token ID -> time until which the token is known to be not revoked
+	users   map[string]userRevocation
+	// cleanedAt: last time the expired entries were removed from the cache
+	cleanedAt time.Time
+}
+
+// cleanupInterval is the minimum interval between two removals of expired cache entries
+const cleanupInterval = time.Minute
+
+// NewCachedRevocationStore creates a revocation store that caches the revocations of the backend.
+// A nil backend keeps the revocations in memory only, which is useful for tests.
+func NewCachedRevocationStore(backend RevocationBackend, duration time.Duration) *CachedRevocationStore {
+	return &CachedRevocationStore{
+		backend:  backend,
+		duration: duration,
+		revoked:  make(map[uuid.UUID]time.Time),
+		checked:  make(map[uuid.UUID]time.Time),
+		users:    make(map[string]userRevocation),
+	}
+}
+
+func (store *CachedRevocationStore) RevokeToken(ctx context.Context, payload *Payload) error {
+	if store.backend != nil {
+		if err := store.backend.RevokeToken(ctx, payload); err != nil {
+			return err
+		}
+	}
+
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	store.removeExpired(time.Now())
+	store.revoked[payload.ID] = payload.ExpiredAt
+	delete(store.checked, payload.ID)
+	return nil
+}
+
+func (store *CachedRevocationStore) RevokeAllTokens(ctx context.Context, username string) error {
+	revokedAt := time.Now()
+	if store.backend != nil {
+		if err := store.backend.RevokeAllTokens(ctx, username, revokedAt); err != nil {
+			return err
+		}
+	}
+
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	store.users[username] = userRevocation{revokedAt: revokedAt, checkedAt: revokedAt}
+	return nil
+}
+
+func (store *CachedRevocationStore) IsRevoked(ctx context.Context, payload *Payload) (bool, error) {
+	revokedAt, err := store.tokensRevokedAt(ctx, payload.Username)
+	if err != nil {
+		return false, err
+	}
+	// all tokens issued before the revocation time of the user are revoked
+	if payload.IssuedAt.Before(revokedAt) {
+		return true, nil
+	}
+
+	now := time.Now()
+	store.mu.Lock()
+	_, revoked := store.revoked[payload.ID]
+	checkedUntil, checked := store.checked[payload.ID]
+	store.mu.Unlock()
+
+	if revoked {
+		return true, nil
+	}
+	if store.backend == nil || (checked && now.Before(checkedUntil)) {
+		return false, nil
+	}
+
+	revoked, err = store.backend.IsTokenRevoked(ctx, payload.ID)
+	if err != nil {
+		return false, err
+	}
+
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	store.removeExpired(now)
+	if revoked {
+		store.revoked[payload.ID] = payload.ExpiredAt
+	} else if store.duration > 0 {
+		store.checked[payload.ID] = now.Add(store.duration)
+	}
+	return revoked, nil
+}
+
+// tokensRevokedAt returns the time before which all tokens of the user are revoked,
+// the backend is only consulted if the cached value is older than store.duration
+func (store *CachedRevocationStore) tokensRevokedAt(ctx context.Context, username string) (time.Time, error) {
+	now := time.Now()
+	store.mu.Lock()
+	user, ok := store.users[username]
+	store.mu.Unlock()
+
+	if store.backend == nil || (ok && now.Sub(user.checkedAt) < store.duration) {
+		return user.revokedAt, nil
+	}
+
+	revokedAt, err := store.backend.TokensRevokedAt(ctx, username)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	// keep a newer revocation made by this instance while the backend was queried
+	if user, ok := store.users[username]; ok && user.revokedAt.After(revokedAt) {
+		revokedAt = user.revokedAt
+	}
+	store.users[username] = userRevocation{revokedAt: revokedAt, checkedAt: now}
+	return revokedAt, nil
+}
+
+// removeExpired deletes the cached entries that are no longer needed, the caller must hold store.mu
+func (store *CachedRevocationStore) removeExpired(now time.Time) {
+	if now.Sub(store.cleanedAt) < cleanupInterval {
+		return
+	}
+	store.cleanedAt = now
+
+	for id, expiredAt := range store.revoked {
+		if now.After(expiredAt) {
+			delete(store.revoked, id)
+		}
+	}
+	for id, checkedUntil := range store.checked {
+		if now.After(checkedUntil) {
+			delete(store.checked, id)
+		}
+	}
+}
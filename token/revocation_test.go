@@ -0,0 +1,137 @@
+package token
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"db.sqlc.dev/app/util"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeRevocationBackend: in-memory RevocationBackend that counts the lookups of the cache
+type fakeRevocationBackend struct {
+	revoked      map[uuid.UUID]bool
+	revokedAt    map[string]time.Time
+	tokenLookups int
+	userLookups  int
+}
+
+func newFakeRevocationBackend() *fakeRevocationBackend {
+	return &fakeRevocationBackend{
+		revoked:   make(map[uuid.UUID]bool),
+		revokedAt: make(map[string]time.Time),
+	}
+}
+
+func (backend *fakeRevocationBackend) RevokeToken(ctx context.Context, payload *Payload) error {
+	backend.revoked[payload.ID] = true
+	return nil
+}
+
+func (backend *fakeRevocationBackend) IsTokenRevoked(ctx context.Context, tokenID uuid.UUID) (bool, error) {
+	backend.tokenLookups++
+	return backend.revoked[tokenID], nil
+}
+
+func (backend *fakeRevocationBackend) RevokeAllTokens(ctx context.Context, username string, revokedAt time.Time) error {
+	backend.revokedAt[username] = revokedAt
+	return nil
+}
+
+func (backend *fakeRevocationBackend) TokensRevokedAt(ctx context.Context, username string) (time.Time, error) {
+	backend.userLookups++
+	return backend.revokedAt[username], nil
+}
+
+func TestRevokeToken(t *testing.T) {
+	store := NewCachedRevocationStore(nil, 0)
+
+	payload1, err := NewPayload(util.RandomOwner(), util.DepositorRole, TokenTypeAccess, time.Minute)
+	require.NoError(t, err)
+	payload2, err := NewPayload(payload1.Username, util.DepositorRole, TokenTypeAccess, time.Minute)
+	require.NoError(t, err)
+
+	err = store.RevokeToken(context.Background(), payload1)
+	require.NoError(t, err)
+
+	revoked, err := store.IsRevoked(context.Background(), payload1)
+	require.NoError(t, err)
+	require.True(t, revoked)
+
+	// other tokens of the same user stay valid
+	revoked, err = store.IsRevoked(context.Background(), payload2)
+	require.NoError(t, err)
+	require.False(t, revoked)
+}
+
+func TestRevokeAllTokens(t *testing.T) {
+	store := NewCachedRevocationStore(nil, 0)
+
+	username := util.RandomOwner()
+	oldPayload, err := NewPayload(username, util.DepositorRole, TokenTypeAccess, time.Minute)
+	require.NoError(t, err)
+	otherPayload, err := NewPayload(util.RandomOwner(), util.DepositorRole, TokenTypeAccess, time.Minute)
+	require.NoError(t, err)
+
+	err = store.RevokeAllTokens(context.Background(), username)
+	require.NoError(t, err)
+
+	newPayload, err := NewPayload(username, util.DepositorRole, TokenTypeAccess, time.Minute)
+	require.NoError(t, err)
+
+	revoked, err := store.IsRevoked(context.Background(), oldPayload)
+	require.NoError(t, err)
+	require.True(t, revoked)
+
+	// tokens issued after the revocation and tokens of other users stay valid
+	revoked, err = store.IsRevoked(context.Background(), newPayload)
+	require.NoError(t, err)
+	require.False(t, revoked)
+
+	revoked, err = store.IsRevoked(context.Background(), otherPayload)
+	require.NoError(t, err)
+	require.False(t, revoked)
+}
+
+func TestCachedRevocationStore(t *testing.T) {
+	backend := newFakeRevocationBackend()
+	store := NewCachedRevocationStore(backend, time.Minute)
+
+	payload, err := NewPayload(util.RandomOwner(), util.DepositorRole, TokenTypeAccess, time.Minute)
+	require.NoError(t, err)
+
+	// the first check asks the backend, the second one is answered by the cache
+	for i := 0; i < 2; i++ {
+		revoked, err := store.IsRevoked(context.Background(), payload)
+		require.NoError(t, err)
+		require.False(t, revoked)
+		require.Equal(t, 1, backend.tokenLookups)
+		require.Equal(t, 1, backend.userLookups)
+	}
+
+	// a revocation made by another server instance is found in the backend once the cache entry expires
+	otherPayload, err := NewPayload(payload.Username, util.DepositorRole, TokenTypeAccess, time.Minute)
+	require.NoError(t, err)
+	backend.revoked[otherPayload.ID] = true
+
+	revoked, err := store.IsRevoked(context.Background(), otherPayload)
+	require.NoError(t, err)
+	require.True(t, revoked)
+	require.Equal(t, 2, backend.tokenLookups)
+
+	// a local revocation is stored in the backend and takes effect immediately
+	err = store.RevokeToken(context.Background(), payload)
+	require.NoError(t, err)
+	require.True(t, backend.revoked[payload.ID])
+
+	revoked, err = store.IsRevoked(context.Background(), payload)
+	require.NoError(t, err)
+	require.True(t, revoked)
+	require.Equal(t, 2, backend.tokenLookups)
+
+	err = store.RevokeAllTokens(context.Background(), payload.Username)
+	require.NoError(t, err)
+	require.NotZero(t, backend.revokedAt[payload.Username])
+}
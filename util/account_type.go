@@ -0,0 +1,9 @@
+package util
+
+// Constants for all account types
+const (
+	// CheckingAccount: default account type, for everyday payments and transfers
+	CheckingAccount = "checking"
+	// SavingsAccount: account earning the interest of its currency (see interest.Schedule)
+	SavingsAccount = "savings"
+)
@@ -12,11 +12,51 @@ type Config struct {
 	// Viper uses the mapstructure package under the hood for unmarshaling values,
 	// so we use the mapstructure tags to specify the name of each config field
 	// must use the exact name of each variable as being declared in the app.env
-	DBDriver            string        `mapstructure:"DB_DRIVER"`
-	DBSource            string        `mapstructure:"DB_SOURCE"`
-	ServerAddress       string        `mapstructure:"SERVER_ADDRESS"`
-	TokenSymmetricKey   string        `mapstructure:"TOKEN_SYMMETRIC_KEY"`
-	AccessTokenDuration time.Duration `mapstructure:"ACCESS_TOKEN_DURATION"`
+	DBDriver      string `mapstructure:"DB_DRIVER"`
+	DBSource      string `mapstructure:"DB_SOURCE"`
+	ServerAddress string `mapstructure:"SERVER_ADDRESS"`
+	// TokenType selects the token maker: paseto(default) or jwt with TokenSymmetricKey,
+	// paseto_public or jwt_eddsa with the Ed25519 key pair stored in the PEM files
+	TokenType           string `mapstructure:"TOKEN_TYPE"`
+	TokenSymmetricKey   string `mapstructure:"TOKEN_SYMMETRIC_KEY"`
+	TokenPrivateKeyPath string `mapstructure:"TOKEN_PRIVATE_KEY_PATH"`
+	TokenPublicKeyPath  string `mapstructure:"TOKEN_PUBLIC_KEY_PATH"`
+	// TokenKeyringPath: optional JSON file of rotated keys, which replaces the single key above (see token.LoadKeyring)
+	TokenKeyringPath     string        `mapstructure:"TOKEN_KEYRING_PATH"`
+	AccessTokenDuration  time.Duration `mapstructure:"ACCESS_TOKEN_DURATION"`
+	RefreshTokenDuration time.Duration `mapstructure:"REFRESH_TOKEN_DURATION"`
+	// how long the server trusts its cached "token not revoked" answers,
+	// revocations made through other server instances take effect after at most this duration
+	RevocationCacheDuration time.Duration `mapstructure:"REVOCATION_CACHE_DURATION"`
+	// how long the responses of requests with an Idempotency-Key header are kept to answer the retries
+	IdempotencyKeyDuration time.Duration `mapstructure:"IDEMPOTENCY_KEY_DURATION"`
+	// JSON file of the exchange rates of cross-currency transfers (see fx.LoadRateFile)
+	FXRatesPath string `mapstructure:"FX_RATES_PATH"`
+	// JSON file of the supported currencies and their minor units (see LoadCurrencyRegistry)
+	CurrenciesPath string `mapstructure:"CURRENCIES_PATH"`
+	// JSON file of the transfer fee rules and the house fee accounts (see fee.LoadSchedule), no fees if empty.
+	// The server doesn't start if a fee account doesn't exist in its currency
+	FeesPath string `mapstructure:"FEES_PATH"`
+	// how often the scheduler runs the due scheduled transfers
+	SchedulerInterval time.Duration `mapstructure:"SCHEDULER_INTERVAL"`
+	// attempts of a failed scheduled transfer run before giving up on the occurrence
+	SchedulerMaxAttempts int32 `mapstructure:"SCHEDULER_MAX_ATTEMPTS"`
+	// delay before retrying a failed scheduled transfer run, doubled at each retry
+	SchedulerRetryBackoff time.Duration `mapstructure:"SCHEDULER_RETRY_BACKOFF"`
+	// default and maximum lifetime of the hold of an authorized transfer, released if not captured in time
+	TransferHoldDuration    time.Duration `mapstructure:"TRANSFER_HOLD_DURATION"`
+	MaxTransferHoldDuration time.Duration `mapstructure:"MAX_TRANSFER_HOLD_DURATION"`
+	// maximum number of open accounts owned by a user, the accounts held jointly with other owners don't count,
+	// the accounts aren't capped if 0
+	MaxAccountsPerUser int64 `mapstructure:"MAX_ACCOUNTS_PER_USER"`
+	// how often the expired holds are released
+	HoldExpiryInterval time.Duration `mapstructure:"HOLD_EXPIRY_INTERVAL"`
+	// JSON file of the interest rates of the accounts and the house interest expense accounts (see interest.LoadSchedule),
+	// no interest if empty
+	InterestPath string `mapstructure:"INTEREST_PATH"`
+	// how often the interest job runs, it accrues each day once and posts the interest of each month once.
+	// The days missed while the job wasn't running are accrued on the balance of the day they are backfilled
+	InterestInterval time.Duration `mapstructure:"INTEREST_INTERVAL"`
 }
 
 // LoadConfig reads configurations from a config file inside the path if it exists,
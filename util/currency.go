@@ -1,18 +1,141 @@
 package util
 
-// Constants for all supported currencies
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"db.sqlc.dev/app/money"
+)
+
+// Constants for the currencies of the default registry
 const (
 	USD = "USD"
 	EUR = "EUR"
 	CAD = "CAD"
 )
 
-// IsSupportedCurrency returns true if the currency is supported
-func IsSupportedCurrency(currency string) bool {
-	// use a simple switch case statement
-	switch currency {
-	case USD, EUR, CAD:
-		return true
+// maxCurrencyExponent limits the minor-unit exponent, ISO 4217 currencies have at most 4 decimal places
+const maxCurrencyExponent = 4
+
+var (
+	currencyCodePattern        = regexp.MustCompile(`^[A-Z]{3}$`)
+	currencyNumericCodePattern = regexp.MustCompile(`^[0-9]{3}$`)
+)
+
+// Currency describes an ISO 4217 currency
+type Currency struct {
+	// Code: alphabetic code, e.g. "USD"
+	Code string `json:"code"`
+	// NumericCode: 3-digit numeric code, e.g. "840"
+	NumericCode string `json:"numeric_code"`
+	// Exponent: number of decimal places of the minor unit, e.g. 2 for USD (cents), 0 for JPY, 3 for BHD
+	Exponent int `json:"exponent"`
+}
+
+// FormatAmount returns an amount in minor units as a decimal string of the major unit,
+// e.g. 1234 -> "12.34" for USD, "1234" for JPY and "1.234" for BHD
+func (currency Currency) FormatAmount(minor int64) string {
+	digits := strconv.FormatInt(minor, 10)
+	if currency.Exponent == 0 {
+		return digits
+	}
+
+	sign := ""
+	if minor < 0 {
+		sign = "-"
+		digits = digits[1:]
+	}
+	// pad with zeros so that there is at least one digit before the decimal point, e.g. 5 -> "0.05"
+	if len(digits) <= currency.Exponent {
+		digits = strings.Repeat("0", currency.Exponent-len(digits)+1) + digits
+	}
+
+	point := len(digits) - currency.Exponent
+	return sign + digits[:point] + "." + digits[point:]
+}
+
+// DefaultCurrencies are the supported currencies when no currency file is configured
+var DefaultCurrencies = []Currency{
+	{Code: USD, NumericCode: "840", Exponent: 2},
+	{Code: EUR, NumericCode: "978", Exponent: 2},
+	{Code: CAD, NumericCode: "124", Exponent: 2},
+}
+
+// CurrencyRegistry holds the currencies supported by the bank
+type CurrencyRegistry struct {
+	currencies map[string]Currency // code -> currency
+}
+
+// NewCurrencyRegistry creates a registry of the input currencies, after checking their codes and exponents
+func NewCurrencyRegistry(currencies ...Currency) (*CurrencyRegistry, error) {
+	registry := &CurrencyRegistry{currencies: make(map[string]Currency, len(currencies))}
+
+	for _, currency := range currencies {
+		if !currencyCodePattern.MatchString(currency.Code) {
+			return nil, fmt.Errorf("invalid currency code %q", currency.Code)
+		}
+		if !currencyNumericCodePattern.MatchString(currency.NumericCode) {
+			return nil, fmt.Errorf("invalid numeric code %q of currency %s", currency.NumericCode, currency.Code)
+		}
+		if currency.Exponent < 0 || currency.Exponent > maxCurrencyExponent {
+			return nil, fmt.Errorf("exponent of currency %s must be between 0 and %d", currency.Code, maxCurrencyExponent)
+		}
+		if _, ok := registry.currencies[currency.Code]; ok {
+			return nil, fmt.Errorf("duplicate currency %s", currency.Code)
+		}
+		registry.currencies[currency.Code] = currency
+	}
+	return registry, nil
+}
+
+// DefaultCurrencyRegistry returns the registry of DefaultCurrencies
+func DefaultCurrencyRegistry() *CurrencyRegistry {
+	// the default currencies are valid, so the registry can always be created
+	registry, _ := NewCurrencyRegistry(DefaultCurrencies...)
+	return registry
+}
+
+// LoadCurrencyRegistry creates a registry with the currencies of a JSON file, e.g.
+//
+//	[
+//	  {"code": "USD", "numeric_code": "840", "exponent": 2},
+//	  {"code": "JPY", "numeric_code": "392", "exponent": 0}
+//	]
+func LoadCurrencyRegistry(path string) (*CurrencyRegistry, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read currency file: %w", err)
+	}
+
+	var currencies []Currency
+	if err := json.Unmarshal(data, &currencies); err != nil {
+		return nil, fmt.Errorf("cannot parse currency file %s: %w", path, err)
+	}
+	return NewCurrencyRegistry(currencies...)
+}
+
+// Lookup returns the currency of the input code, ok is false if the currency is not supported
+func (registry *CurrencyRegistry) Lookup(code string) (currency Currency, ok bool) {
+	currency, ok = registry.currencies[code]
+	return
+}
+
+// IsSupported returns true if the currency is in the registry
+func (registry *CurrencyRegistry) IsSupported(code string) bool {
+	_, ok := registry.currencies[code]
+	return ok
+}
+
+// FormatAmount returns an amount as a decimal string of the major unit of its currency,
+// or an empty string if the currency is not in the registry
+func (registry *CurrencyRegistry) FormatAmount(amount money.Amount) string {
+	currency, ok := registry.currencies[amount.Currency]
+	if !ok {
+		return ""
 	}
-	return false
+	return currency.FormatAmount(amount.Minor)
 }
@@ -0,0 +1,89 @@
+package util
+
+import (
+	"math"
+	"testing"
+
+	"db.sqlc.dev/app/money"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFormatAmount(t *testing.T) {
+	usd := Currency{Code: USD, NumericCode: "840", Exponent: 2}
+	jpy := Currency{Code: "JPY", NumericCode: "392", Exponent: 0}
+	bhd := Currency{Code: "BHD", NumericCode: "048", Exponent: 3}
+
+	testCases := []struct {
+		currency Currency
+		minor    int64
+		expected string
+	}{
+		{currency: usd, minor: 1234, expected: "12.34"},
+		{currency: usd, minor: 5, expected: "0.05"},
+		{currency: usd, minor: 0, expected: "0.00"},
+		{currency: usd, minor: -5, expected: "-0.05"},
+		{currency: usd, minor: -1234, expected: "-12.34"},
+		{currency: usd, minor: math.MinInt64, expected: "-92233720368547758.08"},
+		{currency: jpy, minor: 1234, expected: "1234"},
+		{currency: jpy, minor: -1234, expected: "-1234"},
+		{currency: bhd, minor: 1234, expected: "1.234"},
+		{currency: bhd, minor: 10, expected: "0.010"},
+	}
+
+	for _, tc := range testCases {
+		require.Equal(t, tc.expected, tc.currency.FormatAmount(tc.minor), "%d %s", tc.minor, tc.currency.Code)
+	}
+}
+
+func TestNewCurrencyRegistry(t *testing.T) {
+	registry, err := NewCurrencyRegistry(DefaultCurrencies...)
+	require.NoError(t, err)
+
+	for _, currency := range DefaultCurrencies {
+		require.True(t, registry.IsSupported(currency.Code))
+
+		got, ok := registry.Lookup(currency.Code)
+		require.True(t, ok)
+		require.Equal(t, currency, got)
+	}
+	require.False(t, registry.IsSupported("JPY"))
+	require.Equal(t, "12.34", registry.FormatAmount(money.New(1234, USD)))
+	require.Empty(t, registry.FormatAmount(money.New(1234, "JPY")))
+
+	testCases := []struct {
+		name     string
+		currency Currency
+	}{
+		{name: "LowercaseCode", currency: Currency{Code: "usd", NumericCode: "840", Exponent: 2}},
+		{name: "ShortCode", currency: Currency{Code: "US", NumericCode: "840", Exponent: 2}},
+		{name: "InvalidNumericCode", currency: Currency{Code: "JPY", NumericCode: "39", Exponent: 0}},
+		{name: "NegativeExponent", currency: Currency{Code: "JPY", NumericCode: "392", Exponent: -1}},
+		{name: "ExponentTooLarge", currency: Currency{Code: "JPY", NumericCode: "392", Exponent: 5}},
+		{name: "Duplicate", currency: DefaultCurrencies[0]},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := NewCurrencyRegistry(append([]Currency{tc.currency}, DefaultCurrencies...)...)
+			require.Error(t, err)
+		})
+	}
+}
+
+func TestDefaultCurrencyFile(t *testing.T) {
+	registry, err := LoadCurrencyRegistry("../currencies.json")
+	require.NoError(t, err)
+
+	for _, currency := range DefaultCurrencies {
+		require.True(t, registry.IsSupported(currency.Code))
+	}
+
+	jpy, ok := registry.Lookup("JPY")
+	require.True(t, ok)
+	require.Equal(t, 0, jpy.Exponent)
+
+	bhd, ok := registry.Lookup("BHD")
+	require.True(t, ok)
+	require.Equal(t, "048", bhd.NumericCode)
+	require.Equal(t, 3, bhd.Exponent)
+}
@@ -0,0 +1,9 @@
+package util
+
+// Constants for all user roles
+const (
+	// DepositorRole: a customer who can only access his/her own accounts
+	DepositorRole = "depositor"
+	// BankerRole: a bank employee who can read the accounts and transfers of all customers
+	BankerRole = "banker"
+)
@@ -0,0 +1,154 @@
+// Package verifier lets other services verify the access tokens of simplebank without sharing a secret:
+// the tokens are verified offline with the public keys that the server publishes on /.well-known/jwks.json.
+// Revoked tokens are not detected, since revocations are only known by the server.
+package verifier
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"db.sqlc.dev/app/token"
+)
+
+var ErrUnknownKey = errors.New("token is signed with an unknown key")
+
+// minRefreshInterval limits how often tokens signed with unknown keys can make the verifier fetch the keys again
+const minRefreshInterval = 10 * time.Second
+
+// publicKey is a cached key of the JSON web key set
+type publicKey struct {
+	key ed25519.PublicKey
+	// algorithm: "EdDSA" for the keys of JSON web tokens, empty for the keys of Paseto tokens
+	algorithm string
+}
+
+// Verifier verifies tokens with the public keys fetched from a JSON web key set URL
+type Verifier struct {
+	jwksURL string
+	client  *http.Client
+	// cacheDuration: how long the fetched keys are used before fetching them again
+	cacheDuration time.Duration
+
+	mu        sync.Mutex
+	keys      map[string]publicKey // key ID -> public key
+	fetchedAt time.Time
+}
+
+// NewVerifier creates a verifier of the tokens signed with the keys published on jwksURL,
+// e.g. https://simplebank.example.com/.well-known/jwks.json
+func NewVerifier(jwksURL string, cacheDuration time.Duration) *Verifier {
+	return &Verifier{
+		jwksURL:       jwksURL,
+		client:        &http.Client{Timeout: 10 * time.Second},
+		cacheDuration: cacheDuration,
+	}
+}
+
+// VerifyToken checks the signature, the expiration time and the type of the access token and returns its payload
+func (verifier *Verifier) VerifyToken(ctx context.Context, accessToken string) (*token.Payload, error) {
+	keyID, err := token.KeyID(accessToken)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := verifier.publicKey(ctx, keyID)
+	if err != nil {
+		return nil, err
+	}
+
+	// the algorithm of the key decides the token format, so that a key can't verify tokens of another format
+	var maker token.Maker
+	if key.algorithm == "EdDSA" {
+		maker, err = token.NewJWTEdDSAMaker(nil, key.key)
+	} else {
+		maker, err = token.NewPasetoPublicMaker(nil, key.key)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	payload, err := maker.VerifyToken(accessToken)
+	if err != nil {
+		return nil, err
+	}
+
+	// a refresh token can't be used as an access token by the other services either
+	if err := payload.CheckType(token.TokenTypeAccess); err != nil {
+		return nil, err
+	}
+	return payload, nil
+}
+
+// publicKey returns the key of the input ID, the keys are fetched again if the cache has expired
+// or if the key is unknown, e.g. after a key rotation
+func (verifier *Verifier) publicKey(ctx context.Context, keyID string) (publicKey, error) {
+	verifier.mu.Lock()
+	defer verifier.mu.Unlock()
+
+	now := time.Now()
+	key, ok := verifier.keys[keyID]
+	age := now.Sub(verifier.fetchedAt)
+	if ok && age < verifier.cacheDuration {
+		return key, nil
+	}
+	if !ok && age < minRefreshInterval {
+		return publicKey{}, ErrUnknownKey
+	}
+
+	keys, err := verifier.fetchKeys(ctx)
+	if err != nil {
+		// keep using the cached key while the server can't be reached
+		if ok {
+			return key, nil
+		}
+		return publicKey{}, err
+	}
+	verifier.keys = keys
+	verifier.fetchedAt = now
+
+	key, ok = verifier.keys[keyID]
+	if !ok {
+		return publicKey{}, ErrUnknownKey
+	}
+	return key, nil
+}
+
+// fetchKeys downloads and decodes the JSON web key set
+func (verifier *Verifier) fetchKeys(ctx context.Context) (map[string]publicKey, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, verifier.jwksURL, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	rsp, err := verifier.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("cannot fetch keys: %w", err)
+	}
+	defer rsp.Body.Close()
+
+	if rsp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("cannot fetch keys: unexpected status %s", rsp.Status)
+	}
+
+	var set token.JSONWebKeySet
+	if err := json.NewDecoder(rsp.Body).Decode(&set); err != nil {
+		return nil, fmt.Errorf("cannot decode keys: %w", err)
+	}
+
+	keys := make(map[string]publicKey, len(set.Keys))
+	for _, jwk := range set.Keys {
+		key, err := jwk.Ed25519PublicKey()
+		if err != nil {
+			// skip the keys of other types, which can't verify our tokens
+			continue
+		}
+		keys[jwk.KeyID] = publicKey{key: key, algorithm: jwk.Algorithm}
+	}
+	return keys, nil
+}
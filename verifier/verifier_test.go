@@ -0,0 +1,163 @@
+package verifier
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"db.sqlc.dev/app/token"
+	"db.sqlc.dev/app/util"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestKeyring creates a keyring of the token type with a single random key
+func newTestKeyring(t *testing.T, tokenType string, keyID string) *token.Keyring {
+	publicKey, privateKey, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+
+	keyring, err := token.NewKeyring(tokenType, []token.KeyringKey{
+		{ID: keyID, PrivateKey: privateKey, PublicKey: publicKey},
+	}, keyID)
+	require.NoError(t, err)
+	return keyring
+}
+
+// jwksServer serves the public keys of the current keyring like api.Server and counts the requests
+type jwksServer struct {
+	*httptest.Server
+	keyring  atomic.Value
+	requests int32
+	down     int32
+}
+
+func newJWKSServer(t *testing.T, keyring *token.Keyring) *jwksServer {
+	server := &jwksServer{}
+	server.keyring.Store(keyring)
+	server.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&server.requests, 1)
+		if atomic.LoadInt32(&server.down) == 1 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		keyring := server.keyring.Load().(*token.Keyring)
+		json.NewEncoder(w).Encode(token.JSONWebKeySet{Keys: keyring.PublicKeys()})
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestVerifyToken(t *testing.T) {
+	for _, tokenType := range []string{token.TypePasetoPublic, token.TypeJWTEdDSA} {
+		tokenType := tokenType
+
+		t.Run(tokenType, func(t *testing.T) {
+			keyring := newTestKeyring(t, tokenType, "key")
+			server := newJWKSServer(t, keyring)
+			verifier := NewVerifier(server.URL, time.Minute)
+
+			username := util.RandomOwner()
+			accessToken, _, err := keyring.CreateToken(username, util.DepositorRole, token.TokenTypeAccess, time.Minute)
+			require.NoError(t, err)
+
+			payload, err := verifier.VerifyToken(context.Background(), accessToken)
+			require.NoError(t, err)
+			require.Equal(t, username, payload.Username)
+			require.Equal(t, util.DepositorRole, payload.Role)
+
+			// the keys are cached
+			_, err = verifier.VerifyToken(context.Background(), accessToken)
+			require.NoError(t, err)
+			require.EqualValues(t, 1, atomic.LoadInt32(&server.requests))
+
+			expiredToken, _, err := keyring.CreateToken(username, util.DepositorRole, token.TokenTypeAccess, -time.Minute)
+			require.NoError(t, err)
+			payload, err = verifier.VerifyToken(context.Background(), expiredToken)
+			require.EqualError(t, err, token.ErrExpiredToken.Error())
+			require.Nil(t, payload)
+		})
+	}
+}
+
+func TestVerifyTokenKeyRotation(t *testing.T) {
+	oldKeyring := newTestKeyring(t, token.TypeJWTEdDSA, "old")
+	server := newJWKSServer(t, oldKeyring)
+	verifier := NewVerifier(server.URL, time.Hour)
+
+	oldToken, _, err := oldKeyring.CreateToken(util.RandomOwner(), util.DepositorRole, token.TokenTypeAccess, time.Minute)
+	require.NoError(t, err)
+	_, err = verifier.VerifyToken(context.Background(), oldToken)
+	require.NoError(t, err)
+
+	// a token of an unknown key makes the verifier fetch the keys again, at most once per minRefreshInterval
+	newKeyring := newTestKeyring(t, token.TypeJWTEdDSA, "new")
+	server.keyring.Store(newKeyring)
+	verifier.fetchedAt = time.Now().Add(-minRefreshInterval)
+
+	newToken, _, err := newKeyring.CreateToken(util.RandomOwner(), util.DepositorRole, token.TokenTypeAccess, time.Minute)
+	require.NoError(t, err)
+	_, err = verifier.VerifyToken(context.Background(), newToken)
+	require.NoError(t, err)
+	require.EqualValues(t, 2, atomic.LoadInt32(&server.requests))
+
+	unknownKeyring := newTestKeyring(t, token.TypeJWTEdDSA, "unknown")
+	unknownToken, _, err := unknownKeyring.CreateToken(util.RandomOwner(), util.DepositorRole, token.TokenTypeAccess, time.Minute)
+	require.NoError(t, err)
+	payload, err := verifier.VerifyToken(context.Background(), unknownToken)
+	require.EqualError(t, err, ErrUnknownKey.Error())
+	require.Nil(t, payload)
+	require.EqualValues(t, 2, atomic.LoadInt32(&server.requests))
+}
+
+func TestVerifyTokenServerDown(t *testing.T) {
+	keyring := newTestKeyring(t, token.TypePasetoPublic, "key")
+	server := newJWKSServer(t, keyring)
+	verifier := NewVerifier(server.URL, time.Minute)
+
+	accessToken, _, err := keyring.CreateToken(util.RandomOwner(), util.DepositorRole, token.TokenTypeAccess, time.Minute)
+	require.NoError(t, err)
+
+	atomic.StoreInt32(&server.down, 1)
+	_, err = verifier.VerifyToken(context.Background(), accessToken)
+	require.Error(t, err)
+
+	// the cached keys are still used once the cache has expired
+	atomic.StoreInt32(&server.down, 0)
+	_, err = verifier.VerifyToken(context.Background(), accessToken)
+	require.NoError(t, err)
+
+	atomic.StoreInt32(&server.down, 1)
+	verifier.fetchedAt = time.Now().Add(-time.Hour)
+	_, err = verifier.VerifyToken(context.Background(), accessToken)
+	require.NoError(t, err)
+}
+
+// a key of Paseto tokens can't verify JSON web tokens
+func TestVerifyTokenWrongFormat(t *testing.T) {
+	publicKey, privateKey, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+
+	pasetoKeyring, err := token.NewKeyring(token.TypePasetoPublic, []token.KeyringKey{
+		{ID: "key", PrivateKey: privateKey, PublicKey: publicKey},
+	}, "key")
+	require.NoError(t, err)
+	jwtKeyring, err := token.NewKeyring(token.TypeJWTEdDSA, []token.KeyringKey{
+		{ID: "key", PrivateKey: privateKey, PublicKey: publicKey},
+	}, "key")
+	require.NoError(t, err)
+
+	server := newJWKSServer(t, pasetoKeyring)
+	verifier := NewVerifier(server.URL, time.Minute)
+
+	jwtToken, _, err := jwtKeyring.CreateToken(util.RandomOwner(), util.DepositorRole, token.TokenTypeAccess, time.Minute)
+	require.NoError(t, err)
+
+	payload, err := verifier.VerifyToken(context.Background(), jwtToken)
+	require.EqualError(t, err, token.ErrInvalidToken.Error())
+	require.Nil(t, payload)
+}